@@ -0,0 +1,948 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"minimax-voice-workbench/internal/audio"
+	"minimax-voice-workbench/internal/book"
+	"minimax-voice-workbench/internal/database"
+	"minimax-voice-workbench/internal/model"
+	"minimax-voice-workbench/pkg/minimax"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Paragraphs longer than this are split between sentences when a document
+// is imported, so that each fits a sync request comfortably
+const audiobookParagraphLen = 3000
+
+// Chapters up to this many characters are synthesized paragraph by
+// paragraph with sync T2A; longer ones through async tasks
+const chunkedChapterMaxChars = 20000
+
+// Largest document accepted for an audiobook
+const maxAudiobookSize = 50 << 20
+
+// Polling of the async takes of a chapter
+const (
+	audiobookPollInterval = 10 * time.Second
+	audiobookAsyncTimeout = 2 * time.Hour
+)
+
+// Model of audiobooks whose chapter and project leave it out
+const defaultAudiobookModel = "speech-2.6-hd"
+
+// audiobookRun is one background build of an audiobook
+type audiobookRun struct {
+	cancel   context.CancelFunc
+	stopping bool // Cancelled, but still finishing its current paragraph
+}
+
+// audiobookRuns holds the run of every audiobook being built, until its
+// goroutine has exited
+var audiobookRuns = struct {
+	sync.Mutex
+	runs map[uint]*audiobookRun
+}{runs: map[uint]*audiobookRun{}}
+
+// BuildAudiobookRequest starts synthesizing chapters. Voice, preset,
+// settings and normalization, when given, replace those of every chapter
+// built.
+type BuildAudiobookRequest struct {
+	KeyID         uint                     `json:"key_id"`
+	ChapterIDs    []uint                   `json:"chapter_ids"` // All chapters when empty
+	VoiceID       string                   `json:"voice_id"`
+	PresetID      uint                     `json:"preset_id"`
+	Settings      *model.SynthesisSettings `json:"settings"`
+	Normalization string                   `json:"normalization"` // rules, all or off
+	BatchLimit
+}
+
+// UpdateChapterRequest changes the title, voice, preset, settings,
+// pipeline or normalization of a chapter. Omitted fields are left
+// unchanged.
+type UpdateChapterRequest struct {
+	Title         *string                  `json:"title"`
+	VoiceID       *string                  `json:"voice_id"`
+	PresetID      *uint                    `json:"preset_id"` // 0 clears
+	Settings      *model.SynthesisSettings `json:"settings"`
+	Pipeline      *string                  `json:"pipeline"`      // chunked, async, or empty to choose by length
+	Normalization *string                  `json:"normalization"` // rules, all or off
+}
+
+var audiobookPipelines = map[string]bool{"": true, "chunked": true, "async": true}
+
+// chapterPipeline is the pipeline a chapter is synthesized through
+func chapterPipeline(chapter *model.AudiobookChapter) string {
+	if chapter.Pipeline != "" {
+		return chapter.Pipeline
+	}
+	if chapter.Chars <= chunkedChapterMaxChars {
+		return "chunked"
+	}
+	return "async"
+}
+
+// chapterPreset loads the preset of a chapter, nil when it has none
+func chapterPreset(chapter *model.AudiobookChapter) (*model.Preset, error) {
+	if chapter.PresetID == 0 {
+		return nil, nil
+	}
+	var preset model.Preset
+	if err := database.DB.First(&preset, chapter.PresetID).Error; err != nil {
+		return nil, err
+	}
+	return &preset, nil
+}
+
+// chapterRequest builds the request for one paragraph of a chapter. The
+// chapter's voice and settings win over its preset, and the preset over
+// the project defaults. Audio is always MP3 with fixed parameters, so that
+// takes can be joined frame by frame.
+func chapterRequest(project *model.Project, preset *model.Preset, chapter *model.AudiobookChapter, text string) *minimax.T2ARequest {
+	req := &minimax.T2ARequest{Text: text}
+	applyProjectDefaults(&model.Project{DefaultVoiceID: chapter.VoiceID, Settings: chapter.Settings}, req)
+	if preset != nil {
+		applyProjectDefaults(presetDefaults(preset), req)
+	}
+	applyProjectDefaults(project, req)
+	if req.Model == "" {
+		req.Model = defaultAudiobookModel
+	}
+	req.AudioSetting.Format = "mp3"
+	if req.AudioSetting.AudioSampleRate == 0 {
+		req.AudioSetting.AudioSampleRate = 32000
+	}
+	if req.AudioSetting.Bitrate == 0 {
+		req.AudioSetting.Bitrate = 128000
+	}
+	if req.AudioSetting.Channel == 0 {
+		req.AudioSetting.Channel = 1
+	}
+	return req
+}
+
+// audiobookDir is where the chapter audio and combined track are written
+func audiobookDir(bookID uint) string {
+	return filepath.Join("generated", "audiobooks", strconv.Itoa(int(bookID)))
+}
+
+// startAudiobookBuild builds an audiobook in the background. It returns
+// false when a run of the book, possibly one being stopped, has not exited
+// yet.
+func startAudiobookBuild(bookID uint) bool {
+	audiobookRuns.Lock()
+	defer audiobookRuns.Unlock()
+	if _, ok := audiobookRuns.runs[bookID]; ok {
+		return false
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &audiobookRun{cancel: cancel}
+	audiobookRuns.runs[bookID] = run
+	database.DB.Model(&model.Audiobook{}).Where("id = ?", bookID).Updates(map[string]interface{}{"status": "building", "error": ""})
+	go func() {
+		runAudiobookBuild(ctx, bookID)
+		audiobookRuns.Lock()
+		if audiobookRuns.runs[bookID] == run {
+			delete(audiobookRuns.runs, bookID)
+		}
+		audiobookRuns.Unlock()
+		cancel()
+	}()
+	return true
+}
+
+// stopAudiobookBuild stops the build of an audiobook after its current
+// paragraph. The run stays registered until its goroutine exits, so that no
+// second build starts on the same paragraphs meanwhile.
+func stopAudiobookBuild(bookID uint) {
+	audiobookRuns.Lock()
+	defer audiobookRuns.Unlock()
+	if run, ok := audiobookRuns.runs[bookID]; ok {
+		run.cancel()
+		run.stopping = true
+	}
+}
+
+// audiobookRunState reports whether a book has a run, and whether that run
+// is being stopped
+func audiobookRunState(bookID uint) (running, stopping bool) {
+	audiobookRuns.Lock()
+	defer audiobookRuns.Unlock()
+	run, ok := audiobookRuns.runs[bookID]
+	return ok, ok && run.stopping
+}
+
+// runAudiobookBuild synthesizes the queued chapters of a book one after the
+// other, then joins every ready chapter into the combined track
+func runAudiobookBuild(ctx context.Context, bookID uint) {
+	var ab model.Audiobook
+	if err := database.DB.First(&ab, bookID).Error; err != nil {
+		return
+	}
+	var project model.Project
+	database.DB.First(&project, ab.ProjectID)
+
+	for ctx.Err() == nil {
+		var chapter model.AudiobookChapter
+		err := database.DB.Where("audiobook_id = ? AND status IN ?", bookID, []string{"queued", "synthesizing"}).
+			Order("position asc").First(&chapter).Error
+		if err != nil {
+			break
+		}
+		if err := buildChapter(ctx, &ab, &project, &chapter); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			database.DB.Model(&chapter).Updates(map[string]interface{}{"status": "failed", "error": err.Error()})
+		}
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	status, errMsg := "ready", ""
+	if err := assembleAudiobook(&ab); err != nil {
+		status, errMsg = "failed", err.Error()
+	} else {
+		var failed, unbuilt int64
+		database.DB.Model(&model.AudiobookChapter{}).Where("audiobook_id = ? AND status = ?", bookID, "failed").Count(&failed)
+		database.DB.Model(&model.AudiobookChapter{}).Where("audiobook_id = ? AND status <> ?", bookID, "ready").Count(&unbuilt)
+		if failed > 0 {
+			status, errMsg = "failed", fmt.Sprintf("%d chapters failed", failed)
+		} else if unbuilt > 0 {
+			status = "partial"
+		}
+	}
+	database.DB.Model(&ab).Updates(map[string]interface{}{"status": status, "error": errMsg})
+}
+
+// chapterTake is a paragraph of a chapter with the take used for it
+type chapterTake struct {
+	line    model.Line
+	request *minimax.T2ARequest
+	payload string
+	task    *model.SynthesisTask
+}
+
+// chapterTakes returns the paragraphs of a chapter with their requests and
+// any take that can be reused, and the characters of the chapter
+func chapterTakes(ab *model.Audiobook, project *model.Project, chapter *model.AudiobookChapter, rules []model.NormalizationRule) ([]*chapterTake, int, error) {
+	var lines []model.Line
+	database.DB.Where("project_id = ? AND folder_id = ?", ab.ProjectID, chapter.FolderID).Order("id asc").Find(&lines)
+	if len(lines) == 0 {
+		return nil, 0, errors.New("chapter has no paragraphs")
+	}
+
+	preset, err := chapterPreset(chapter)
+	if err != nil {
+		return nil, 0, errors.New("preset of the chapter not found")
+	}
+
+	dicts := pronunciationDicts(ab.ProjectID)
+	takes := make([]*chapterTake, len(lines))
+	chars := 0
+	for i, line := range lines {
+		req := chapterRequest(project, preset, chapter, line.Text)
+		req.Text = normalizeText(req.Text, rules, req.LanguageBoost, chapter.Normalization)
+		addDictTones(req, dicts)
+		if req.VoiceSetting.VoiceID == "" {
+			return nil, 0, errors.New("no voice chosen for the chapter")
+		}
+		payload, _ := json.Marshal(req)
+		takes[i] = &chapterTake{line: line, request: req, payload: string(payload), task: reusableTake(&line, string(payload))}
+		chars += len([]rune(line.Text))
+	}
+	return takes, chars, nil
+}
+
+// buildChapter synthesizes the paragraphs of a chapter that have no take
+// matching their current text and settings, then joins the takes into the
+// chapter audio. Each new take is selected on its line.
+func buildChapter(ctx context.Context, ab *model.Audiobook, project *model.Project, chapter *model.AudiobookChapter) error {
+	database.DB.Model(chapter).Updates(map[string]interface{}{"status": "synthesizing", "error": ""})
+
+	takes, chars, err := chapterTakes(ab, project, chapter, normalizationRules(ab.ProjectID))
+	if err != nil {
+		return err
+	}
+	// Paragraphs may have been edited since the import
+	chapter.Chars = chars
+	database.DB.Model(chapter).Update("chars", chars)
+
+	if chapterPipeline(chapter) == "chunked" {
+		err = synthesizeChunked(ctx, ab, chapter, takes)
+	} else {
+		err = synthesizeAsync(ctx, ab, chapter, takes)
+	}
+	if err != nil {
+		return err
+	}
+
+	paths := make([]string, len(takes))
+	for i, t := range takes {
+		paths[i] = generatedFilePath(t.task.Output)
+	}
+	dir := audiobookDir(ab.ID)
+	os.MkdirAll(dir, 0755)
+	name := fmt.Sprintf("chapter_%03d.mp3", chapter.Position)
+	var durations []float64
+	err = writeAudiobookFile(filepath.Join(dir, name), func(f *os.File) error {
+		var err error
+		durations, err = audio.ConcatMP3(f, paths)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	total := 0.0
+	for _, d := range durations {
+		total += d
+	}
+	return database.DB.Model(chapter).Updates(map[string]interface{}{
+		"status":   "ready",
+		"output":   "/files/audiobooks/" + strconv.Itoa(int(ab.ID)) + "/" + name,
+		"duration": total,
+	}).Error
+}
+
+// reusableTake returns the take of a line made from exactly payload: the
+// selected one if it matches, or else one still processing from an
+// interrupted build
+func reusableTake(line *model.Line, payload string) *model.SynthesisTask {
+	var task model.SynthesisTask
+	if line.SelectedTaskID > 0 && database.DB.First(&task, line.SelectedTaskID).Error == nil &&
+		task.Status == "success" && task.RequestPayload == payload {
+		if _, err := os.Stat(generatedFilePath(task.Output)); err == nil {
+			return &task
+		}
+	}
+	err := database.DB.Where("line_id = ? AND status = ? AND request_payload = ?", line.ID, "processing", payload).
+		Order("id desc").First(&task).Error
+	if err == nil && task.TaskID > 0 {
+		return &task
+	}
+	return nil
+}
+
+// newChapterTask records a take of a paragraph
+func newChapterTask(ab *model.Audiobook, chapter *model.AudiobookChapter, t *chapterTake, keyID uint) *model.SynthesisTask {
+	task := &model.SynthesisTask{
+		Text:           t.line.Text,
+		VoiceID:        t.request.VoiceSetting.VoiceID,
+		Format:         t.request.AudioSetting.Format,
+		SampleRate:     t.request.AudioSetting.AudioSampleRate,
+		Channel:        t.request.AudioSetting.Channel,
+		Status:         "processing",
+		RequestPayload: t.payload,
+		KeyID:          keyID,
+		ProjectID:      ab.ProjectID,
+		FolderID:       chapter.FolderID,
+		LineID:         t.line.ID,
+	}
+	if t.request.Text != t.line.Text {
+		task.NormalizedText = t.request.Text
+	}
+	return task
+}
+
+// selectTake makes task the selected take of its line
+func selectTake(task *model.SynthesisTask) {
+	database.DB.Model(&model.Line{}).Where("id = ?", task.LineID).Update("selected_task_id", task.ID)
+}
+
+// synthesizeChunked synthesizes the missing takes one paragraph at a time
+// with sync T2A. A rate limit on every key pauses before trying again.
+func synthesizeChunked(ctx context.Context, ab *model.Audiobook, chapter *model.AudiobookChapter, takes []*chapterTake) error {
+	for _, t := range takes {
+		for t.task == nil || t.task.Status != "success" {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if t.task != nil && t.task.Status == "processing" {
+				// Left by an async build; the chunked pipeline does not wait for it
+				t.task = nil
+			}
+
+			var resp *minimax.T2AResponse
+			apiKey, err := withVoiceKey(t.request.VoiceSetting.VoiceID, ab.KeyID, func(client *minimax.Client) error {
+				var err error
+				resp, err = client.T2A(t.request)
+				return err
+			})
+			if apiKey == nil {
+				return errors.New("no API key available")
+			}
+			if err != nil && minimax.IsRateLimited(err) {
+				if !sleepContext(ctx, previewJobRateLimitWait) {
+					return ctx.Err()
+				}
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("paragraph %d: %w", t.line.ID, err)
+			}
+			audioBytes, err := hex.DecodeString(resp.Data.Audio)
+			if err != nil {
+				return fmt.Errorf("paragraph %d: failed to decode audio", t.line.ID)
+			}
+
+			task := newChapterTask(ab, chapter, t, apiKey.ID)
+			database.DB.Create(task)
+			outputDir := filepath.Join("generated", "audios")
+			os.MkdirAll(outputDir, 0755)
+			filename := fmt.Sprintf("audio_%d.%s", task.ID, task.Format)
+			if err := os.WriteFile(filepath.Join(outputDir, filename), audioBytes, 0644); err != nil {
+				database.DB.Model(task).Updates(map[string]interface{}{"status": "failed", "error": "Failed to save file"})
+				return err
+			}
+			task.Output = "/files/audios/" + filename
+			task.Status = "success"
+			database.DB.Save(task)
+			t.task = task
+			markVoiceUsed(task.VoiceID)
+		}
+		selectTake(t.task)
+	}
+	return nil
+}
+
+// synthesizeAsync submits an async task for every missing take, then polls
+// them until all are done
+func synthesizeAsync(ctx context.Context, ab *model.Audiobook, chapter *model.AudiobookChapter, takes []*chapterTake) error {
+	for _, t := range takes {
+		for t.task == nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			var resp *minimax.T2AAsyncResponse
+			apiKey, err := withVoiceKey(t.request.VoiceSetting.VoiceID, ab.KeyID, func(client *minimax.Client) error {
+				var err error
+				resp, err = client.T2AAsync(t.request)
+				return err
+			})
+			if apiKey == nil {
+				return errors.New("no API key available")
+			}
+			if err != nil && minimax.IsRateLimited(err) {
+				if !sleepContext(ctx, previewJobRateLimitWait) {
+					return ctx.Err()
+				}
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("paragraph %d: %w", t.line.ID, err)
+			}
+			task := newChapterTask(ab, chapter, t, apiKey.ID)
+			task.TaskID = resp.TaskID
+			database.DB.Create(task)
+			t.task = task
+		}
+	}
+
+	deadline := time.Now().Add(audiobookAsyncTimeout)
+	for {
+		pending := 0
+		for _, t := range takes {
+			if t.task.Status == "success" {
+				continue
+			}
+			if t.task.Status == "failed" {
+				return fmt.Errorf("paragraph %d: %s", t.line.ID, t.task.Error)
+			}
+			mu := lockTask(t.task.ID)
+			mu.Lock()
+			if err := database.DB.First(t.task, t.task.ID).Error; err == nil && t.task.Status == "processing" {
+				pollSynthesisTask(t.task, 0)
+			}
+			mu.Unlock()
+			if t.task.Status == "processing" {
+				pending++
+			}
+		}
+		if pending == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%d paragraphs still processing after %s", pending, audiobookAsyncTimeout)
+		}
+		if !sleepContext(ctx, audiobookPollInterval) {
+			return ctx.Err()
+		}
+	}
+
+	for _, t := range takes {
+		if t.task.Status != "success" {
+			return fmt.Errorf("paragraph %d: %s", t.line.ID, t.task.Error)
+		}
+		selectTake(t.task)
+	}
+	return nil
+}
+
+// assembleAudiobook joins the ready chapters into one track, with a
+// chapter marker at the start of each
+func assembleAudiobook(ab *model.Audiobook) error {
+	var chapters []model.AudiobookChapter
+	database.DB.Where("audiobook_id = ? AND status = ?", ab.ID, "ready").Order("position asc").Find(&chapters)
+	if len(chapters) == 0 {
+		return errors.New("no chapter was synthesized")
+	}
+
+	paths := make([]string, len(chapters))
+	for i, ch := range chapters {
+		paths[i] = generatedFilePath(ch.Output)
+	}
+	markers := make([]audio.Chapter, len(chapters))
+	for i, ch := range chapters {
+		markers[i].Title = ch.Title
+	}
+	// Reserve the tag before the audio, and fill in the times once the
+	// durations are known
+	tag, err := audio.ChapterTag(ab.Title, markers)
+	if err != nil {
+		return err
+	}
+
+	dir := audiobookDir(ab.ID)
+	os.MkdirAll(dir, 0755)
+	start := 0.0
+	err = writeAudiobookFile(filepath.Join(dir, "book.mp3"), func(f *os.File) error {
+		if _, err := f.Write(tag); err != nil {
+			return err
+		}
+		durations, err := audio.ConcatMP3(f, paths)
+		if err != nil {
+			return err
+		}
+		for i := range markers {
+			markers[i].Start, markers[i].End = start, start+durations[i]
+			start += durations[i]
+		}
+		final, err := audio.ChapterTag(ab.Title, markers)
+		if err != nil {
+			return err
+		}
+		_, err = f.WriteAt(final, 0)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	ab.Output = "/files/audiobooks/" + strconv.Itoa(int(ab.ID)) + "/book.mp3"
+	ab.Duration = start
+	return database.DB.Model(ab).Updates(map[string]interface{}{"output": ab.Output, "duration": ab.Duration}).Error
+}
+
+// writeAudiobookFile streams a file through write into a temporary file
+// that replaces path once complete, so players never see a partial file
+func writeAudiobookFile(path string, write func(f *os.File) error) error {
+	tmp := path + ".part"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ResumeAudiobookBuilds restarts builds that were running when the server
+// stopped
+func ResumeAudiobookBuilds() {
+	var books []model.Audiobook
+	database.DB.Where("status = ?", "building").Find(&books)
+	for _, ab := range books {
+		log.Println("Resuming audiobook build", ab.ID)
+		startAudiobookBuild(ab.ID)
+	}
+}
+
+// loadAudiobook loads the audiobook of the :id parameter with its chapters
+func loadAudiobook(c *gin.Context) (*model.Audiobook, error) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var ab model.Audiobook
+	err := database.DB.Preload("Chapters", func(db *gorm.DB) *gorm.DB { return db.Order("position asc") }).
+		First(&ab, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &ab, nil
+}
+
+// CreateAudiobook imports a Markdown, plain text or EPUB document. Each
+// chapter becomes a folder of the project and each paragraph a line in it.
+// Without project_id a project named after the book is created, using
+// voice_id and settings (JSON) as its defaults.
+func CreateAudiobook(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 1, "File upload required")
+		return
+	}
+	if fileHeader.Size > maxAudiobookSize {
+		ErrorResponse(c, http.StatusBadRequest, 2, "File is too large")
+		return
+	}
+	f, err := fileHeader.Open()
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 1, "File upload required")
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(f, maxAudiobookSize))
+	f.Close()
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 1, "File upload required")
+		return
+	}
+
+	parsed, err := book.Parse(fileHeader.Filename, data, audiobookParagraphLen)
+	if errors.Is(err, book.ErrUnsupportedFormat) {
+		ErrorResponse(c, http.StatusBadRequest, 2, "Only .md, .txt and .epub files are supported")
+		return
+	}
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 3, "Failed to read document: "+err.Error())
+		return
+	}
+	// The combined track lists every chapter in its ID3 tag
+	if len(parsed.Chapters) > audio.MaxChapters {
+		ErrorResponse(c, http.StatusBadRequest, 3, fmt.Sprintf("Document has %d chapters; audiobooks hold at most %d", len(parsed.Chapters), audio.MaxChapters))
+		return
+	}
+	if title := strings.TrimSpace(c.PostForm("title")); title != "" {
+		parsed.Title = title
+	}
+
+	var project model.Project
+	if projectID, _ := strconv.Atoi(c.PostForm("project_id")); projectID > 0 {
+		if err := database.DB.First(&project, projectID).Error; err != nil {
+			ErrorResponse(c, http.StatusNotFound, 4, "Project not found")
+			return
+		}
+	} else {
+		project = model.Project{Name: parsed.Title, DefaultVoiceID: c.PostForm("voice_id")}
+		if raw := c.PostForm("settings"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &project.Settings); err != nil {
+				ErrorResponse(c, http.StatusBadRequest, 5, "Invalid settings")
+				return
+			}
+		}
+	}
+
+	ab := model.Audiobook{Title: parsed.Title, Format: parsed.Format, Filename: filepath.Base(fileHeader.Filename)}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if project.ID == 0 {
+			if err := tx.Create(&project).Error; err != nil {
+				return err
+			}
+		}
+		ab.ProjectID = project.ID
+		if err := tx.Create(&ab).Error; err != nil {
+			return err
+		}
+		for i, ch := range parsed.Chapters {
+			// Folders are listed by name, so the position leads
+			folder := model.ProjectFolder{ProjectID: project.ID, Name: fmt.Sprintf("%03d %s", i+1, ch.Title)}
+			if err := tx.Create(&folder).Error; err != nil {
+				return err
+			}
+			chapter := model.AudiobookChapter{
+				AudiobookID: ab.ID,
+				FolderID:    folder.ID,
+				Position:    i + 1,
+				Title:       ch.Title,
+				Chars:       ch.Chars(),
+				Status:      "draft",
+			}
+			if err := tx.Create(&chapter).Error; err != nil {
+				return err
+			}
+			lines := make([]model.Line, len(ch.Paragraphs))
+			for j, p := range ch.Paragraphs {
+				lines[j] = model.Line{ProjectID: project.ID, FolderID: folder.ID, Name: lineName(p), Text: p}
+			}
+			if err := tx.CreateInBatches(lines, 100).Error; err != nil {
+				return err
+			}
+			ab.Chapters = append(ab.Chapters, chapter)
+		}
+		return nil
+	})
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 6, "Failed to create audiobook")
+		return
+	}
+	SuccessResponse(c, ab)
+}
+
+// ListAudiobooks returns audiobooks, newest first
+func ListAudiobooks(c *gin.Context) {
+	books, page, err := findPage[model.Audiobook](c, database.DB.Model(&model.Audiobook{}), newestFirst)
+	if err != nil {
+		listErrorResponse(c, err, 1, "Failed to fetch audiobooks")
+		return
+	}
+	PagedSuccessResponse(c, books, page)
+}
+
+// GetAudiobook returns an audiobook with its chapters. Their paragraphs are
+// the lines of the chapter folders.
+func GetAudiobook(c *gin.Context) {
+	ab, err := loadAudiobook(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Audiobook not found")
+		return
+	}
+	SuccessResponse(c, ab)
+}
+
+// UpdateAudiobookChapter changes the title, voice, settings, pipeline or
+// normalization of a chapter. The change applies from its next build.
+func UpdateAudiobookChapter(c *gin.Context) {
+	var chapter model.AudiobookChapter
+	if err := database.DB.Where("id = ? AND audiobook_id = ?", c.Param("chapter_id"), c.Param("id")).First(&chapter).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Chapter not found")
+		return
+	}
+
+	var req UpdateChapterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 2, "Invalid request")
+		return
+	}
+	if req.Title != nil {
+		if strings.TrimSpace(*req.Title) == "" {
+			ErrorResponse(c, http.StatusBadRequest, 2, "title cannot be empty")
+			return
+		}
+		chapter.Title = strings.TrimSpace(*req.Title)
+	}
+	if req.VoiceID != nil {
+		chapter.VoiceID = *req.VoiceID
+	}
+	if req.PresetID != nil {
+		chapter.PresetID = *req.PresetID
+		if _, err := chapterPreset(&chapter); err != nil {
+			ErrorResponse(c, http.StatusNotFound, 5, "Preset not found")
+			return
+		}
+	}
+	if req.Settings != nil {
+		chapter.Settings = *req.Settings
+	}
+	if req.Pipeline != nil {
+		if !audiobookPipelines[*req.Pipeline] {
+			ErrorResponse(c, http.StatusBadRequest, 3, "pipeline must be chunked or async")
+			return
+		}
+		chapter.Pipeline = *req.Pipeline
+	}
+	if req.Normalization != nil {
+		if !normalizationModes[*req.Normalization] {
+			ErrorResponse(c, http.StatusBadRequest, 6, "normalization must be rules, all or off")
+			return
+		}
+		chapter.Normalization = *req.Normalization
+	}
+	if err := database.DB.Save(&chapter).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 4, "Failed to update chapter")
+		return
+	}
+	SuccessResponse(c, chapter)
+}
+
+// BuildAudiobook queues chapters and synthesizes them in the background:
+// short chapters through sync requests per paragraph, long ones through
+// async tasks. Paragraphs whose selected take matches their text and
+// settings are reused, so after editing a few paragraphs only those are
+// synthesized again.
+func BuildAudiobook(c *gin.Context) {
+	ab, err := loadAudiobook(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Audiobook not found")
+		return
+	}
+
+	var req BuildAudiobookRequest
+	if err := bindOptionalJSON(c, &req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 8, "Invalid request body")
+		return
+	}
+	if !normalizationModes[req.Normalization] {
+		ErrorResponse(c, http.StatusBadRequest, 10, "normalization must be rules, all or off")
+		return
+	}
+
+	if running, stopping := audiobookRunState(ab.ID); stopping {
+		ErrorResponse(c, http.StatusConflict, 2, "The cancelled build is still finishing its current paragraph; try again shortly")
+		return
+	} else if running {
+		ErrorResponse(c, http.StatusConflict, 2, "Audiobook is already being built")
+		return
+	}
+
+	var project model.Project
+	database.DB.First(&project, ab.ProjectID)
+	chosen := map[uint]bool{}
+	for _, id := range req.ChapterIDs {
+		chosen[id] = true
+	}
+	var queued []uint
+	for i := range ab.Chapters {
+		ch := &ab.Chapters[i]
+		if len(chosen) > 0 && !chosen[ch.ID] {
+			continue
+		}
+		if req.VoiceID != "" {
+			ch.VoiceID = req.VoiceID
+		}
+		if req.PresetID > 0 {
+			ch.PresetID = req.PresetID
+		}
+		if req.Settings != nil {
+			ch.Settings = *req.Settings
+		}
+		if req.Normalization != "" {
+			ch.Normalization = req.Normalization
+		}
+		preset, err := chapterPreset(ch)
+		if err != nil {
+			ErrorResponse(c, http.StatusNotFound, 9, "Preset not found for chapter "+strconv.Itoa(ch.Position))
+			return
+		}
+		if ch.VoiceID == "" && (preset == nil || preset.VoiceID == "") && project.DefaultVoiceID == "" {
+			ErrorResponse(c, http.StatusBadRequest, 3, "No voice chosen for chapter "+strconv.Itoa(ch.Position))
+			return
+		}
+		queued = append(queued, ch.ID)
+	}
+	if len(queued) == 0 {
+		ErrorResponse(c, http.StatusBadRequest, 4, "No chapters to build")
+		return
+	}
+
+	// Only paragraphs without a reusable take are synthesized. Chunked
+	// chapters send each paragraph through sync T2A.
+	e := newSpeechEstimate()
+	rules := normalizationRules(ab.ProjectID)
+	for i := range ab.Chapters {
+		ch := &ab.Chapters[i]
+		if len(chosen) > 0 && !chosen[ch.ID] {
+			continue
+		}
+		takes, chars, err := chapterTakes(ab, &project, ch, rules)
+		if err != nil {
+			continue
+		}
+		ch.Chars = chars
+		for _, t := range takes {
+			if chapterPipeline(ch) == "chunked" && utf8.RuneCountInString(t.request.Text) > syncTextLimit {
+				ErrorResponse(c, http.StatusBadRequest, 6, fmt.Sprintf("A paragraph of chapter %d is longer than the sync limit of %d characters; split it or use the async pipeline", ch.Position, syncTextLimit))
+				return
+			}
+			if t.task == nil {
+				e.add(t.request)
+			}
+		}
+	}
+	if !req.check(c, e, false, 7) {
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, ch := range ab.Chapters {
+			if !chosen[ch.ID] && len(chosen) > 0 {
+				continue
+			}
+			ch.Status, ch.Error = "queued", ""
+			if err := tx.Save(&ch).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(ab).Update("key_id", req.KeyID).Error
+	})
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 5, "Failed to queue chapters")
+		return
+	}
+
+	if !startAudiobookBuild(ab.ID) {
+		ErrorResponse(c, http.StatusConflict, 2, "Audiobook is already being built")
+		return
+	}
+	ab, _ = loadAudiobook(c)
+	SuccessResponse(c, ab)
+}
+
+// CancelAudiobookBuild stops a build after its current paragraph. Async
+// tasks already submitted keep running and are picked up by the next build.
+func CancelAudiobookBuild(c *gin.Context) {
+	ab, err := loadAudiobook(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Audiobook not found")
+		return
+	}
+	if ab.Status != "building" {
+		ErrorResponse(c, http.StatusBadRequest, 2, "Audiobook is not being built")
+		return
+	}
+
+	stopAudiobookBuild(ab.ID)
+	database.DB.Model(&model.AudiobookChapter{}).Where("audiobook_id = ? AND status IN ?", ab.ID, []string{"queued", "synthesizing"}).
+		Update("status", "draft")
+	database.DB.Model(ab).Update("status", "cancelled")
+	ab, _ = loadAudiobook(c)
+	SuccessResponse(c, ab)
+}
+
+// DeleteAudiobook removes an audiobook with its chapter audio. Its project,
+// with the paragraphs and their takes, is kept. A book being built is not
+// deleted; its build is cancelled so that a later attempt succeeds.
+func DeleteAudiobook(c *gin.Context) {
+	ab, err := loadAudiobook(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Audiobook not found")
+		return
+	}
+
+	// A running build still writes into the book directory, so it has to
+	// exit before the directory can be removed
+	if running, _ := audiobookRunState(ab.ID); running {
+		stopAudiobookBuild(ab.ID)
+		ErrorResponse(c, http.StatusConflict, 3, "The build is being cancelled and still finishing its current paragraph; try again shortly")
+		return
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("audiobook_id = ?", ab.ID).Delete(&model.AudiobookChapter{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(ab).Error
+	})
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 2, "Failed to delete audiobook")
+		return
+	}
+	os.RemoveAll(audiobookDir(ab.ID))
+	SuccessResponse(c, nil)
+}
@@ -0,0 +1,278 @@
+package api
+
+import (
+	"fmt"
+	"mime/multipart"
+	"minimax-voice-workbench/internal/database"
+	"minimax-voice-workbench/internal/model"
+	"minimax-voice-workbench/pkg/minimax"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cloneJobDir is where the source audio of a clone job is kept
+func cloneJobDir(jobID uint) string {
+	return filepath.Join("uploads", "clone_jobs", strconv.Itoa(int(jobID)))
+}
+
+// saveCloneJobFile stores an uploaded file in the job directory and
+// returns its local path
+func saveCloneJobFile(c *gin.Context, jobID uint, prefix string, fileHeader *multipart.FileHeader) (string, error) {
+	dir := cloneJobDir(jobID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, prefix+"_"+filepath.Base(fileHeader.Filename))
+	if err := c.SaveUploadedFile(fileHeader, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// runCloneJob uploads the job's audio, calls Voice Clone and saves the
+// resulting voice. The outcome is written back to the job. Uploaded files
+// belong to one account, so a failover to another key re-uploads everything.
+func runCloneJob(job *model.CloneJob, keyID uint) (*model.Voice, error) {
+	// The voice already exists on Minimax when only saving it failed;
+	// cloning again would be refused as a duplicate or billed twice
+	resumeSave := job.FailedStep == "save" && job.KeyID != 0
+	job.Attempts++
+	job.Status = "pending"
+	job.FailedStep = ""
+	job.Error = ""
+
+	fail := func(step string, err error) (*model.Voice, error) {
+		job.Status = "failed"
+		job.FailedStep = step
+		job.Error = err.Error()
+		database.DB.Save(job)
+		return nil, err
+	}
+
+	if resumeSave {
+		voice, err := saveClonedVoice(job)
+		if err != nil {
+			return fail("save", err)
+		}
+		return voice, nil
+	}
+
+	step := "upload"
+	var cloneResp *minimax.VoiceCloneResponse
+	apiKey, err := withKeyFailover(keyID, func(client *minimax.Client) error {
+		step = "upload"
+		uploadResp, err := client.UploadFile(job.SourceFile, "voice_clone")
+		if err != nil {
+			return err
+		}
+		job.FileID = uploadResp.File.FileID
+
+		var clonePrompt *minimax.ClonePrompt
+		if job.PromptFile != "" {
+			promptUploadResp, err := client.UploadFile(job.PromptFile, "prompt_audio")
+			if err != nil {
+				return err
+			}
+			job.PromptFileID = promptUploadResp.File.FileID
+			clonePrompt = &minimax.ClonePrompt{
+				PromptAudio: job.PromptFileID,
+				PromptText:  job.PromptText,
+			}
+		}
+
+		step = "clone"
+		cloneResp, err = client.VoiceClone(&minimax.VoiceCloneRequest{
+			FileID:                  job.FileID,
+			VoiceID:                 job.VoiceID,
+			ClonePrompt:             clonePrompt,
+			Text:                    job.DemoText,
+			Model:                   job.Model,
+			LanguageBoost:           job.LanguageBoost,
+			NeedNoiseReduction:      job.NeedNoiseReduction,
+			NeedVolumeNormalization: job.NeedVolumeNormalization,
+			AigcWatermark:           job.AigcWatermark,
+		})
+		return err
+	})
+	if apiKey == nil {
+		return fail("key", err)
+	}
+	job.KeyID = apiKey.ID
+	if err != nil {
+		return fail(step, err)
+	}
+	job.InputSensitive = cloneResp.InputSensitive
+	job.InputSensitiveType = cloneResp.InputSensitiveType
+
+	// Download demo audio if available
+	if cloneResp.DemoAudio != "" {
+		outputDir := "generated/voices"
+		os.MkdirAll(outputDir, 0755)
+		filename := fmt.Sprintf("demo_%s.mp3", job.VoiceID)
+		demoFilePath := filepath.Join(outputDir, filename)
+
+		if err := downloadAudioFromURL(cloneResp.DemoAudio, demoFilePath); err == nil {
+			job.DemoAudio = "/files/voices/" + filename
+		}
+	}
+
+	voice, err := saveClonedVoice(job)
+	if err != nil {
+		return fail("save", err)
+	}
+	return voice, nil
+}
+
+// saveClonedVoice records the voice of a cloned job under the job's key and
+// marks the job successful. A deleted voice with the same voice_id is
+// restored, since the ID is unique even among deleted rows.
+func saveClonedVoice(job *model.CloneJob) (*model.Voice, error) {
+	voice := model.Voice{
+		Name:      job.Name,
+		VoiceID:   job.VoiceID,
+		Type:      "cloned",
+		Preview:   job.DemoAudio,
+		KeyID:     job.KeyID,
+		CreatedAt: time.Now(),
+	}
+	voice.ComputeExpiry()
+
+	var existing model.Voice
+	err := database.DB.Unscoped().Where("voice_id = ?", job.VoiceID).First(&existing).Error
+	switch {
+	case err == nil && !existing.DeletedAt.Valid:
+		return nil, errVoiceIDTaken
+	case err == nil:
+		// Replaces every column of the deleted row, so nothing of the old
+		// voice carries over
+		voice.ID = existing.ID
+		err = database.DB.Unscoped().Save(&voice).Error
+	default:
+		err = database.DB.Create(&voice).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+	if job.DemoAudio != "" {
+		recordVoicePreview(&voice, &model.VoicePreview{
+			Source: "demo",
+			Text:   job.DemoText,
+			Model:  job.Model,
+			File:   job.DemoAudio,
+			KeyID:  job.KeyID,
+		})
+	}
+
+	job.Status = "success"
+	database.DB.Save(job)
+	return &voice, nil
+}
+
+// cloneJobErrorResponse reports a failed clone job with the error code of
+// the step that failed
+func cloneJobErrorResponse(c *gin.Context, job *model.CloneJob) {
+	switch job.FailedStep {
+	case "key":
+		ErrorResponse(c, http.StatusBadRequest, 3, "Invalid API Key or No Default Key")
+	case "upload":
+		ErrorResponse(c, http.StatusInternalServerError, 6, "Minimax Upload Failed: "+job.Error)
+	case "clone":
+		ErrorResponse(c, http.StatusInternalServerError, 7, "Minimax Voice Clone Failed: "+job.Error)
+	default:
+		ErrorResponse(c, http.StatusInternalServerError, 8, "Failed to save voice to DB")
+	}
+}
+
+// ListCloneJobs returns clone jobs, newest first. Filter by status or voice_id.
+func ListCloneJobs(c *gin.Context) {
+	query := database.DB.Model(&model.CloneJob{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if voiceID := c.Query("voice_id"); voiceID != "" {
+		query = query.Where("voice_id = ?", voiceID)
+	}
+	jobs, page, err := findPage[model.CloneJob](c, query, newestFirst)
+	if err != nil {
+		listErrorResponse(c, err, 1, "Failed to fetch clone jobs")
+		return
+	}
+	PagedSuccessResponse(c, jobs, page)
+}
+
+// GetCloneJob returns a single clone job
+func GetCloneJob(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var job model.CloneJob
+	if err := database.DB.First(&job, id).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Clone job not found")
+		return
+	}
+	SuccessResponse(c, job)
+}
+
+// RetryCloneJobRequest optionally overrides the key of the previous attempt
+type RetryCloneJobRequest struct {
+	KeyID uint `json:"key_id"`
+}
+
+// RetryCloneJob runs a failed clone job again from its stored audio and parameters
+func RetryCloneJob(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	var req RetryCloneJobRequest
+	if err := bindOptionalJSON(c, &req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 5, "Invalid request body")
+		return
+	}
+
+	var job model.CloneJob
+	if err := database.DB.First(&job, id).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Clone job not found")
+		return
+	}
+	if job.Status == "success" {
+		ErrorResponse(c, http.StatusBadRequest, 2, "Clone job already succeeded")
+		return
+	}
+	// A job that only failed to save resumes there and needs no audio
+	if _, err := os.Stat(job.SourceFile); err != nil && job.FailedStep != "save" {
+		ErrorResponse(c, http.StatusGone, 4, "Source audio of this job is no longer available")
+		return
+	}
+
+	keyID := req.KeyID
+	if keyID == 0 {
+		keyID = job.KeyID
+	}
+
+	voice, err := runCloneJob(&job, keyID)
+	if err != nil {
+		cloneJobErrorResponse(c, &job)
+		return
+	}
+	SuccessResponse(c, gin.H{"job": job, "voice": voice})
+}
+
+// DeleteCloneJob removes a clone job and its stored audio
+func DeleteCloneJob(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	var job model.CloneJob
+	if err := database.DB.First(&job, id).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Clone job not found")
+		return
+	}
+
+	os.RemoveAll(cloneJobDir(job.ID))
+	if err := database.DB.Delete(&job).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 9, "Failed to delete clone job")
+		return
+	}
+	SuccessResponse(c, nil)
+}
@@ -0,0 +1,302 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"minimax-voice-workbench/internal/audio"
+	"minimax-voice-workbench/internal/database"
+	"minimax-voice-workbench/internal/model"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cloneSampleDir is where prepared clone samples are kept
+const cloneSampleDir = "uploads/clone_samples"
+
+// Seconds a range may exceed the clone limit by when silence is stripped
+// from it
+const cloneSampleSilenceMargin = 60.0
+
+// parseSeconds reads an optional form value in seconds
+func parseSeconds(c *gin.Context, field string) (float64, error) {
+	value := c.PostForm(field)
+	if value == "" {
+		return 0, nil
+	}
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds < 0 {
+		return 0, fmt.Errorf("invalid %s", field)
+	}
+	return seconds, nil
+}
+
+// PrepareCloneSample cuts a clip from an uploaded WAV recording, optionally
+// strips leading and trailing silence and downmixes to mono, and stores the
+// result as a 16-bit WAV that CloneVoice accepts via sample_id
+func PrepareCloneSample(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 1, "File upload required")
+		return
+	}
+
+	start, err := parseSeconds(c, "start")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 2, err.Error())
+		return
+	}
+	end, err := parseSeconds(c, "end")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 2, err.Error())
+		return
+	}
+	if end > 0 && end <= start {
+		ErrorResponse(c, http.StatusBadRequest, 2, "end must be after start")
+		return
+	}
+	stripSilence := c.PostForm("strip_silence") == "true"
+	mono := c.PostForm("mono") == "true"
+	threshold := audio.DefaultSilenceThresholdDB
+	if value := c.PostForm("silence_threshold"); value != "" {
+		threshold, err = strconv.ParseFloat(value, 64)
+		if err != nil || threshold >= 0 {
+			ErrorResponse(c, http.StatusBadRequest, 2, "silence_threshold must be a negative dBFS value")
+			return
+		}
+	}
+
+	name := c.PostForm("name")
+	if name == "" {
+		name = strings.TrimSuffix(fileHeader.Filename, filepath.Ext(fileHeader.Filename))
+	}
+
+	// 1. Decode the requested range
+	f, err := fileHeader.Open()
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 4, "Failed to read file")
+		return
+	}
+	defer f.Close()
+
+	// Check the length from the header, before holding the samples
+	duration, err := audio.WAVRangeDuration(f, fileHeader.Size, start, end)
+	if err == audio.ErrUnsupportedFormat {
+		ErrorResponse(c, http.StatusBadRequest, 3, "Only WAV recordings can be prepared")
+		return
+	}
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 4, "Failed to decode audio: "+err.Error())
+		return
+	}
+	maxRange := cloneMaxDuration
+	if stripSilence {
+		maxRange += cloneSampleSilenceMargin
+	}
+	if duration > maxRange {
+		ErrorResponse(c, http.StatusBadRequest, 2, fmt.Sprintf("The selected range is %.0f seconds; choose at most %.0f seconds with start and end", duration, maxRange))
+		return
+	}
+
+	pcm, err := audio.DecodeWAV(f, fileHeader.Size, start, end)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 4, "Failed to decode audio: "+err.Error())
+		return
+	}
+
+	// 2. Process
+	if mono {
+		pcm = pcm.Mono()
+	}
+	if stripSilence {
+		pcm = pcm.StripSilence(threshold, audio.DefaultSilencePadding)
+		if pcm == nil {
+			ErrorResponse(c, http.StatusBadRequest, 5, "The selected range contains only silence")
+			return
+		}
+	}
+
+	// 3. Encode and check the clip against the clone limits
+	var buf bytes.Buffer
+	if err := audio.EncodeWAV(&buf, pcm); err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 7, "Failed to encode audio")
+		return
+	}
+	info, err := audio.Probe(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	validation := newAudioValidation()
+	validation.File = validation.checkAudio("file", int64(buf.Len()), info, err, cloneMinDuration, cloneMaxDuration)
+	if !validation.Valid() {
+		ErrorResponseWithData(c, http.StatusBadRequest, 6, "Prepared clip failed validation", validation)
+		return
+	}
+
+	// 4. Store
+	sample := model.CloneSample{
+		Name:           name,
+		SourceFilename: fileHeader.Filename,
+		Start:          start,
+		End:            end,
+		StripSilence:   stripSilence,
+		Mono:           mono,
+		Duration:       info.Duration,
+		SampleRate:     info.SampleRate,
+		Channels:       info.Channels,
+		Size:           info.Size,
+		PeakDB:         *info.PeakDB,
+		RMSDB:          *info.RMSDB,
+		Warnings:       validation.WarningMessages(),
+	}
+	if err := database.DB.Create(&sample).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 7, "Failed to save sample")
+		return
+	}
+
+	sample.File = filepath.Join(cloneSampleDir, fmt.Sprintf("%d.wav", sample.ID))
+	os.MkdirAll(cloneSampleDir, 0755)
+	if err := os.WriteFile(sample.File, buf.Bytes(), 0644); err != nil {
+		database.DB.Unscoped().Delete(&sample)
+		ErrorResponse(c, http.StatusInternalServerError, 7, "Failed to save sample")
+		return
+	}
+	database.DB.Save(&sample)
+
+	SuccessResponse(c, sample)
+}
+
+// ListCloneSamples returns prepared clone samples, newest first
+func ListCloneSamples(c *gin.Context) {
+	samples, page, err := findPage[model.CloneSample](c, database.DB.Model(&model.CloneSample{}), newestFirst)
+	if err != nil {
+		listErrorResponse(c, err, 1, "Failed to fetch samples")
+		return
+	}
+	PagedSuccessResponse(c, samples, page)
+}
+
+// GetCloneSampleAudio streams the prepared WAV for listening
+func GetCloneSampleAudio(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var sample model.CloneSample
+	if err := database.DB.First(&sample, id).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Sample not found")
+		return
+	}
+	c.File(sample.File)
+}
+
+// DeleteCloneSample removes a prepared sample and its file. Clone jobs keep
+// their own copy, so they can still be retried.
+func DeleteCloneSample(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var sample model.CloneSample
+	if err := database.DB.First(&sample, id).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Sample not found")
+		return
+	}
+
+	os.Remove(sample.File)
+	if err := database.DB.Delete(&sample).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 2, "Failed to delete sample")
+		return
+	}
+	SuccessResponse(c, nil)
+}
+
+// cloneSource is the sample of a clone request: either an upload or a
+// prepared sample referenced by sample_id
+type cloneSource struct {
+	upload *multipart.FileHeader
+	sample *model.CloneSample
+}
+
+// cloneSourceFromRequest reads sample_id or the file field. A sample_id
+// that does not exist is an error; a missing file returns nil.
+func cloneSourceFromRequest(c *gin.Context) (*cloneSource, error) {
+	if c.PostForm("sample_id") != "" {
+		sampleID, _ := strconv.Atoi(c.PostForm("sample_id"))
+		var sample model.CloneSample
+		if err := database.DB.First(&sample, sampleID).Error; err != nil {
+			return nil, errors.New("sample not found")
+		}
+		return &cloneSource{sample: &sample}, nil
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return nil, nil
+	}
+	return &cloneSource{upload: fileHeader}, nil
+}
+
+// filename is the name shown for the source in clone jobs
+func (s *cloneSource) filename() string {
+	if s.sample != nil {
+		return s.sample.Name + ".wav"
+	}
+	return s.upload.Filename
+}
+
+// sampleID is the ID of the prepared sample, or 0 for uploads
+func (s *cloneSource) sampleID() uint {
+	if s.sample != nil {
+		return s.sample.ID
+	}
+	return 0
+}
+
+// probe reads the size and audio properties of the source
+func (s *cloneSource) probe() (int64, *audio.Info, error) {
+	if s.upload != nil {
+		info, err := probeUpload(s.upload)
+		return s.upload.Size, info, err
+	}
+
+	f, err := os.Open(s.sample.File)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return 0, nil, err
+	}
+	info, err := audio.Probe(f, stat.Size())
+	return stat.Size(), info, err
+}
+
+// save copies the source into the job directory. Jobs keep their own copy
+// so they can be retried after the sample is deleted.
+func (s *cloneSource) save(c *gin.Context, jobID uint) (string, error) {
+	if s.upload != nil {
+		return saveCloneJobFile(c, jobID, "source", s.upload)
+	}
+
+	dir := cloneJobDir(jobID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	src, err := os.Open(s.sample.File)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	path := filepath.Join(dir, fmt.Sprintf("source_sample_%d.wav", s.sample.ID))
+	dst, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return "", err
+	}
+	return path, nil
+}
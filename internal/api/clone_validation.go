@@ -0,0 +1,159 @@
+package api
+
+import (
+	"fmt"
+	"mime/multipart"
+	"minimax-voice-workbench/internal/audio"
+	"minimax-voice-workbench/internal/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limits Minimax applies to voice clone audio
+const (
+	cloneMaxFileSize    = 20 << 20 // 20MB
+	cloneMinDuration    = 10.0     // Seconds
+	cloneMaxDuration    = 300.0
+	promptMaxDuration   = 8.0
+	cloneMinSampleRate  = 16000 // Below this quality suffers noticeably
+	cloneClippingPeakDB = -0.1
+	cloneQuietRMSDB     = -40.0
+	cloneLoudRMSDB      = -6.0
+)
+
+var cloneFormats = map[string]bool{"mp3": true, "m4a": true, "wav": true}
+
+// AudioIssue is one problem found in clone audio
+type AudioIssue struct {
+	Field   string `json:"field"` // file, prompt_file or prompt_text
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// AudioValidation is the result of checking clone audio before upload.
+// Errors would make Minimax reject the clone; warnings only affect quality.
+type AudioValidation struct {
+	File       *audio.Info  `json:"file,omitempty"`
+	PromptFile *audio.Info  `json:"prompt_file,omitempty"`
+	Errors     []AudioIssue `json:"errors"`
+	Warnings   []AudioIssue `json:"warnings"`
+}
+
+// Valid reports whether no errors were found
+func (v *AudioValidation) Valid() bool {
+	return len(v.Errors) == 0
+}
+
+// WarningMessages returns the warnings as plain text
+func (v *AudioValidation) WarningMessages() model.StringList {
+	messages := model.StringList{}
+	for _, w := range v.Warnings {
+		messages = append(messages, w.Message)
+	}
+	return messages
+}
+
+func (v *AudioValidation) addError(field, code, format string, args ...interface{}) {
+	v.Errors = append(v.Errors, AudioIssue{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *AudioValidation) addWarning(field, code, format string, args ...interface{}) {
+	v.Warnings = append(v.Warnings, AudioIssue{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// probeUpload reads the properties of an uploaded file without saving it
+func probeUpload(fileHeader *multipart.FileHeader) (*audio.Info, error) {
+	f, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return audio.Probe(f, fileHeader.Size)
+}
+
+// newAudioValidation returns an empty validation result
+func newAudioValidation() *AudioValidation {
+	return &AudioValidation{Errors: []AudioIssue{}, Warnings: []AudioIssue{}}
+}
+
+// validateCloneAudio checks the clone sample and the optional prompt audio
+// against Minimax limits. promptFile may be nil.
+func validateCloneAudio(source *cloneSource, promptFile *multipart.FileHeader, promptText string) *AudioValidation {
+	v := newAudioValidation()
+
+	size, info, err := source.probe()
+	v.File = v.checkAudio("file", size, info, err, cloneMinDuration, cloneMaxDuration)
+
+	if promptFile != nil {
+		info, err := probeUpload(promptFile)
+		v.PromptFile = v.checkAudio("prompt_file", promptFile.Size, info, err, 0, promptMaxDuration)
+		if promptText == "" {
+			v.addWarning("prompt_text", "prompt_text_missing",
+				"Prompt audio works best with its transcript in prompt_text")
+		}
+	}
+
+	return v
+}
+
+// checkAudio validates the probe result of one file and returns it
+// when the file could be read
+func (v *AudioValidation) checkAudio(field string, size int64, info *audio.Info, err error, minDuration, maxDuration float64) *audio.Info {
+	if size > cloneMaxFileSize {
+		v.addError(field, "file_too_large", "File is %.1fMB, the limit is 20MB", float64(size)/(1<<20))
+	}
+
+	if err == audio.ErrUnsupportedFormat {
+		v.addError(field, "unsupported_format", "Only mp3, m4a and wav files are accepted")
+		return nil
+	}
+	if err != nil {
+		v.addError(field, "unreadable", "Could not read audio: %v", err)
+		return nil
+	}
+	if !cloneFormats[info.Format] {
+		v.addError(field, "unsupported_format", "Only mp3, m4a and wav files are accepted")
+	}
+
+	if minDuration > 0 && info.Duration < minDuration {
+		v.addError(field, "too_short", "Audio is %.1fs, at least %.0fs is required", info.Duration, minDuration)
+	}
+	if info.Duration > maxDuration {
+		v.addError(field, "too_long", "Audio is %.1fs, at most %.0fs is allowed", info.Duration, maxDuration)
+	}
+
+	if info.SampleRate > 0 && info.SampleRate < cloneMinSampleRate {
+		v.addWarning(field, "low_sample_rate", "Sample rate is %dHz, 16kHz or more is recommended", info.SampleRate)
+	}
+	if info.PeakDB != nil && *info.PeakDB >= cloneClippingPeakDB {
+		v.addWarning(field, "clipping", "Audio peaks at %.1f dBFS and is likely clipped", *info.PeakDB)
+	}
+	if info.RMSDB != nil {
+		switch {
+		case *info.RMSDB < cloneQuietRMSDB:
+			v.addWarning(field, "too_quiet", "Average level is %.1f dBFS, the recording is very quiet", *info.RMSDB)
+		case *info.RMSDB > cloneLoudRMSDB:
+			v.addWarning(field, "too_loud", "Average level is %.1f dBFS, the recording is very loud", *info.RMSDB)
+		}
+	}
+
+	return info
+}
+
+// ValidateCloneAudio checks clone audio without cloning, so the UI can
+// report problems as soon as a file is picked
+func ValidateCloneAudio(c *gin.Context) {
+	source, err := cloneSourceFromRequest(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 2, "Sample not found")
+		return
+	}
+	if source == nil {
+		ErrorResponse(c, http.StatusBadRequest, 1, "File upload required")
+		return
+	}
+	promptFileHeader, _ := c.FormFile("prompt_file")
+
+	SuccessResponse(c, validateCloneAudio(source, promptFileHeader, c.PostForm("prompt_text")))
+}
@@ -2,9 +2,12 @@ package api
 
 import (
 	"errors"
+	"io"
 	"minimax-voice-workbench/internal/database"
 	"minimax-voice-workbench/internal/model"
 	"net/http"
+	"path/filepath"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,6 +19,24 @@ type Response struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
+// PagedResponse is a Response whose data is one page of a list
+type PagedResponse struct {
+	Response
+	Pagination *Pagination `json:"pagination"`
+}
+
+// Pagination describes the page returned in a PagedResponse. Offset pages
+// set page; cursor pages set next_cursor while more rows follow.
+type Pagination struct {
+	Total      int64  `json:"total"`
+	Page       int    `json:"page,omitempty"`
+	PageSize   int    `json:"page_size"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Sort       string `json:"sort"`
+	Order      string `json:"order"`
+}
+
 // SuccessResponse sends a standard success response
 func SuccessResponse(c *gin.Context, data interface{}) {
 	c.JSON(http.StatusOK, Response{
@@ -25,6 +46,14 @@ func SuccessResponse(c *gin.Context, data interface{}) {
 	})
 }
 
+// PagedSuccessResponse sends a page of a list
+func PagedSuccessResponse(c *gin.Context, data interface{}, pagination *Pagination) {
+	c.JSON(http.StatusOK, PagedResponse{
+		Response:   Response{Code: 0, Message: "success", Data: data},
+		Pagination: pagination,
+	})
+}
+
 // ErrorResponse sends a standard error response
 func ErrorResponse(c *gin.Context, httpCode int, errCode int, message string) {
 	c.JSON(httpCode, Response{
@@ -33,6 +62,24 @@ func ErrorResponse(c *gin.Context, httpCode int, errCode int, message string) {
 	})
 }
 
+// ErrorResponseWithData sends an error response with details in data
+func ErrorResponseWithData(c *gin.Context, httpCode int, errCode int, message string, data interface{}) {
+	c.JSON(httpCode, Response{
+		Code:    errCode,
+		Message: message,
+		Data:    data,
+	})
+}
+
+// bindOptionalJSON binds a body whose fields are all optional. An empty
+// body leaves obj as it is; a malformed one is an error.
+func bindOptionalJSON(c *gin.Context, obj interface{}) error {
+	if err := c.ShouldBindJSON(obj); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
 // getEffectiveKey returns the specified key or the default key
 func getEffectiveKey(keyID uint) (*model.ApiKey, error) {
 	var apiKey model.ApiKey
@@ -55,3 +102,7 @@ func getEffectiveKey(keyID uint) (*model.ApiKey, error) {
 	return &apiKey, nil
 }
 
+// generatedFilePath maps a "/files/..." URL to its file under ./generated
+func generatedFilePath(webPath string) string {
+	return filepath.Join("generated", strings.TrimPrefix(webPath, "/files/"))
+}
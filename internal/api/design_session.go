@@ -0,0 +1,348 @@
+package api
+
+import (
+	"encoding/hex"
+	"fmt"
+	"minimax-voice-workbench/internal/database"
+	"minimax-voice-workbench/internal/model"
+	"minimax-voice-workbench/pkg/minimax"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateDesignSessionRequest starts a design session
+type CreateDesignSessionRequest struct {
+	Name  string `json:"name"`
+	KeyID uint   `json:"key_id"`
+}
+
+// AddDesignCandidateRequest designs one more candidate in a session
+type AddDesignCandidateRequest struct {
+	Prompt      string `json:"prompt" binding:"required"`
+	PreviewText string `json:"preview_text" binding:"required"`
+	VoiceID     string `json:"voice_id"` // Optional custom Minimax voice ID
+}
+
+// PromoteDesignCandidateRequest picks the candidate that joins the library
+type PromoteDesignCandidateRequest struct {
+	CandidateID uint   `json:"candidate_id" binding:"required"`
+	Name        string `json:"name"` // Overrides the session name
+}
+
+// CandidateCleanup reports the cleanup of one discarded candidate
+type CandidateCleanup struct {
+	CandidateID uint   `json:"candidate_id"`
+	VoiceID     string `json:"voice_id"`
+	Error       string `json:"error,omitempty"`
+}
+
+// loadDesignSession loads a session with its candidates, oldest first
+func loadDesignSession(c *gin.Context) (*model.DesignSession, error) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var session model.DesignSession
+	err := database.DB.Preload("Candidates", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at asc")
+	}).First(&session, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// discardDesignCandidate deletes a candidate's voice on Minimax and its
+// trial audio. A failed remote delete is recorded on the candidate.
+func discardDesignCandidate(candidate *model.DesignCandidate) CandidateCleanup {
+	result := CandidateCleanup{CandidateID: candidate.ID, VoiceID: candidate.VoiceID}
+
+	_, err := withKey(candidate.KeyID, func(client *minimax.Client) error {
+		return client.DeleteVoice("voice_generation", candidate.VoiceID)
+	})
+	if err != nil {
+		result.Error = err.Error()
+		candidate.CleanupError = err.Error()
+	} else {
+		candidate.RemoteDeleted = true
+		candidate.CleanupError = ""
+	}
+
+	if candidate.TrialAudio != "" {
+		os.Remove(generatedFilePath(candidate.TrialAudio))
+		candidate.TrialAudio = ""
+	}
+	candidate.Status = "discarded"
+	database.DB.Save(candidate)
+	return result
+}
+
+// CreateDesignSession starts a new, empty design session
+func CreateDesignSession(c *gin.Context) {
+	var req CreateDesignSessionRequest
+	if err := bindOptionalJSON(c, &req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 2, "Invalid request body")
+		return
+	}
+
+	session := model.DesignSession{Name: req.Name, KeyID: req.KeyID, Status: "open"}
+	if err := database.DB.Create(&session).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 1, "Failed to create design session")
+		return
+	}
+	session.Candidates = []model.DesignCandidate{}
+	SuccessResponse(c, session)
+}
+
+// ListDesignSessions returns design sessions with their candidates, newest
+// first. Filter by status.
+func ListDesignSessions(c *gin.Context) {
+	query := database.DB.Preload("Candidates", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at asc")
+	})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	sessions, page, err := findPage[model.DesignSession](c, query, newestFirst)
+	if err != nil {
+		listErrorResponse(c, err, 1, "Failed to fetch design sessions")
+		return
+	}
+	PagedSuccessResponse(c, sessions, page)
+}
+
+// GetDesignSession returns one session with its candidates
+func GetDesignSession(c *gin.Context) {
+	session, err := loadDesignSession(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Design session not found")
+		return
+	}
+	SuccessResponse(c, session)
+}
+
+// AddDesignCandidate designs a voice from the prompt and keeps it as a
+// candidate of the session, next to the earlier ones
+func AddDesignCandidate(c *gin.Context) {
+	session, err := loadDesignSession(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Design session not found")
+		return
+	}
+	if session.Status != "open" {
+		ErrorResponse(c, http.StatusBadRequest, 2, "Design session is closed")
+		return
+	}
+
+	var req AddDesignCandidateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 3, "prompt and preview_text are required")
+		return
+	}
+	if req.VoiceID != "" {
+		if err := checkVoiceID(req.VoiceID); err != nil {
+			voiceIDErrorResponse(c, err, 4, 5)
+			return
+		}
+	}
+
+	var resp *minimax.VoiceDesignResponse
+	apiKey, err := withKeyFailover(session.KeyID, func(client *minimax.Client) error {
+		var err error
+		resp, err = client.VoiceDesign(&minimax.VoiceDesignRequest{
+			Prompt:      req.Prompt,
+			PreviewText: req.PreviewText,
+			VoiceID:     req.VoiceID,
+		})
+		return err
+	})
+	if apiKey == nil {
+		ErrorResponse(c, http.StatusBadRequest, 6, "Invalid API Key or No Default Key")
+		return
+	}
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 7, "Design Failed: "+err.Error())
+		return
+	}
+
+	candidate := model.DesignCandidate{
+		SessionID:   session.ID,
+		Prompt:      req.Prompt,
+		PreviewText: req.PreviewText,
+		VoiceID:     resp.VoiceID,
+		KeyID:       apiKey.ID,
+		Status:      "active",
+	}
+	if err := database.DB.Create(&candidate).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 8, "Failed to save candidate")
+		return
+	}
+
+	// resp.TrialAudio is hex encoded
+	if audioBytes, err := hex.DecodeString(resp.TrialAudio); err == nil {
+		outputDir := "generated/voices"
+		os.MkdirAll(outputDir, 0755)
+		filename := fmt.Sprintf("design_%d_%d.mp3", session.ID, candidate.ID)
+		if err := os.WriteFile(filepath.Join(outputDir, filename), audioBytes, 0644); err == nil {
+			candidate.TrialAudio = "/files/voices/" + filename
+			database.DB.Save(&candidate)
+		}
+	}
+
+	SuccessResponse(c, candidate)
+}
+
+// DiscardDesignCandidate removes one candidate locally and on Minimax
+func DiscardDesignCandidate(c *gin.Context) {
+	var candidate model.DesignCandidate
+	err := database.DB.Where("id = ? AND session_id = ?", c.Param("candidate_id"), c.Param("id")).
+		First(&candidate).Error
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Candidate not found")
+		return
+	}
+	if candidate.Status != "active" {
+		ErrorResponse(c, http.StatusBadRequest, 2, "Candidate is not active")
+		return
+	}
+
+	SuccessResponse(c, discardDesignCandidate(&candidate))
+}
+
+// PromoteDesignCandidate saves the chosen candidate as a library voice,
+// closes the session and cleans up every other candidate
+func PromoteDesignCandidate(c *gin.Context) {
+	session, err := loadDesignSession(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Design session not found")
+		return
+	}
+	if session.Status != "open" {
+		ErrorResponse(c, http.StatusBadRequest, 2, "Design session is closed")
+		return
+	}
+
+	var req PromoteDesignCandidateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 3, "candidate_id is required")
+		return
+	}
+
+	var chosen *model.DesignCandidate
+	for i := range session.Candidates {
+		if session.Candidates[i].ID == req.CandidateID {
+			chosen = &session.Candidates[i]
+		}
+	}
+	if chosen == nil || chosen.Status != "active" {
+		ErrorResponse(c, http.StatusBadRequest, 4, "Candidate not found or not active")
+		return
+	}
+
+	name := req.Name
+	if name == "" {
+		name = session.Name
+	}
+	if name == "" {
+		name = "Designed " + chosen.VoiceID[:8]
+	}
+
+	// The trial audio becomes the voice preview
+	previewPath := ""
+	if chosen.TrialAudio != "" {
+		filename := fmt.Sprintf("preview_%s.mp3", chosen.VoiceID)
+		if err := os.Rename(generatedFilePath(chosen.TrialAudio), filepath.Join("generated/voices", filename)); err == nil {
+			previewPath = "/files/voices/" + filename
+		}
+	}
+
+	voice := model.Voice{
+		Name:    name,
+		VoiceID: chosen.VoiceID,
+		Type:    "generated",
+		Preview: previewPath,
+		KeyID:   chosen.KeyID,
+	}
+	voice.ComputeExpiry()
+	if err := database.DB.Create(&voice).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 5, "Failed to save generated voice")
+		return
+	}
+	if previewPath != "" {
+		recordVoicePreview(&voice, &model.VoicePreview{
+			Source: "design",
+			Text:   chosen.PreviewText,
+			File:   previewPath,
+			KeyID:  chosen.KeyID,
+		})
+	}
+
+	chosen.Status = "promoted"
+	chosen.TrialAudio = previewPath
+	database.DB.Save(chosen)
+	session.Status = "promoted"
+	session.VoiceID = chosen.VoiceID
+	database.DB.Omit("Candidates").Save(session)
+
+	cleanup := []CandidateCleanup{}
+	for i := range session.Candidates {
+		if session.Candidates[i].Status == "active" {
+			cleanup = append(cleanup, discardDesignCandidate(&session.Candidates[i]))
+		}
+	}
+
+	SuccessResponse(c, gin.H{"voice": voice, "cleanup": cleanup})
+}
+
+// DeleteDesignSession discards every remaining candidate and the session
+func DeleteDesignSession(c *gin.Context) {
+	session, err := loadDesignSession(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Design session not found")
+		return
+	}
+
+	cleanup := []CandidateCleanup{}
+	for i := range session.Candidates {
+		if session.Candidates[i].Status == "active" {
+			cleanup = append(cleanup, discardDesignCandidate(&session.Candidates[i]))
+		}
+	}
+
+	if session.Status == "open" {
+		session.Status = "discarded"
+		database.DB.Omit("Candidates").Save(session)
+	}
+	if err := database.DB.Delete(&model.DesignSession{}, session.ID).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 9, "Failed to delete design session")
+		return
+	}
+	SuccessResponse(c, gin.H{"cleanup": cleanup})
+}
+
+// RetryDesignCleanup retries the remote delete of discarded candidates
+// whose cleanup failed, e.g. while their key was cooling down
+func RetryDesignCleanup(c *gin.Context) {
+	var candidates []model.DesignCandidate
+	database.DB.Where("status = ? AND remote_deleted = ?", "discarded", false).Find(&candidates)
+
+	cleanup := []CandidateCleanup{}
+	for i := range candidates {
+		cleanup = append(cleanup, discardDesignCandidate(&candidates[i]))
+	}
+	SuccessResponse(c, cleanup)
+}
+
+// designCandidateVoiceIDs returns the voice IDs of candidates that are not
+// in the library, so that voice sync does not import them
+func designCandidateVoiceIDs() map[string]bool {
+	var candidates []model.DesignCandidate
+	database.DB.Select("voice_id").Where("status <> ?", "promoted").Find(&candidates)
+	ids := make(map[string]bool, len(candidates))
+	for _, candidate := range candidates {
+		ids[candidate.VoiceID] = true
+	}
+	return ids
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"fmt"
+	"minimax-voice-workbench/internal/estimate"
+	"minimax-voice-workbench/pkg/minimax"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Most characters Minimax accepts in one sync T2A request
+const syncTextLimit = 10000
+
+// speechPrices are those of the platform the client talks to
+var speechPrices = estimate.ForEndpoint(minimax.BaseURL)
+
+// ModelCost is the cost of an estimate with one model
+type ModelCost struct {
+	Model string  `json:"model"`
+	Cost  float64 `json:"cost"`
+}
+
+// SpeechEstimate sums up one or more synthesis requests
+type SpeechEstimate struct {
+	Requests      int     `json:"requests"`
+	Chars         int     `json:"chars"`
+	BillableChars int     `json:"billable_chars"` // Chinese characters count twice
+	Duration      float64 `json:"duration"`       // Seconds of audio
+	// Cost with the model of each request. Models without a known price
+	// add nothing.
+	Cost     float64     `json:"cost"`
+	Costs    []ModelCost `json:"costs"`    // Cost if every request used the model
+	Currency string      `json:"currency"` // Of every cost, as billed by the endpoint
+	// Set when a request is too long for sync T2A and must go through
+	// async tasks or be split
+	ExceedsSyncLimit bool `json:"exceeds_sync_limit"`
+	SyncLimit        int  `json:"sync_limit"`
+}
+
+func newSpeechEstimate() *SpeechEstimate {
+	e := &SpeechEstimate{SyncLimit: syncTextLimit, Currency: speechPrices.Currency}
+	for _, p := range speechPrices.Prices {
+		e.Costs = append(e.Costs, ModelCost{Model: p.Model})
+	}
+	return e
+}
+
+// add counts one request
+func (e *SpeechEstimate) add(req *minimax.T2ARequest) {
+	chars := utf8.RuneCountInString(req.Text)
+	billable := estimate.BillableChars(req.Text)
+	e.Requests++
+	e.Chars += chars
+	e.BillableChars += billable
+	e.Duration += estimate.Duration(req.Text, req.VoiceSetting.Speed)
+	if cost, ok := speechPrices.Cost(req.Model, billable); ok {
+		e.Cost += cost
+	}
+	for i := range e.Costs {
+		cost, _ := speechPrices.Cost(e.Costs[i].Model, billable)
+		e.Costs[i].Cost += cost
+	}
+	if chars > syncTextLimit {
+		e.ExceedsSyncLimit = true
+	}
+}
+
+// BatchLimit is accepted by requests that start a batch of syntheses
+type BatchLimit struct {
+	MaxCost float64 `json:"max_cost"` // Refuse to start above this estimated cost, in the estimate currency; 0 for no limit
+	DryRun  bool    `json:"dry_run"`  // Return the estimate without starting
+}
+
+// check answers a dry run with the estimate, and refuses batches over the
+// cost limit or, when sync is set, with a request too long for sync T2A.
+// It returns whether the batch may start.
+func (l BatchLimit) check(c *gin.Context, e *SpeechEstimate, sync bool, code int) bool {
+	switch {
+	case sync && e.ExceedsSyncLimit:
+		ErrorResponseWithData(c, http.StatusBadRequest, code, fmt.Sprintf("Text is longer than the sync limit of %d characters", syncTextLimit), e)
+	case l.MaxCost > 0 && e.Cost > l.MaxCost:
+		ErrorResponseWithData(c, http.StatusBadRequest, code, fmt.Sprintf("Estimated cost %.4f %s is over max_cost", e.Cost, e.Currency), e)
+	case l.DryRun:
+		SuccessResponse(c, e)
+	default:
+		return true
+	}
+	return false
+}
+
+// EstimateSpeech returns the billable characters, cost per model and audio
+// duration of a synthesis request, taking the same body as GenerateSpeech.
+// Markup and normalization are applied first, so the text estimated is the
+// text that would be sent.
+func EstimateSpeech(c *gin.Context) {
+	var req GenerateSpeechRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 2, "Invalid request body")
+		return
+	}
+
+	input, ok := checkSpeechRequest(c, &req)
+	if !ok {
+		return
+	}
+	if req.Text == "" {
+		// Files uploaded before inputs were stored have no text to count
+		if input == nil {
+			ErrorResponse(c, http.StatusNotFound, 13, "Text of the input file is not stored")
+			return
+		}
+		req.Text = input.Text
+	}
+
+	e := newSpeechEstimate()
+	e.add(&req.T2ARequest)
+	SuccessResponse(c, e)
+}
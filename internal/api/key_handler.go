@@ -10,15 +10,22 @@ import (
 	"gorm.io/gorm"
 )
 
+// Sortable columns of ListKeys
+var keySortFields = map[string]string{
+	"id":         "id",
+	"remark":     "remark",
+	"platform":   "platform",
+	"created_at": "created_at",
+}
+
 // ListKeys returns all API keys
 func ListKeys(c *gin.Context) {
-	var keys []model.ApiKey
-	result := database.DB.Find(&keys)
-	if result.Error != nil {
-		ErrorResponse(c, http.StatusInternalServerError, 1, "Failed to fetch keys")
+	keys, page, err := findPage[model.ApiKey](c, database.DB.Model(&model.ApiKey{}), listSort{Fields: keySortFields, Default: "id", Order: "asc"})
+	if err != nil {
+		listErrorResponse(c, err, 1, "Failed to fetch keys")
 		return
 	}
-	SuccessResponse(c, keys)
+	PagedSuccessResponse(c, keys, page)
 }
 
 // AddKeyRequest defines the body for adding a key
@@ -26,6 +33,7 @@ type AddKeyRequest struct {
 	Platform string `json:"platform"`
 	Key      string `json:"key" binding:"required"`
 	Remark   string `json:"remark"`
+	Pool     string `json:"pool"`
 }
 
 // AddKey creates a new API key
@@ -39,6 +47,9 @@ func AddKey(c *gin.Context) {
 	if req.Platform == "" {
 		req.Platform = "minimax"
 	}
+	if req.Pool == "" {
+		req.Pool = "default"
+	}
 
 	// Check if this is the first key
 	var count int64
@@ -50,6 +61,7 @@ func AddKey(c *gin.Context) {
 		Key:       req.Key,
 		Remark:    req.Remark,
 		IsDefault: isDefault,
+		Pool:      req.Pool,
 	}
 
 	result := database.DB.Create(&apiKey)
@@ -61,6 +73,78 @@ func AddKey(c *gin.Context) {
 	SuccessResponse(c, apiKey)
 }
 
+// UpdateKeyRequest defines the body for updating a key
+type UpdateKeyRequest struct {
+	Remark *string `json:"remark"`
+	Pool   *string `json:"pool"`
+}
+
+// UpdateKey changes the remark or failover pool of a key
+func UpdateKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 4, "Invalid ID format")
+		return
+	}
+
+	var req UpdateKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 2, "Invalid request body")
+		return
+	}
+
+	var apiKey model.ApiKey
+	if err := database.DB.First(&apiKey, id).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, 6, "Key not found")
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Remark != nil {
+		updates["remark"] = *req.Remark
+	}
+	if req.Pool != nil {
+		pool := *req.Pool
+		if pool == "" {
+			pool = "default"
+		}
+		updates["pool"] = pool
+	}
+	if len(updates) > 0 {
+		if err := database.DB.Model(&apiKey).Updates(updates).Error; err != nil {
+			ErrorResponse(c, http.StatusInternalServerError, 8, "Failed to update key")
+			return
+		}
+	}
+
+	SuccessResponse(c, apiKey)
+}
+
+// ResetKeyHealth clears the cooldown of a key so failover uses it again
+func ResetKeyHealth(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 4, "Invalid ID format")
+		return
+	}
+
+	result := database.DB.Model(&model.ApiKey{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"cooldown_until": nil,
+		"failure_count":  0,
+		"last_error":     "",
+	})
+	if result.Error != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 8, "Failed to update key")
+		return
+	}
+	if result.RowsAffected == 0 {
+		ErrorResponse(c, http.StatusNotFound, 6, "Key not found")
+		return
+	}
+
+	SuccessResponse(c, nil)
+}
+
 // SetDefaultKey sets a key as default
 func SetDefaultKey(c *gin.Context) {
 	idStr := c.Param("id")
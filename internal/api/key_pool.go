@@ -0,0 +1,143 @@
+package api
+
+import (
+	"minimax-voice-workbench/internal/database"
+	"minimax-voice-workbench/internal/model"
+	"minimax-voice-workbench/pkg/minimax"
+	"time"
+	"unicode/utf8"
+)
+
+// Cooldown applied to a key after MiniMax refuses it
+const (
+	rateLimitCooldown  = time.Minute
+	balanceCooldown    = time.Hour
+	invalidKeyCooldown = 24 * time.Hour
+)
+
+// keyCooldown reports how long a key should rest after err,
+// or false when err is not caused by the key itself
+func keyCooldown(err error) (time.Duration, bool) {
+	switch {
+	case minimax.IsRateLimited(err):
+		return rateLimitCooldown, true
+	case minimax.IsInsufficientBalance(err):
+		return balanceCooldown, true
+	case minimax.IsInvalidKey(err):
+		return invalidKeyCooldown, true
+	}
+	return 0, false
+}
+
+// keyCandidates returns the keys to try for a request: the effective key
+// first, then the other healthy keys of its pool. A preferred key that is
+// cooling down is kept as the last resort.
+func keyCandidates(keyID uint) ([]model.ApiKey, error) {
+	primary, err := getEffectiveKey(keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var others []model.ApiKey
+	err = database.DB.Where("pool = ? AND id <> ?", primary.Pool, primary.ID).
+		Where("cooldown_until IS NULL OR cooldown_until < ?", now).
+		Order("is_default desc, created_at asc").
+		Find(&others).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if primary.CooldownUntil != nil && primary.CooldownUntil.After(now) {
+		return append(others, *primary), nil
+	}
+	return append([]model.ApiKey{*primary}, others...), nil
+}
+
+// withKeyFailover runs fn with the effective key and retries it on the next
+// healthy key of the same pool whenever MiniMax rejects the key as invalid,
+// out of balance or rate limited. Refused keys are put into cooldown.
+// It returns the key the last attempt ran with, so callers can record it.
+func withKeyFailover(keyID uint, fn func(client *minimax.Client) error) (*model.ApiKey, error) {
+	candidates, err := keyCandidates(keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var key *model.ApiKey
+	for i := range candidates {
+		key = &candidates[i]
+		err = fn(minimax.NewClient(key.Key))
+		if err == nil {
+			markKeyHealthy(key)
+			return key, nil
+		}
+
+		cooldown, ok := keyCooldown(err)
+		if !ok {
+			return key, err
+		}
+		markKeyUnhealthy(key, cooldown, err)
+	}
+	return key, err
+}
+
+// withKey runs fn with the effective key only. Used when the request refers
+// to account-bound resources (uploaded files, async tasks) that other keys
+// cannot see.
+func withKey(keyID uint, fn func(client *minimax.Client) error) (*model.ApiKey, error) {
+	key, err := getEffectiveKey(keyID)
+	if err != nil {
+		return nil, err
+	}
+	err = fn(minimax.NewClient(key.Key))
+	if err == nil {
+		markKeyHealthy(key)
+	} else if cooldown, ok := keyCooldown(err); ok {
+		markKeyUnhealthy(key, cooldown, err)
+	}
+	return key, err
+}
+
+// withVoiceKey runs fn with a key that can use voiceID. Cloned and designed
+// voices exist only under the account that created them, so they default to
+// their owning key and never fail over. Other voices use withKeyFailover.
+func withVoiceKey(voiceID string, keyID uint, fn func(client *minimax.Client) error) (*model.ApiKey, error) {
+	var voice model.Voice
+	if err := database.DB.Where("voice_id = ?", voiceID).First(&voice).Error; err == nil && voice.KeyID > 0 {
+		if keyID == 0 {
+			keyID = voice.KeyID
+		}
+		return withKey(keyID, fn)
+	}
+	return withKeyFailover(keyID, fn)
+}
+
+func markKeyHealthy(key *model.ApiKey) {
+	if key.FailureCount == 0 && key.CooldownUntil == nil {
+		return
+	}
+	database.DB.Model(key).Updates(map[string]interface{}{
+		"cooldown_until": nil,
+		"failure_count":  0,
+		"last_error":     "",
+	})
+}
+
+func markKeyUnhealthy(key *model.ApiKey, cooldown time.Duration, cause error) {
+	until := time.Now().Add(cooldown)
+	msg := cause.Error()
+	if len(msg) > 255 {
+		// Cut on a character boundary
+		n := 255
+		for n > 0 && !utf8.RuneStart(msg[n]) {
+			n--
+		}
+		msg = msg[:n]
+	}
+	database.DB.Model(key).Updates(map[string]interface{}{
+		"cooldown_until": until,
+		"failure_count":  key.FailureCount + 1,
+		"last_error":     msg,
+	})
+}
@@ -0,0 +1,510 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"minimax-voice-workbench/internal/database"
+	"minimax-voice-workbench/internal/model"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// LineRequest creates or updates a line. On update, omitted fields are left
+// unchanged.
+type LineRequest struct {
+	Name      *string `json:"name"`
+	Text      *string `json:"text"`
+	ProjectID *uint   `json:"project_id"`
+	FolderID  *uint   `json:"folder_id"`
+	TaskIDs   []uint  `json:"task_ids"` // Existing tasks to add as takes, on create only
+}
+
+// LineTakesRequest adds existing tasks to a line as takes
+type LineTakesRequest struct {
+	TaskIDs []uint `json:"task_ids" binding:"required"`
+}
+
+// SelectTakeRequest selects a take of a line, 0 clears the selection
+type SelectTakeRequest struct {
+	TaskID uint `json:"task_id"`
+}
+
+// LineSummary is a line with its number of takes
+type LineSummary struct {
+	model.Line
+	TakeCount int64 `json:"take_count"`
+}
+
+// SettingChange is a request setting that differs between two takes.
+// Nested settings are named by path, such as voice_setting.speed.
+type SettingChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"` // nil when not set
+	To    interface{} `json:"to"`
+}
+
+// LineTake is a task of a line with what changed since the previous take
+type LineTake struct {
+	model.SynthesisTask
+	Take     int             `json:"take"` // 1 for the first take
+	Selected bool            `json:"selected"`
+	Changes  []SettingChange `json:"changes"`
+}
+
+// LineDetail is a line with its takes, oldest first
+type LineDetail struct {
+	model.Line
+	Takes []LineTake `json:"takes"`
+}
+
+// Name of a line created without one, taken from its text
+const lineNameLen = 40
+
+// Request fields that change between takes without being settings
+var ignoredTakeFields = map[string]bool{"text_file_id": true}
+
+// flattenSettings turns a request payload into settings by path
+func flattenSettings(prefix string, value interface{}, out map[string]interface{}) {
+	if m, ok := value.(map[string]interface{}); ok && (prefix == "" || len(m) > 0) {
+		for k, v := range m {
+			if prefix != "" {
+				k = prefix + "." + k
+			}
+			flattenSettings(k, v, out)
+		}
+		return
+	}
+	if !ignoredTakeFields[prefix] {
+		out[prefix] = value
+	}
+}
+
+// settingsDiff lists the settings that differ between two request payloads
+func settingsDiff(from, to string) []SettingChange {
+	var a, b interface{}
+	json.Unmarshal([]byte(from), &a)
+	json.Unmarshal([]byte(to), &b)
+	before, after := map[string]interface{}{}, map[string]interface{}{}
+	flattenSettings("", a, before)
+	flattenSettings("", b, after)
+
+	changes := []SettingChange{}
+	for field, v := range after {
+		if old, ok := before[field]; !ok || !reflect.DeepEqual(old, v) {
+			changes = append(changes, SettingChange{Field: field, From: before[field], To: v})
+		}
+	}
+	for field, old := range before {
+		if _, ok := after[field]; !ok {
+			changes = append(changes, SettingChange{Field: field, From: old})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// lineName derives the name of a line from its text
+func lineName(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= lineNameLen {
+		return text
+	}
+	return string([]rune(text)[:lineNameLen]) + "…"
+}
+
+func loadLine(c *gin.Context) (*model.Line, error) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var line model.Line
+	if err := database.DB.First(&line, id).Error; err != nil {
+		return nil, err
+	}
+	return &line, nil
+}
+
+// ListLines returns lines with their take counts. project_id and folder_id
+// filter as for synthesis tasks.
+func ListLines(c *gin.Context) {
+	query := database.DB.Model(&model.Line{})
+	if projectID := c.Query("project_id"); projectID == "none" {
+		query = query.Where("project_id = 0")
+	} else if id, _ := strconv.Atoi(projectID); id > 0 {
+		query = query.Where("project_id = ?", id)
+		if folderStr := c.Query("folder_id"); folderStr != "" {
+			folderID, _ := strconv.Atoi(folderStr)
+			query = query.Where("folder_id = ?", folderID)
+		}
+	}
+
+	lines, page, err := findPage[model.Line](c, query, listSort{
+		Fields:  map[string]string{"name": "name", "created_at": "created_at", "updated_at": "updated_at"},
+		Default: "created_at",
+		Order:   "asc",
+	})
+	if err != nil {
+		listErrorResponse(c, err, 1, "Failed to fetch lines")
+		return
+	}
+
+	ids := make([]uint, len(lines))
+	for i, l := range lines {
+		ids[i] = l.ID
+	}
+	var counts []struct {
+		LineID uint
+		Count  int64
+	}
+	database.DB.Model(&model.SynthesisTask{}).Select("line_id, count(*) as count").
+		Where("line_id IN ?", ids).Group("line_id").Scan(&counts)
+	byLine := map[uint]int64{}
+	for _, c := range counts {
+		byLine[c.LineID] = c.Count
+	}
+
+	summaries := make([]LineSummary, len(lines))
+	for i, l := range lines {
+		summaries[i] = LineSummary{Line: l, TakeCount: byLine[l.ID]}
+	}
+	PagedSuccessResponse(c, summaries, page)
+}
+
+// CreateLine creates a line, optionally from existing tasks. Without text,
+// the text of the first task is used.
+func CreateLine(c *gin.Context) {
+	var req LineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 1, "Invalid request")
+		return
+	}
+
+	var line model.Line
+	if req.Text != nil {
+		line.Text = *req.Text
+	}
+	if strings.TrimSpace(line.Text) == "" && len(req.TaskIDs) > 0 {
+		var first model.SynthesisTask
+		if err := database.DB.Where("id IN ?", req.TaskIDs).Order("id").First(&first).Error; err == nil {
+			line.Text = first.Text
+		}
+	}
+	if strings.TrimSpace(line.Text) == "" {
+		ErrorResponse(c, http.StatusBadRequest, 1, "text or task_ids is required")
+		return
+	}
+	line.Name = lineName(line.Text)
+	if req.Name != nil && strings.TrimSpace(*req.Name) != "" {
+		line.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.ProjectID != nil {
+		line.ProjectID = *req.ProjectID
+	}
+	if req.FolderID != nil {
+		line.FolderID = *req.FolderID
+	}
+	if err := checkProjectPlace(line.ProjectID, line.FolderID); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 2, "Project or folder not found")
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&line).Error; err != nil {
+			return err
+		}
+		if len(req.TaskIDs) == 0 {
+			return nil
+		}
+		_, err := moveTakes(tx, line.ID, req.TaskIDs)
+		return err
+	})
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 3, "Failed to create line")
+		return
+	}
+	SuccessResponse(c, line)
+}
+
+// GetLine returns a line with its takes, each with the settings changed
+// since the take before it
+func GetLine(c *gin.Context) {
+	line, err := loadLine(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Line not found")
+		return
+	}
+
+	var tasks []model.SynthesisTask
+	if err := database.DB.Where("line_id = ?", line.ID).Order("id asc").Find(&tasks).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 2, "Failed to fetch takes")
+		return
+	}
+	detail := LineDetail{Line: *line, Takes: make([]LineTake, len(tasks))}
+	for i, task := range tasks {
+		take := LineTake{SynthesisTask: task, Take: i + 1, Selected: task.ID == line.SelectedTaskID, Changes: []SettingChange{}}
+		if i > 0 {
+			take.Changes = settingsDiff(tasks[i-1].RequestPayload, task.RequestPayload)
+		}
+		detail.Takes[i] = take
+	}
+	SuccessResponse(c, detail)
+}
+
+// UpdateLine renames a line, changes its text or moves it
+func UpdateLine(c *gin.Context) {
+	line, err := loadLine(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Line not found")
+		return
+	}
+
+	var req LineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 2, "Invalid request")
+		return
+	}
+	if req.Name != nil {
+		if strings.TrimSpace(*req.Name) == "" {
+			ErrorResponse(c, http.StatusBadRequest, 2, "name cannot be empty")
+			return
+		}
+		line.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Text != nil {
+		line.Text = *req.Text
+	}
+	if req.ProjectID != nil {
+		line.ProjectID = *req.ProjectID
+		line.FolderID = 0
+	}
+	if req.FolderID != nil {
+		line.FolderID = *req.FolderID
+	}
+	if err := checkProjectPlace(line.ProjectID, line.FolderID); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 3, "Project or folder not found")
+		return
+	}
+	if err := database.DB.Save(line).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 4, "Failed to update line")
+		return
+	}
+	SuccessResponse(c, line)
+}
+
+// DeleteLine deletes a line. Its takes are kept as plain tasks.
+func DeleteLine(c *gin.Context) {
+	line, err := loadLine(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Line not found")
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.SynthesisTask{}).Where("line_id = ?", line.ID).Update("line_id", 0).Error; err != nil {
+			return err
+		}
+		return tx.Delete(line).Error
+	})
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 2, "Failed to delete line")
+		return
+	}
+	SuccessResponse(c, nil)
+}
+
+// moveTakes makes tasks takes of a line. A take moved away from another
+// line stops being its selection.
+func moveTakes(tx *gorm.DB, lineID uint, taskIDs []uint) (int64, error) {
+	if err := tx.Model(&model.Line{}).Where("id <> ? AND selected_task_id IN ?", lineID, taskIDs).
+		Update("selected_task_id", 0).Error; err != nil {
+		return 0, err
+	}
+	result := tx.Model(&model.SynthesisTask{}).Where("id IN ?", taskIDs).Update("line_id", lineID)
+	return result.RowsAffected, result.Error
+}
+
+// AddLineTakes adds existing tasks to a line as takes, taking them out of
+// any other line
+func AddLineTakes(c *gin.Context) {
+	line, err := loadLine(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Line not found")
+		return
+	}
+
+	var req LineTakesRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.TaskIDs) == 0 {
+		ErrorResponse(c, http.StatusBadRequest, 2, "task_ids is required")
+		return
+	}
+
+	var added int64
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		added, err = moveTakes(tx, line.ID, req.TaskIDs)
+		return err
+	})
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 3, "Failed to add takes")
+		return
+	}
+	SuccessResponse(c, gin.H{"added": added})
+}
+
+// RemoveLineTake takes a task out of a line. The task itself is kept.
+func RemoveLineTake(c *gin.Context) {
+	line, err := loadLine(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Line not found")
+		return
+	}
+	taskID, _ := strconv.Atoi(c.Param("task_id"))
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.SynthesisTask{}).Where("id = ? AND line_id = ?", taskID, line.ID).Update("line_id", 0)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if line.SelectedTaskID == uint(taskID) {
+			return tx.Model(line).Update("selected_task_id", 0).Error
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ErrorResponse(c, http.StatusNotFound, 2, "Take not found in line")
+		return
+	}
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 3, "Failed to remove take")
+		return
+	}
+	SuccessResponse(c, nil)
+}
+
+// SelectLineTake marks the take of a line used on export. Only successful
+// takes can be selected.
+func SelectLineTake(c *gin.Context) {
+	line, err := loadLine(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Line not found")
+		return
+	}
+
+	var req SelectTakeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 2, "Invalid request")
+		return
+	}
+	if req.TaskID > 0 {
+		var task model.SynthesisTask
+		if err := database.DB.Where("id = ? AND line_id = ?", req.TaskID, line.ID).First(&task).Error; err != nil {
+			ErrorResponse(c, http.StatusNotFound, 3, "Take not found in line")
+			return
+		}
+		if task.Status != "success" {
+			ErrorResponse(c, http.StatusBadRequest, 4, "Only successful takes can be selected")
+			return
+		}
+	}
+
+	if err := database.DB.Model(line).Update("selected_task_id", req.TaskID).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 5, "Failed to select take")
+		return
+	}
+	SuccessResponse(c, line)
+}
+
+// DiffLineTakes compares the settings of two takes of a line. from defaults
+// to the selected take.
+func DiffLineTakes(c *gin.Context) {
+	line, err := loadLine(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Line not found")
+		return
+	}
+
+	fromID, _ := strconv.Atoi(c.Query("from"))
+	toID, _ := strconv.Atoi(c.Query("to"))
+	if fromID == 0 {
+		fromID = int(line.SelectedTaskID)
+	}
+	if fromID == 0 || toID == 0 {
+		ErrorResponse(c, http.StatusBadRequest, 2, "from and to are required")
+		return
+	}
+
+	var takes []model.SynthesisTask
+	database.DB.Where("id IN ? AND line_id = ?", []int{fromID, toID}, line.ID).Find(&takes)
+	byID := map[uint]model.SynthesisTask{}
+	for _, t := range takes {
+		byID[t.ID] = t
+	}
+	from, okFrom := byID[uint(fromID)]
+	to, okTo := byID[uint(toID)]
+	if !okFrom || !okTo {
+		ErrorResponse(c, http.StatusNotFound, 3, "Take not found in line")
+		return
+	}
+	SuccessResponse(c, gin.H{"from": from.ID, "to": to.ID, "changes": settingsDiff(from.RequestPayload, to.RequestPayload)})
+}
+
+// ExportLine downloads the audio of the selected take of a line
+func ExportLine(c *gin.Context) {
+	line, err := loadLine(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Line not found")
+		return
+	}
+
+	var task model.SynthesisTask
+	if line.SelectedTaskID == 0 || database.DB.First(&task, line.SelectedTaskID).Error != nil {
+		ErrorResponse(c, http.StatusNotFound, 2, "Line has no selected take")
+		return
+	}
+	local := generatedFilePath(task.Output)
+	if _, err := os.Stat(local); task.Output == "" || err != nil {
+		ErrorResponse(c, http.StatusNotFound, 3, "Audio of the selected take is missing")
+		return
+	}
+	c.FileAttachment(local, exportName(line.Name)+filepath.Ext(local))
+}
+
+// exportedTakes returns the selected take of each line among tasks, and
+// the lines with takes but none of them selected. Those lines are left out
+// of exports rather than exported with a take nobody chose.
+func exportedTakes(tasks []model.SynthesisTask) (chosen map[uint]uint, unselected []model.Line) {
+	present := map[uint]bool{}
+	seen := map[uint]bool{}
+	var lineIDs []uint
+	for _, t := range tasks {
+		present[t.ID] = true
+		if t.LineID > 0 && !seen[t.LineID] {
+			seen[t.LineID] = true
+			lineIDs = append(lineIDs, t.LineID)
+		}
+	}
+	chosen = map[uint]uint{}
+	if len(lineIDs) == 0 {
+		return chosen, nil
+	}
+
+	var lines []model.Line
+	database.DB.Where("id IN ?", lineIDs).Order("id asc").Find(&lines)
+	for _, l := range lines {
+		if l.SelectedTaskID > 0 && present[l.SelectedTaskID] {
+			chosen[l.ID] = l.SelectedTaskID
+		} else {
+			unselected = append(unselected, l)
+		}
+	}
+	return chosen, unselected
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"minimax-voice-workbench/internal/markup"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConvertMarkupRequest is text in the SSML subset of package markup
+type ConvertMarkupRequest struct {
+	Text string `json:"text" binding:"required"`
+	markup.Options
+}
+
+// ConvertMarkup shows the Minimax text and pronunciation dict entries that
+// text written in markup becomes, without submitting it. Errors are listed
+// in data with their line and column.
+func ConvertMarkup(c *gin.Context) {
+	var req ConvertMarkupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 1, "text is required")
+		return
+	}
+	res, err := markup.Convert(req.Text, req.Options)
+	if err != nil {
+		ErrorResponseWithData(c, http.StatusBadRequest, 2, "Invalid markup: "+err.Error(), err)
+		return
+	}
+	SuccessResponse(c, res)
+}
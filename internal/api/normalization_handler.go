@@ -0,0 +1,203 @@
+package api
+
+import (
+	"minimax-voice-workbench/internal/database"
+	"minimax-voice-workbench/internal/model"
+	"minimax-voice-workbench/internal/normalize"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Values of the normalization field of requests: user rules only (the
+// default), user rules then the built-in normalizers, or none
+var normalizationModes = map[string]bool{"": true, "rules": true, "all": true, "off": true}
+
+// NormalizationRuleRequest creates or replaces a normalization rule
+type NormalizationRuleRequest struct {
+	ProjectID   uint   `json:"project_id"`
+	Language    string `json:"language"`
+	Name        string `json:"name"`
+	Pattern     string `json:"pattern" binding:"required"`
+	Replacement string `json:"replacement"`
+	Position    int    `json:"position"`
+	Enabled     *bool  `json:"enabled"` // Defaults to true
+}
+
+// NormalizeTextRequest previews the normalization of a text
+type NormalizeTextRequest struct {
+	Text          string `json:"text" binding:"required"`
+	ProjectID     uint   `json:"project_id"`
+	Language      string `json:"language"` // A language_boost value; auto or empty detects it
+	Normalization string `json:"normalization"`
+}
+
+// NormalizeTextResponse is a text before and after normalization
+type NormalizeTextResponse struct {
+	Text       string `json:"text"`
+	Normalized string `json:"normalized"`
+	Language   string `json:"language"` // Language the rules were picked for
+}
+
+// normalizationRules returns the enabled rules of a project and the
+// global ones, in the order they run
+func normalizationRules(projectID uint) []model.NormalizationRule {
+	var rules []model.NormalizationRule
+	database.DB.Where("enabled = ? AND project_id IN ?", true, []uint{0, projectID}).
+		Order("position asc, id asc").Find(&rules)
+	return rules
+}
+
+// normalizationLanguage is the language rules are picked for: the
+// language_boost, or else the one detected in the text
+func normalizationLanguage(language, text string) string {
+	if language == "" || language == "auto" {
+		return normalize.Detect(text)
+	}
+	return language
+}
+
+// normalizeText runs the rules matching language over text, followed by
+// the built-in normalizers when mode is all
+func normalizeText(text string, rules []model.NormalizationRule, language, mode string) string {
+	if mode == "off" || text == "" {
+		return text
+	}
+	language = normalizationLanguage(language, text)
+	var steps []normalize.Func
+	for _, r := range rules {
+		if r.Language != "" && !strings.EqualFold(r.Language, language) {
+			continue
+		}
+		// Patterns are checked when saved
+		if re, err := regexp.Compile(r.Pattern); err == nil {
+			steps = append(steps, normalize.Rule{Pattern: re, Replacement: r.Replacement}.Func())
+		}
+	}
+	if mode == "all" {
+		steps = append(steps, normalize.Builtins(language, text)...)
+	}
+	return normalize.Text(text, steps)
+}
+
+// apply validates the request and copies it onto rule
+func (req *NormalizationRuleRequest) apply(c *gin.Context, rule *model.NormalizationRule) bool {
+	if _, err := regexp.Compile(req.Pattern); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 2, "Invalid pattern: "+err.Error())
+		return false
+	}
+	if req.ProjectID > 0 {
+		var project model.Project
+		if err := database.DB.First(&project, req.ProjectID).Error; err != nil {
+			ErrorResponse(c, http.StatusNotFound, 3, "Project not found")
+			return false
+		}
+	}
+	rule.ProjectID = req.ProjectID
+	rule.Language = req.Language
+	rule.Name = req.Name
+	rule.Pattern = req.Pattern
+	rule.Replacement = req.Replacement
+	rule.Position = req.Position
+	rule.Enabled = req.Enabled == nil || *req.Enabled
+	return true
+}
+
+// ListNormalizationRules returns rules in the order they run. project_id
+// keeps the rules of one project, 0 for the global ones.
+func ListNormalizationRules(c *gin.Context) {
+	query := database.DB.Model(&model.NormalizationRule{})
+	if projectID := c.Query("project_id"); projectID != "" {
+		id, _ := strconv.Atoi(projectID)
+		query = query.Where("project_id = ?", id)
+	}
+	if language := c.Query("language"); language != "" {
+		query = query.Where("language = ?", language)
+	}
+
+	rules, page, err := findPage[model.NormalizationRule](c, query, listSort{
+		Fields:  map[string]string{"position": "position", "name": "name", "created_at": "created_at"},
+		Default: "position",
+		Order:   "asc",
+	})
+	if err != nil {
+		listErrorResponse(c, err, 1, "Failed to fetch rules")
+		return
+	}
+	PagedSuccessResponse(c, rules, page)
+}
+
+// CreateNormalizationRule adds a rule
+func CreateNormalizationRule(c *gin.Context) {
+	var req NormalizationRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 1, "pattern is required")
+		return
+	}
+
+	var rule model.NormalizationRule
+	if !req.apply(c, &rule) {
+		return
+	}
+	if err := database.DB.Create(&rule).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 4, "Failed to save rule")
+		return
+	}
+	SuccessResponse(c, rule)
+}
+
+// UpdateNormalizationRule replaces a rule
+func UpdateNormalizationRule(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var rule model.NormalizationRule
+	if err := database.DB.First(&rule, id).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, 5, "Rule not found")
+		return
+	}
+
+	var req NormalizationRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 1, "pattern is required")
+		return
+	}
+	if !req.apply(c, &rule) {
+		return
+	}
+	if err := database.DB.Save(&rule).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 4, "Failed to save rule")
+		return
+	}
+	SuccessResponse(c, rule)
+}
+
+// DeleteNormalizationRule removes a rule
+func DeleteNormalizationRule(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	if err := database.DB.Delete(&model.NormalizationRule{}, id).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 1, "Failed to delete rule")
+		return
+	}
+	SuccessResponse(c, nil)
+}
+
+// NormalizeText shows what a text becomes with the rules of a project,
+// without submitting it
+func NormalizeText(c *gin.Context) {
+	var req NormalizeTextRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 1, "text is required")
+		return
+	}
+	if !normalizationModes[req.Normalization] {
+		ErrorResponse(c, http.StatusBadRequest, 2, "normalization must be rules, all or off")
+		return
+	}
+	SuccessResponse(c, NormalizeTextResponse{
+		Text:       req.Text,
+		Normalized: normalizeText(req.Text, normalizationRules(req.ProjectID), req.Language, req.Normalization),
+		Language:   normalizationLanguage(req.Language, req.Text),
+	})
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"minimax-voice-workbench/internal/database"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Page sizes of list endpoints
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// listSort lists the sortable columns of a list endpoint by sort parameter
+type listSort struct {
+	Fields  map[string]string
+	Default string // Sort parameter used when none is given
+	Order   string // Default order, asc or desc
+}
+
+// newestFirst sorts lists that are only ordered by creation
+var newestFirst = listSort{
+	Fields:  map[string]string{"id": "id", "created_at": "created_at"},
+	Default: "created_at",
+	Order:   "desc",
+}
+
+// pageCursor points after the last row of a page: its sort value and id
+type pageCursor struct {
+	Value interface{} `json:"v"`
+	Time  bool        `json:"t,omitempty"`
+	ID    uint        `json:"id"`
+}
+
+func encodeCursor(cur pageCursor) string {
+	payload, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+func decodeCursor(raw string) (*pageCursor, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var cur pageCursor
+	if err := json.Unmarshal(payload, &cur); err != nil {
+		return nil, errInvalidCursor
+	}
+	if cur.Time {
+		s, ok := cur.Value.(string)
+		if !ok {
+			return nil, errInvalidCursor
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, errInvalidCursor
+		}
+		cur.Value = t
+	}
+	return &cur, nil
+}
+
+// pageParams reads page and page_size, applying the defaults and limits
+func pageParams(c *gin.Context) (page, pageSize int) {
+	page, _ = strconv.Atoi(c.Query("page"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ = strconv.Atoi(c.Query("page_size"))
+	if pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	return page, min(pageSize, maxPageSize)
+}
+
+// findPage orders query by the sort and order parameters, with id breaking
+// ties, and loads one page of at most maxPageSize rows. With cursor (empty
+// for the first page) it loads the rows after the cursor; otherwise the
+// offset page given by page, the first by default.
+func findPage[T any](c *gin.Context, query *gorm.DB, sorts listSort) ([]T, *Pagination, error) {
+	sortParam := c.Query("sort")
+	column, ok := sorts.Fields[sortParam]
+	if !ok {
+		sortParam = sorts.Default
+		column = sorts.Fields[sortParam]
+	}
+	order := sorts.Order
+	if o := c.Query("order"); o == "asc" || o == "desc" {
+		order = o
+	}
+	query = query.Model(new(T)).Session(&gorm.Session{})
+	ordered := query.Order(column + " " + order)
+	if column != "id" {
+		ordered = ordered.Order("id " + order)
+	}
+
+	rawCursor, cursorMode := c.GetQuery("cursor")
+	var items []T
+	page, pageSize := pageParams(c)
+	pagination := &Pagination{PageSize: pageSize, Sort: sortParam, Order: order}
+	if err := query.Count(&pagination.Total).Error; err != nil {
+		return nil, nil, err
+	}
+
+	if !cursorMode {
+		pagination.Page = page
+		pagination.HasMore = int64(page*pageSize) < pagination.Total
+		err := ordered.Offset((page - 1) * pageSize).Limit(pageSize).Find(&items).Error
+		return items, pagination, err
+	}
+
+	if rawCursor != "" {
+		cur, err := decodeCursor(rawCursor)
+		if err != nil {
+			return nil, nil, err
+		}
+		op := ">"
+		if order == "desc" {
+			op = "<"
+		}
+		if column == "id" {
+			ordered = ordered.Where("id "+op+" ?", cur.ID)
+		} else {
+			ordered = ordered.Where("("+column+" "+op+" ?) OR ("+column+" = ? AND id "+op+" ?)", cur.Value, cur.Value, cur.ID)
+		}
+	}
+	// One extra row tells whether another page follows
+	if err := ordered.Limit(pageSize + 1).Find(&items).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(items) > pageSize {
+		items = items[:pageSize]
+		next, err := cursorAfter(c, &items[pageSize-1], column)
+		if err != nil {
+			return nil, nil, err
+		}
+		pagination.HasMore = true
+		pagination.NextCursor = next
+	}
+	return items, pagination, nil
+}
+
+// cursorAfter builds the cursor pointing after row
+func cursorAfter(c *gin.Context, row interface{}, column string) (string, error) {
+	stmt := &gorm.Statement{DB: database.DB}
+	if err := stmt.Parse(row); err != nil {
+		return "", err
+	}
+	rv := reflect.ValueOf(row).Elem()
+	idField, sortField := stmt.Schema.LookUpField("id"), stmt.Schema.LookUpField(column)
+	if idField == nil || sortField == nil {
+		return "", errors.New("unknown sort column " + column)
+	}
+
+	var cur pageCursor
+	id, _ := idField.ValueOf(c.Request.Context(), rv)
+	cur.ID, _ = id.(uint)
+	cur.Value, _ = sortField.ValueOf(c.Request.Context(), rv)
+	if t, ok := cur.Value.(*time.Time); ok && t != nil {
+		cur.Value = *t
+	}
+	if t, ok := cur.Value.(time.Time); ok {
+		cur.Value = t.Format(time.RFC3339Nano)
+		cur.Time = true
+	}
+	return encodeCursor(cur), nil
+}
+
+// listErrorResponse reports a failed findPage: a bad cursor is the client's
+// fault, anything else is reported with message
+func listErrorResponse(c *gin.Context, err error, errCode int, message string) {
+	if errors.Is(err, errInvalidCursor) {
+		ErrorResponse(c, http.StatusBadRequest, errCode, "Invalid cursor")
+		return
+	}
+	ErrorResponse(c, http.StatusInternalServerError, errCode, message)
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern matched with ESCAPE '\'
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// likeContains is a LIKE pattern, for use with ESCAPE '\', matching text
+// that contains s literally
+func likeContains(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
+// whereTag limits query to rows whose tags list has tag as an entry
+func whereTag(query *gorm.DB, tag string) *gorm.DB {
+	return query.Where("EXISTS (SELECT 1 FROM json_each(CASE WHEN json_valid(tags) THEN tags END) WHERE value = ?)", tag)
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"minimax-voice-workbench/internal/database"
+	"minimax-voice-workbench/internal/model"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PresetRequest creates or replaces a preset
+type PresetRequest struct {
+	ProjectID uint                    `json:"project_id"`
+	Name      string                  `json:"name" binding:"required"`
+	VoiceID   string                  `json:"voice_id"`
+	Settings  model.SynthesisSettings `json:"settings"`
+}
+
+// apply validates the request and copies it onto preset
+func (req *PresetRequest) apply(c *gin.Context, preset *model.Preset) bool {
+	if strings.TrimSpace(req.Name) == "" {
+		ErrorResponse(c, http.StatusBadRequest, 1, "name is required")
+		return false
+	}
+	if req.ProjectID > 0 {
+		if err := database.DB.First(&model.Project{}, req.ProjectID).Error; err != nil {
+			ErrorResponse(c, http.StatusNotFound, 2, "Project not found")
+			return false
+		}
+	}
+	preset.ProjectID = req.ProjectID
+	preset.Name = strings.TrimSpace(req.Name)
+	preset.VoiceID = strings.TrimSpace(req.VoiceID)
+	preset.Settings = req.Settings
+	return true
+}
+
+// presetDefaults wraps a preset as project defaults, so that it fills in
+// a request the way a project does
+func presetDefaults(preset *model.Preset) *model.Project {
+	return &model.Project{DefaultVoiceID: preset.VoiceID, Settings: preset.Settings}
+}
+
+// ListPresets returns presets by name. project_id keeps the presets of one
+// project, 0 for the global ones; voice_id those of one voice.
+func ListPresets(c *gin.Context) {
+	query := database.DB.Model(&model.Preset{})
+	if projectID := c.Query("project_id"); projectID != "" {
+		id, _ := strconv.Atoi(projectID)
+		query = query.Where("project_id = ?", id)
+	}
+	if voiceID := c.Query("voice_id"); voiceID != "" {
+		query = query.Where("voice_id = ?", voiceID)
+	}
+
+	presets, page, err := findPage[model.Preset](c, query, listSort{
+		Fields:  map[string]string{"name": "name", "created_at": "created_at", "updated_at": "updated_at"},
+		Default: "name",
+		Order:   "asc",
+	})
+	if err != nil {
+		listErrorResponse(c, err, 1, "Failed to fetch presets")
+		return
+	}
+	PagedSuccessResponse(c, presets, page)
+}
+
+// CreatePreset adds a preset
+func CreatePreset(c *gin.Context) {
+	var req PresetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 1, "name is required")
+		return
+	}
+
+	var preset model.Preset
+	if !req.apply(c, &preset) {
+		return
+	}
+	if err := database.DB.Create(&preset).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 3, "Failed to save preset")
+		return
+	}
+	SuccessResponse(c, preset)
+}
+
+// UpdatePreset replaces a preset
+func UpdatePreset(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var preset model.Preset
+	if err := database.DB.First(&preset, id).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, 4, "Preset not found")
+		return
+	}
+
+	var req PresetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 1, "name is required")
+		return
+	}
+	if !req.apply(c, &preset) {
+		return
+	}
+	if err := database.DB.Save(&preset).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 3, "Failed to save preset")
+		return
+	}
+	SuccessResponse(c, preset)
+}
+
+// DeletePreset removes a preset
+func DeletePreset(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	if err := database.DB.Delete(&model.Preset{}, id).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 1, "Failed to delete preset")
+		return
+	}
+	SuccessResponse(c, nil)
+}
@@ -0,0 +1,403 @@
+package api
+
+import (
+	"context"
+	"log"
+	"minimax-voice-workbench/internal/database"
+	"minimax-voice-workbench/internal/model"
+	"minimax-voice-workbench/pkg/minimax"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Wait after every key reported a rate limit
+const previewJobRateLimitWait = time.Minute
+
+// previewJobRun is one background run of a preview job
+type previewJobRun struct {
+	cancel   context.CancelFunc
+	stopping bool // Paused or deleted, but still finishing its current item
+}
+
+// runningPreviewJobs holds the run of every job being worked on, until its
+// goroutine has exited
+var runningPreviewJobs = struct {
+	sync.Mutex
+	runs map[uint]*previewJobRun
+}{runs: map[uint]*previewJobRun{}}
+
+// startPreviewJob runs a job in the background. It returns false when a
+// run of the job, possibly one being stopped, has not exited yet.
+func startPreviewJob(jobID uint) bool {
+	runningPreviewJobs.Lock()
+	defer runningPreviewJobs.Unlock()
+	if _, ok := runningPreviewJobs.runs[jobID]; ok {
+		return false
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &previewJobRun{cancel: cancel}
+	runningPreviewJobs.runs[jobID] = run
+	database.DB.Model(&model.PreviewJob{}).Where("id = ?", jobID).Update("status", "running")
+	go func() {
+		runPreviewJob(ctx, jobID)
+		// A paused and resumed job may already have a newer run
+		runningPreviewJobs.Lock()
+		if runningPreviewJobs.runs[jobID] == run {
+			delete(runningPreviewJobs.runs, jobID)
+		}
+		runningPreviewJobs.Unlock()
+		cancel()
+	}()
+	return true
+}
+
+// stopPreviewJob stops a running job after its current item. The run stays
+// registered until its goroutine exits, so that a resume cannot start a
+// second run on the same items meanwhile.
+func stopPreviewJob(jobID uint) {
+	runningPreviewJobs.Lock()
+	defer runningPreviewJobs.Unlock()
+	if run, ok := runningPreviewJobs.runs[jobID]; ok {
+		run.cancel()
+		run.stopping = true
+	}
+}
+
+// previewJobStopping reports whether a stopped run of a job is still
+// finishing its current item
+func previewJobStopping(jobID uint) bool {
+	runningPreviewJobs.Lock()
+	defer runningPreviewJobs.Unlock()
+	run, ok := runningPreviewJobs.runs[jobID]
+	return ok && run.stopping
+}
+
+// sleepContext waits for d, returning false if ctx ends first
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// runPreviewJob works through the pending items of a job one at a time,
+// no faster than the job's rate
+func runPreviewJob(ctx context.Context, jobID uint) {
+	var job model.PreviewJob
+	if err := database.DB.First(&job, jobID).Error; err != nil {
+		return
+	}
+	interval := time.Minute / time.Duration(max(job.RatePerMinute, 1))
+	var lastCall time.Time
+
+	bump := func(column string) {
+		database.DB.Model(&model.PreviewJob{}).Where("id = ?", jobID).
+			UpdateColumn(column, gorm.Expr(column+" + 1"))
+	}
+
+	for ctx.Err() == nil {
+		var item model.PreviewJobItem
+		err := database.DB.Where("job_id = ? AND status = ?", jobID, "pending").Order("id asc").First(&item).Error
+		if err != nil {
+			database.DB.Model(&model.PreviewJob{}).Where("id = ? AND status = ?", jobID, "running").
+				Update("status", "completed")
+			return
+		}
+
+		var voice model.Voice
+		if err := database.DB.Where("voice_id = ?", item.VoiceID).First(&voice).Error; err != nil {
+			database.DB.Model(&item).Updates(map[string]interface{}{"status": "failed", "error": "Voice not found"})
+			bump("failed")
+			continue
+		}
+
+		// Skip voices whose preview is already on disk
+		if voice.Preview != "" {
+			if _, err := os.Stat(generatedFilePath(voice.Preview)); err == nil {
+				database.DB.Model(&item).Update("status", "skipped")
+				bump("skipped")
+				continue
+			}
+		}
+
+		if wait := time.Until(lastCall.Add(interval)); wait > 0 && !sleepContext(ctx, wait) {
+			return
+		}
+		lastCall = time.Now()
+
+		err = generateVoicePreview(&voice, previewOptions{KeyID: job.KeyID})
+		if err != nil && minimax.IsRateLimited(err) {
+			// Every usable key is limited; keep the item and try again later
+			database.DB.Model(&model.PreviewJob{}).Where("id = ?", jobID).Update("last_error", err.Error())
+			if !sleepContext(ctx, previewJobRateLimitWait) {
+				return
+			}
+			continue
+		}
+		if err != nil {
+			database.DB.Model(&item).Updates(map[string]interface{}{"status": "failed", "error": err.Error()})
+			database.DB.Model(&model.PreviewJob{}).Where("id = ?", jobID).Update("last_error", err.Error())
+			bump("failed")
+			continue
+		}
+		database.DB.Model(&item).Update("status", "generated")
+		bump("generated")
+	}
+}
+
+// ResumePreviewJobs restarts jobs that were running when the server stopped
+func ResumePreviewJobs() {
+	var jobs []model.PreviewJob
+	database.DB.Where("status = ?", "running").Find(&jobs)
+	for _, job := range jobs {
+		log.Println("Resuming preview job", job.ID)
+		startPreviewJob(job.ID)
+	}
+}
+
+// estimatePreviews sums up the previews a job would generate: those of
+// voices without a preview on disk, each from the template of its language
+func estimatePreviews(voices []model.Voice) *SpeechEstimate {
+	e := newSpeechEstimate()
+	templates := map[string]*model.PreviewTemplate{}
+	for _, v := range voices {
+		if v.Preview != "" {
+			if _, err := os.Stat(generatedFilePath(v.Preview)); err == nil {
+				continue
+			}
+		}
+		tpl, ok := templates[v.Language]
+		if !ok {
+			tpl, _ = selectPreviewTemplate(v.Language)
+			templates[v.Language] = tpl
+		}
+		if tpl != nil {
+			e.add(&minimax.T2ARequest{Model: tpl.Model, Text: tpl.Text, VoiceSetting: minimax.VoiceSetting{Speed: tpl.Speed}})
+		}
+	}
+	return e
+}
+
+// CreatePreviewJobRequest selects the voices of a preview job
+type CreatePreviewJobRequest struct {
+	Type          string `json:"type"` // system, cloned, generated
+	Favorite      bool   `json:"favorite"`
+	Tag           string `json:"tag"`
+	Language      string `json:"language"`
+	KeyID         uint   `json:"key_id"`
+	RatePerMinute int    `json:"rate_per_minute"` // Defaults to 20
+	BatchLimit
+}
+
+// CreatePreviewJob queues every matching voice and starts generating
+// previews in the background. Only one job runs at a time.
+func CreatePreviewJob(c *gin.Context) {
+	var req CreatePreviewJobRequest
+	if err := bindOptionalJSON(c, &req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 4, "Invalid request body")
+		return
+	}
+
+	var active int64
+	database.DB.Model(&model.PreviewJob{}).Where("status IN ?", []string{"pending", "running"}).Count(&active)
+	if active > 0 {
+		ErrorResponse(c, http.StatusConflict, 1, "Another preview job is running")
+		return
+	}
+
+	query := database.DB.Model(&model.Voice{})
+	if req.Type != "" {
+		query = query.Where("type = ?", req.Type)
+	}
+	if req.Favorite {
+		query = query.Where("is_favorite = ?", true)
+	}
+	if req.Tag != "" {
+		query = whereTag(query, req.Tag)
+	}
+	if req.Language != "" {
+		query = query.Where("language = ?", req.Language)
+	}
+	var voices []model.Voice
+	if err := query.Select("voice_id", "language", "preview").Order("id asc").Find(&voices).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 2, "Failed to fetch voices")
+		return
+	}
+	if !req.check(c, estimatePreviews(voices), true, 4) {
+		return
+	}
+
+	if req.RatePerMinute <= 0 {
+		req.RatePerMinute = 20
+	}
+	status := "pending"
+	if len(voices) == 0 {
+		status = "completed"
+	}
+	job := model.PreviewJob{
+		Type:          req.Type,
+		Favorite:      req.Favorite,
+		Tag:           req.Tag,
+		Language:      req.Language,
+		KeyID:         req.KeyID,
+		RatePerMinute: req.RatePerMinute,
+		Status:        status,
+		Total:         len(voices),
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&job).Error; err != nil {
+			return err
+		}
+		items := make([]model.PreviewJobItem, len(voices))
+		for i, v := range voices {
+			items[i] = model.PreviewJobItem{JobID: job.ID, VoiceID: v.VoiceID, Status: "pending"}
+		}
+		if len(items) > 0 {
+			return tx.CreateInBatches(items, 200).Error
+		}
+		return nil
+	})
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 3, "Failed to create preview job")
+		return
+	}
+
+	if job.Status == "pending" {
+		startPreviewJob(job.ID)
+		database.DB.First(&job, job.ID)
+	}
+	SuccessResponse(c, job)
+}
+
+// ListPreviewJobs returns preview jobs, newest first
+func ListPreviewJobs(c *gin.Context) {
+	jobs, page, err := findPage[model.PreviewJob](c, database.DB.Model(&model.PreviewJob{}), newestFirst)
+	if err != nil {
+		listErrorResponse(c, err, 1, "Failed to fetch preview jobs")
+		return
+	}
+	PagedSuccessResponse(c, jobs, page)
+}
+
+// GetPreviewJob returns a job with its progress. Items are included with
+// items=true, optionally filtered by item_status.
+func GetPreviewJob(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var job model.PreviewJob
+	if err := database.DB.First(&job, id).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Preview job not found")
+		return
+	}
+
+	if c.Query("items") != "true" {
+		SuccessResponse(c, job)
+		return
+	}
+
+	var items []model.PreviewJobItem
+	query := database.DB.Where("job_id = ?", job.ID)
+	if status := c.Query("item_status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	query.Order("id asc").Find(&items)
+	SuccessResponse(c, gin.H{"job": job, "items": items})
+}
+
+// loadPreviewJob loads the job of the :id parameter
+func loadPreviewJob(c *gin.Context) (*model.PreviewJob, error) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var job model.PreviewJob
+	if err := database.DB.First(&job, id).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// PausePreviewJob stops a running job after its current voice
+func PausePreviewJob(c *gin.Context) {
+	job, err := loadPreviewJob(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Preview job not found")
+		return
+	}
+	if job.Status != "running" && job.Status != "pending" {
+		ErrorResponse(c, http.StatusBadRequest, 2, "Preview job is not running")
+		return
+	}
+
+	stopPreviewJob(job.ID)
+	job.Status = "paused"
+	database.DB.Model(job).Update("status", "paused")
+	SuccessResponse(c, job)
+}
+
+// ResumePreviewJob continues a paused job with its remaining voices. Failed
+// voices are retried when retry_failed=true.
+func ResumePreviewJob(c *gin.Context) {
+	job, err := loadPreviewJob(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Preview job not found")
+		return
+	}
+	if job.Status == "running" || job.Status == "pending" {
+		ErrorResponse(c, http.StatusBadRequest, 2, "Preview job is already running")
+		return
+	}
+
+	if previewJobStopping(job.ID) {
+		ErrorResponse(c, http.StatusConflict, 4, "The paused job is still finishing its current voice; try again shortly")
+		return
+	}
+
+	var active int64
+	database.DB.Model(&model.PreviewJob{}).Where("status IN ? AND id <> ?", []string{"pending", "running"}, job.ID).Count(&active)
+	if active > 0 {
+		ErrorResponse(c, http.StatusConflict, 3, "Another preview job is running")
+		return
+	}
+
+	if c.Query("retry_failed") == "true" {
+		result := database.DB.Model(&model.PreviewJobItem{}).
+			Where("job_id = ? AND status = ?", job.ID, "failed").
+			Updates(map[string]interface{}{"status": "pending", "error": ""})
+		database.DB.Model(job).UpdateColumn("failed", gorm.Expr("failed - ?", result.RowsAffected))
+	}
+
+	if !startPreviewJob(job.ID) {
+		ErrorResponse(c, http.StatusConflict, 4, "The paused job is still finishing its current voice; try again shortly")
+		return
+	}
+	database.DB.First(job, job.ID)
+	SuccessResponse(c, job)
+}
+
+// DeletePreviewJob stops a job and removes it with its items. Previews
+// that were generated are kept.
+func DeletePreviewJob(c *gin.Context) {
+	job, err := loadPreviewJob(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Preview job not found")
+		return
+	}
+
+	stopPreviewJob(job.ID)
+	if job.Status == "running" || job.Status == "pending" {
+		database.DB.Model(job).Update("status", "cancelled")
+	}
+	database.DB.Where("job_id = ?", job.ID).Delete(&model.PreviewJobItem{})
+	if err := database.DB.Delete(job).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 2, "Failed to delete preview job")
+		return
+	}
+	SuccessResponse(c, nil)
+}
@@ -0,0 +1,624 @@
+package api
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"minimax-voice-workbench/internal/database"
+	"minimax-voice-workbench/internal/model"
+	"minimax-voice-workbench/pkg/minimax"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ProjectRequest creates or updates a project. On update, omitted fields
+// are left unchanged.
+type ProjectRequest struct {
+	Name           *string                  `json:"name"`
+	Description    *string                  `json:"description"`
+	DefaultVoiceID *string                  `json:"default_voice_id"`
+	Settings       *model.SynthesisSettings `json:"settings"`
+}
+
+// FolderRequest creates, renames or moves a folder
+type FolderRequest struct {
+	Name     *string `json:"name"`
+	ParentID *uint   `json:"parent_id"` // 0 moves the folder to the top level
+}
+
+// MoveTasksRequest moves tasks into a project folder. project_id 0 takes
+// the tasks out of any project.
+type MoveTasksRequest struct {
+	TaskIDs   []uint `json:"task_ids" binding:"required"`
+	ProjectID uint   `json:"project_id"`
+	FolderID  uint   `json:"folder_id"`
+}
+
+// ProjectSummary is a project with its number of tasks
+type ProjectSummary struct {
+	model.Project
+	TaskCount int64 `json:"task_count"`
+}
+
+// FolderNode is a folder with its subfolders
+type FolderNode struct {
+	model.ProjectFolder
+	TaskCount int64         `json:"task_count"` // Tasks directly in this folder
+	Children  []*FolderNode `json:"children"`
+}
+
+// ProjectDetail is a project with its folder tree
+type ProjectDetail struct {
+	model.Project
+	TaskCount     int64         `json:"task_count"`      // All tasks of the project
+	RootTaskCount int64         `json:"root_task_count"` // Tasks outside any folder
+	Folders       []*FolderNode `json:"folders"`
+}
+
+// errFolderNotInProject is returned for a folder of another project
+var errFolderNotInProject = errors.New("folder does not belong to the project")
+
+// projectFolders loads all folders of a project
+func projectFolders(projectID uint) []model.ProjectFolder {
+	var folders []model.ProjectFolder
+	database.DB.Where("project_id = ?", projectID).Order("name asc").Find(&folders)
+	return folders
+}
+
+// folderSubtree returns the ID of a folder and of every folder below it
+func folderSubtree(folders []model.ProjectFolder, rootID uint) []uint {
+	children := map[uint][]uint{}
+	for _, f := range folders {
+		children[f.ParentID] = append(children[f.ParentID], f.ID)
+	}
+	ids := []uint{rootID}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	return ids
+}
+
+// checkProjectFolder verifies that a folder exists in the project.
+// Folder 0 is the project root.
+func checkProjectFolder(projectID, folderID uint) error {
+	if folderID == 0 {
+		return nil
+	}
+	var folder model.ProjectFolder
+	if err := database.DB.First(&folder, folderID).Error; err != nil {
+		return err
+	}
+	if folder.ProjectID != projectID {
+		return errFolderNotInProject
+	}
+	return nil
+}
+
+// checkProjectPlace verifies the project and folder of a line or script.
+// Project 0 puts it outside any project, where it has no folder.
+func checkProjectPlace(projectID, folderID uint) error {
+	if projectID == 0 {
+		if folderID > 0 {
+			return errFolderNotInProject
+		}
+		return nil
+	}
+	if err := database.DB.First(&model.Project{}, projectID).Error; err != nil {
+		return err
+	}
+	return checkProjectFolder(projectID, folderID)
+}
+
+// applyProjectDefaults fills in the voice and settings a request leaves out
+// from the project defaults
+func applyProjectDefaults(project *model.Project, req *minimax.T2ARequest) {
+	s := project.Settings
+	if req.VoiceSetting.VoiceID == "" {
+		req.VoiceSetting.VoiceID = project.DefaultVoiceID
+	}
+	if req.Model == "" {
+		req.Model = s.Model
+	}
+	if req.LanguageBoost == "" {
+		req.LanguageBoost = s.LanguageBoost
+	}
+	if req.VoiceSetting.Speed == 0 {
+		req.VoiceSetting.Speed = s.Speed
+	}
+	if req.VoiceSetting.Vol == 0 {
+		req.VoiceSetting.Vol = s.Vol
+	}
+	if req.VoiceSetting.Pitch == 0 {
+		req.VoiceSetting.Pitch = s.Pitch
+	}
+	if req.VoiceSetting.Emotion == "" {
+		req.VoiceSetting.Emotion = s.Emotion
+	}
+	if req.AudioSetting.Format == "" {
+		req.AudioSetting.Format = s.Format
+	}
+	if req.AudioSetting.AudioSampleRate == 0 {
+		req.AudioSetting.AudioSampleRate = s.SampleRate
+	}
+	if req.AudioSetting.Bitrate == 0 {
+		req.AudioSetting.Bitrate = s.Bitrate
+	}
+	if req.AudioSetting.Channel == 0 {
+		req.AudioSetting.Channel = s.Channel
+	}
+}
+
+// loadProject loads the project of the :id parameter
+func loadProject(c *gin.Context) (*model.Project, error) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var project model.Project
+	if err := database.DB.First(&project, id).Error; err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// ListProjects returns all projects with their task counts
+func ListProjects(c *gin.Context) {
+	projects, page, err := findPage[model.Project](c, database.DB.Model(&model.Project{}), listSort{
+		Fields:  map[string]string{"name": "name", "created_at": "created_at", "updated_at": "updated_at"},
+		Default: "name",
+		Order:   "asc",
+	})
+	if err != nil {
+		listErrorResponse(c, err, 1, "Failed to fetch projects")
+		return
+	}
+
+	var counts []struct {
+		ProjectID uint
+		Count     int64
+	}
+	database.DB.Model(&model.SynthesisTask{}).Select("project_id, count(*) as count").
+		Where("project_id > 0").Group("project_id").Scan(&counts)
+	byProject := map[uint]int64{}
+	for _, c := range counts {
+		byProject[c.ProjectID] = c.Count
+	}
+
+	summaries := make([]ProjectSummary, len(projects))
+	for i, p := range projects {
+		summaries[i] = ProjectSummary{Project: p, TaskCount: byProject[p.ID]}
+	}
+	PagedSuccessResponse(c, summaries, page)
+}
+
+// CreateProject creates a project
+func CreateProject(c *gin.Context) {
+	var req ProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Name == nil || strings.TrimSpace(*req.Name) == "" {
+		ErrorResponse(c, http.StatusBadRequest, 1, "name is required")
+		return
+	}
+
+	project := model.Project{Name: strings.TrimSpace(*req.Name)}
+	if req.Description != nil {
+		project.Description = *req.Description
+	}
+	if req.DefaultVoiceID != nil {
+		project.DefaultVoiceID = *req.DefaultVoiceID
+	}
+	if req.Settings != nil {
+		project.Settings = *req.Settings
+	}
+	if err := database.DB.Create(&project).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 2, "Failed to create project")
+		return
+	}
+	SuccessResponse(c, project)
+}
+
+// GetProject returns a project with its folder tree and task counts
+func GetProject(c *gin.Context) {
+	project, err := loadProject(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Project not found")
+		return
+	}
+
+	var counts []struct {
+		FolderID uint
+		Count    int64
+	}
+	database.DB.Model(&model.SynthesisTask{}).Select("folder_id, count(*) as count").
+		Where("project_id = ?", project.ID).Group("folder_id").Scan(&counts)
+	byFolder := map[uint]int64{}
+	detail := ProjectDetail{Project: *project, Folders: []*FolderNode{}}
+	for _, c := range counts {
+		byFolder[c.FolderID] = c.Count
+		detail.TaskCount += c.Count
+	}
+	detail.RootTaskCount = byFolder[0]
+
+	folders := projectFolders(project.ID)
+	nodes := make(map[uint]*FolderNode, len(folders))
+	for _, f := range folders {
+		nodes[f.ID] = &FolderNode{ProjectFolder: f, TaskCount: byFolder[f.ID], Children: []*FolderNode{}}
+	}
+	for _, f := range folders {
+		if parent, ok := nodes[f.ParentID]; ok {
+			parent.Children = append(parent.Children, nodes[f.ID])
+		} else {
+			detail.Folders = append(detail.Folders, nodes[f.ID])
+		}
+	}
+	SuccessResponse(c, detail)
+}
+
+// UpdateProject changes a project's name, description or defaults
+func UpdateProject(c *gin.Context) {
+	project, err := loadProject(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Project not found")
+		return
+	}
+
+	var req ProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 2, "Invalid request")
+		return
+	}
+	if req.Name != nil {
+		if strings.TrimSpace(*req.Name) == "" {
+			ErrorResponse(c, http.StatusBadRequest, 2, "name cannot be empty")
+			return
+		}
+		project.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Description != nil {
+		project.Description = *req.Description
+	}
+	if req.DefaultVoiceID != nil {
+		project.DefaultVoiceID = *req.DefaultVoiceID
+	}
+	if req.Settings != nil {
+		project.Settings = *req.Settings
+	}
+	if err := database.DB.Save(project).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 3, "Failed to update project")
+		return
+	}
+	SuccessResponse(c, project)
+}
+
+// DeleteProject deletes a project with its folders, presets and
+// dictionaries. Its tasks, lines and scripts are kept and taken out of the
+// project. Projects with audiobooks are refused.
+func DeleteProject(c *gin.Context) {
+	project, err := loadProject(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Project not found")
+		return
+	}
+	// Audiobooks are read from the lines of their project
+	var books int64
+	database.DB.Model(&model.Audiobook{}).Where("project_id = ?", project.ID).Count(&books)
+	if books > 0 {
+		ErrorResponse(c, http.StatusConflict, 3, "Project has audiobooks; delete them first")
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, m := range []interface{}{&model.SynthesisTask{}, &model.Line{}, &model.Script{}} {
+			if err := tx.Model(m).Where("project_id = ?", project.ID).
+				Updates(map[string]interface{}{"project_id": 0, "folder_id": 0}).Error; err != nil {
+				return err
+			}
+		}
+		for _, m := range []interface{}{&model.ProjectFolder{}, &model.Preset{}, &model.PronunciationDict{}} {
+			if err := tx.Where("project_id = ?", project.ID).Delete(m).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(project).Error
+	})
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 2, "Failed to delete project")
+		return
+	}
+	SuccessResponse(c, nil)
+}
+
+// CreateFolder adds a folder to a project, optionally inside another one
+func CreateFolder(c *gin.Context) {
+	project, err := loadProject(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Project not found")
+		return
+	}
+
+	var req FolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Name == nil || strings.TrimSpace(*req.Name) == "" {
+		ErrorResponse(c, http.StatusBadRequest, 2, "name is required")
+		return
+	}
+	folder := model.ProjectFolder{ProjectID: project.ID, Name: strings.TrimSpace(*req.Name)}
+	if req.ParentID != nil {
+		folder.ParentID = *req.ParentID
+	}
+	if err := checkProjectFolder(project.ID, folder.ParentID); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 3, "Parent folder not found in project")
+		return
+	}
+	if err := database.DB.Create(&folder).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 4, "Failed to create folder")
+		return
+	}
+	SuccessResponse(c, folder)
+}
+
+// loadProjectFolder loads the :folder_id folder of the :id project
+func loadProjectFolder(c *gin.Context) (*model.ProjectFolder, error) {
+	var folder model.ProjectFolder
+	err := database.DB.Where("id = ? AND project_id = ?", c.Param("folder_id"), c.Param("id")).First(&folder).Error
+	if err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// UpdateFolder renames a folder or moves it under another parent
+func UpdateFolder(c *gin.Context) {
+	folder, err := loadProjectFolder(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Folder not found")
+		return
+	}
+
+	var req FolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 2, "Invalid request")
+		return
+	}
+	if req.Name != nil {
+		if strings.TrimSpace(*req.Name) == "" {
+			ErrorResponse(c, http.StatusBadRequest, 2, "name cannot be empty")
+			return
+		}
+		folder.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.ParentID != nil && *req.ParentID != folder.ParentID {
+		if err := checkProjectFolder(folder.ProjectID, *req.ParentID); err != nil {
+			ErrorResponse(c, http.StatusBadRequest, 3, "Parent folder not found in project")
+			return
+		}
+		// A folder cannot move into itself or one of its subfolders
+		for _, id := range folderSubtree(projectFolders(folder.ProjectID), folder.ID) {
+			if id == *req.ParentID {
+				ErrorResponse(c, http.StatusBadRequest, 4, "Cannot move a folder into itself")
+				return
+			}
+		}
+		folder.ParentID = *req.ParentID
+	}
+	if err := database.DB.Save(folder).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 5, "Failed to update folder")
+		return
+	}
+	SuccessResponse(c, folder)
+}
+
+// DeleteFolder deletes a folder with its subfolders. Their tasks, lines and
+// scripts move up to the parent of the deleted folder. Folders of
+// audiobook chapters are refused.
+func DeleteFolder(c *gin.Context) {
+	folder, err := loadProjectFolder(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Folder not found")
+		return
+	}
+
+	ids := folderSubtree(projectFolders(folder.ProjectID), folder.ID)
+	// A chapter's paragraphs are the lines of its folder
+	var chapters int64
+	database.DB.Model(&model.AudiobookChapter{}).Where("folder_id IN ?", ids).Count(&chapters)
+	if chapters > 0 {
+		ErrorResponse(c, http.StatusConflict, 3, "Folder holds audiobook chapters; delete the audiobook first")
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, m := range []interface{}{&model.SynthesisTask{}, &model.Line{}, &model.Script{}} {
+			if err := tx.Model(m).Where("folder_id IN ?", ids).
+				Update("folder_id", folder.ParentID).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&model.ProjectFolder{}, ids).Error
+	})
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 2, "Failed to delete folder")
+		return
+	}
+	SuccessResponse(c, gin.H{"deleted_folders": len(ids)})
+}
+
+// MoveSynthesisTasks moves tasks into a project folder in one go
+func MoveSynthesisTasks(c *gin.Context) {
+	var req MoveTasksRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.TaskIDs) == 0 {
+		ErrorResponse(c, http.StatusBadRequest, 1, "task_ids is required")
+		return
+	}
+	if req.ProjectID == 0 && req.FolderID > 0 {
+		ErrorResponse(c, http.StatusBadRequest, 2, "folder_id requires project_id")
+		return
+	}
+	if req.ProjectID > 0 {
+		if err := database.DB.First(&model.Project{}, req.ProjectID).Error; err != nil {
+			ErrorResponse(c, http.StatusNotFound, 3, "Project not found")
+			return
+		}
+		if err := checkProjectFolder(req.ProjectID, req.FolderID); err != nil {
+			ErrorResponse(c, http.StatusBadRequest, 4, "Folder not found in project")
+			return
+		}
+	}
+
+	result := database.DB.Model(&model.SynthesisTask{}).Where("id IN ?", req.TaskIDs).
+		Updates(map[string]interface{}{"project_id": req.ProjectID, "folder_id": req.FolderID})
+	if result.Error != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 5, "Failed to move tasks")
+		return
+	}
+	SuccessResponse(c, gin.H{"moved": result.RowsAffected})
+}
+
+// projectExportEntry describes one task in a project export
+type projectExportEntry struct {
+	TaskID    uint      `json:"task_id"`
+	LineID    uint      `json:"line_id,omitempty"`
+	Text      string    `json:"text"`
+	VoiceID   string    `json:"voice_id"`
+	Folder    string    `json:"folder"`
+	File      string    `json:"file,omitempty"` // Path inside the zip
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// projectExportLine is a line left out of a project export because none of
+// its takes is selected
+type projectExportLine struct {
+	LineID uint   `json:"line_id"`
+	Name   string `json:"name"`
+	Folder string `json:"folder"`
+}
+
+// projectExportScript describes one script in a project export
+type projectExportScript struct {
+	ScriptID uint   `json:"script_id"`
+	Name     string `json:"name"`
+	Folder   string `json:"folder"`
+	File     string `json:"file"` // Path of the text inside the zip
+	Notes    string `json:"notes,omitempty"`
+}
+
+// exportName makes a folder or project name safe to use as a zip path
+func exportName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < 32 {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" || name == "." || name == ".." {
+		return "_"
+	}
+	return name
+}
+
+// ExportProject downloads a project as a zip: the audio of its successful
+// tasks and the text of its scripts laid out by folder, and a manifest of
+// every task, script, preset and dictionary. Of the takes of a line only
+// the selected one is exported; lines without one are listed in the
+// manifest as unselected_lines.
+func ExportProject(c *gin.Context) {
+	project, err := loadProject(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Project not found")
+		return
+	}
+
+	folders := projectFolders(project.ID)
+	byID := map[uint]model.ProjectFolder{}
+	for _, f := range folders {
+		byID[f.ID] = f
+	}
+	folderPath := func(id uint) string {
+		parts := []string{}
+		for seen := 0; id != 0 && seen <= len(folders); seen++ {
+			f, ok := byID[id]
+			if !ok {
+				break
+			}
+			parts = append([]string{exportName(f.Name)}, parts...)
+			id = f.ParentID
+		}
+		return path.Join(parts...)
+	}
+
+	var tasks []model.SynthesisTask
+	database.DB.Where("project_id = ?", project.ID).Order("created_at asc").Find(&tasks)
+
+	filename := fmt.Sprintf("project_%d_%s.zip", project.ID, time.Now().Format("20060102_150405"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+
+	zw := zip.NewWriter(c.Writer)
+	defer zw.Close()
+
+	root := exportName(project.Name)
+	takes, unselected := exportedTakes(tasks)
+	skipped := make([]projectExportLine, 0, len(unselected))
+	for _, l := range unselected {
+		skipped = append(skipped, projectExportLine{LineID: l.ID, Name: l.Name, Folder: folderPath(l.FolderID)})
+	}
+	entries := make([]projectExportEntry, 0, len(tasks))
+	for _, task := range tasks {
+		// Lines are exported with one take only
+		if task.LineID > 0 && takes[task.LineID] != task.ID {
+			continue
+		}
+		entry := projectExportEntry{
+			TaskID:    task.ID,
+			LineID:    task.LineID,
+			Text:      task.Text,
+			VoiceID:   task.VoiceID,
+			Folder:    folderPath(task.FolderID),
+			Status:    task.Status,
+			CreatedAt: task.CreatedAt,
+		}
+		if task.Status == "success" && task.Output != "" {
+			local := generatedFilePath(task.Output)
+			if src, err := os.Open(local); err == nil {
+				entry.File = path.Join(entry.Folder, filepath.Base(local))
+				if w, err := zw.Create(path.Join(root, entry.File)); err == nil {
+					io.Copy(w, src)
+				}
+				src.Close()
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	var scripts []model.Script
+	database.DB.Where("project_id = ?", project.ID).Order("id asc").Find(&scripts)
+	scriptEntries := make([]projectExportScript, 0, len(scripts))
+	for _, s := range scripts {
+		entry := projectExportScript{ScriptID: s.ID, Name: s.Name, Folder: folderPath(s.FolderID), Notes: s.Notes}
+		// The ID keeps scripts of the same name apart
+		entry.File = path.Join(entry.Folder, fmt.Sprintf("%s_%d.txt", exportName(s.Name), s.ID))
+		if w, err := zw.Create(path.Join(root, entry.File)); err == nil {
+			io.WriteString(w, s.Text)
+		}
+		scriptEntries = append(scriptEntries, entry)
+	}
+
+	var presets []model.Preset
+	database.DB.Where("project_id = ?", project.ID).Order("id asc").Find(&presets)
+	var dicts []model.PronunciationDict
+	database.DB.Where("project_id = ?", project.ID).Order("id asc").Find(&dicts)
+
+	w, err := zw.Create(path.Join(root, "manifest.json"))
+	if err != nil {
+		return
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(gin.H{"project": project, "tasks": entries, "scripts": scriptEntries, "presets": presets, "dictionaries": dicts, "unselected_lines": skipped})
+}
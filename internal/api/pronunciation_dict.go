@@ -0,0 +1,217 @@
+package api
+
+import (
+	"fmt"
+	"minimax-voice-workbench/internal/database"
+	"minimax-voice-workbench/internal/model"
+	"minimax-voice-workbench/pkg/minimax"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PronunciationDictRequest creates or replaces a pronunciation dictionary
+type PronunciationDictRequest struct {
+	ProjectID uint     `json:"project_id"`
+	VoiceID   string   `json:"voice_id"`
+	Name      string   `json:"name" binding:"required"`
+	Entries   []string `json:"entries"` // "text/reading"; blank entries are dropped
+	Enabled   *bool    `json:"enabled"` // Defaults to true
+}
+
+// toneText is the text an entry gives a reading for
+func toneText(entry string) string {
+	text, _, _ := strings.Cut(entry, "/")
+	return strings.TrimSpace(text)
+}
+
+// dictEntries trims entries and checks that each gives one text a reading
+func dictEntries(entries []string) (model.StringList, error) {
+	out := model.StringList{}
+	seen := map[string]bool{}
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		text, reading, ok := strings.Cut(e, "/")
+		text, reading = strings.TrimSpace(text), strings.TrimSpace(reading)
+		if !ok || text == "" || reading == "" {
+			return nil, fmt.Errorf("entry %q is not text/reading", e)
+		}
+		if seen[text] {
+			return nil, fmt.Errorf("%q has more than one reading", text)
+		}
+		seen[text] = true
+		out = append(out, text+"/"+reading)
+	}
+	return out, nil
+}
+
+// apply validates the request and copies it onto dict
+func (req *PronunciationDictRequest) apply(c *gin.Context, dict *model.PronunciationDict) bool {
+	if strings.TrimSpace(req.Name) == "" {
+		ErrorResponse(c, http.StatusBadRequest, 1, "name is required")
+		return false
+	}
+	entries, err := dictEntries(req.Entries)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 2, "Invalid entries: "+err.Error())
+		return false
+	}
+	if req.ProjectID > 0 {
+		if err := database.DB.First(&model.Project{}, req.ProjectID).Error; err != nil {
+			ErrorResponse(c, http.StatusNotFound, 3, "Project not found")
+			return false
+		}
+	}
+	dict.ProjectID = req.ProjectID
+	dict.VoiceID = strings.TrimSpace(req.VoiceID)
+	dict.Name = strings.TrimSpace(req.Name)
+	dict.Entries = entries
+	dict.Enabled = req.Enabled == nil || *req.Enabled
+	return true
+}
+
+// pronunciationDicts returns the enabled dictionaries of a project and the
+// global ones, those of a project first
+func pronunciationDicts(projectID uint) []model.PronunciationDict {
+	var dicts []model.PronunciationDict
+	database.DB.Where("enabled = ? AND project_id IN ?", true, []uint{0, projectID}).
+		Order("project_id desc, id asc").Find(&dicts)
+	return dicts
+}
+
+// addDictTones adds the entries of the dictionaries for the voice of req.
+// Readings the request already has win, then those of dictionaries bound
+// to the voice, then the others; within each, a project's win over global
+// ones.
+func addDictTones(req *minimax.T2ARequest, dicts []model.PronunciationDict) {
+	has := map[string]bool{}
+	existing, _ := req.PronunciationDict["tone"].([]any)
+	for _, t := range existing {
+		if s, ok := t.(string); ok {
+			has[toneText(s)] = true
+		}
+	}
+
+	var tones []string
+	for _, voiceOnly := range []bool{true, false} {
+		for _, d := range dicts {
+			if (d.VoiceID != "") != voiceOnly || voiceOnly && d.VoiceID != req.VoiceSetting.VoiceID {
+				continue
+			}
+			for _, e := range d.Entries {
+				if text := toneText(e); !has[text] {
+					has[text] = true
+					tones = append(tones, e)
+				}
+			}
+		}
+	}
+	addTones(req, tones)
+}
+
+// addTones adds pronunciation dict entries to a request, after those it
+// already has. Entries it has are not repeated.
+func addTones(req *minimax.T2ARequest, tones []string) {
+	if len(tones) == 0 {
+		return
+	}
+	if req.PronunciationDict == nil {
+		req.PronunciationDict = map[string]any{}
+	}
+	existing, _ := req.PronunciationDict["tone"].([]any)
+	seen := map[string]bool{}
+	for _, t := range existing {
+		if s, ok := t.(string); ok {
+			seen[s] = true
+		}
+	}
+	for _, t := range tones {
+		if !seen[t] {
+			existing = append(existing, t)
+			seen[t] = true
+		}
+	}
+	req.PronunciationDict["tone"] = existing
+}
+
+// ListPronunciationDicts returns dictionaries by name. project_id keeps
+// those of one project, 0 for the global ones; voice_id those of one voice.
+func ListPronunciationDicts(c *gin.Context) {
+	query := database.DB.Model(&model.PronunciationDict{})
+	if projectID := c.Query("project_id"); projectID != "" {
+		id, _ := strconv.Atoi(projectID)
+		query = query.Where("project_id = ?", id)
+	}
+	if voiceID := c.Query("voice_id"); voiceID != "" {
+		query = query.Where("voice_id = ?", voiceID)
+	}
+
+	dicts, page, err := findPage[model.PronunciationDict](c, query, listSort{
+		Fields:  map[string]string{"name": "name", "created_at": "created_at", "updated_at": "updated_at"},
+		Default: "name",
+		Order:   "asc",
+	})
+	if err != nil {
+		listErrorResponse(c, err, 1, "Failed to fetch dictionaries")
+		return
+	}
+	PagedSuccessResponse(c, dicts, page)
+}
+
+// CreatePronunciationDict adds a dictionary
+func CreatePronunciationDict(c *gin.Context) {
+	var req PronunciationDictRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 1, "name is required")
+		return
+	}
+
+	var dict model.PronunciationDict
+	if !req.apply(c, &dict) {
+		return
+	}
+	if err := database.DB.Create(&dict).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 4, "Failed to save dictionary")
+		return
+	}
+	SuccessResponse(c, dict)
+}
+
+// UpdatePronunciationDict replaces a dictionary
+func UpdatePronunciationDict(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var dict model.PronunciationDict
+	if err := database.DB.First(&dict, id).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, 5, "Dictionary not found")
+		return
+	}
+
+	var req PronunciationDictRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 1, "name is required")
+		return
+	}
+	if !req.apply(c, &dict) {
+		return
+	}
+	if err := database.DB.Save(&dict).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 4, "Failed to save dictionary")
+		return
+	}
+	SuccessResponse(c, dict)
+}
+
+// DeletePronunciationDict removes a dictionary
+func DeletePronunciationDict(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	if err := database.DB.Delete(&model.PronunciationDict{}, id).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 1, "Failed to delete dictionary")
+		return
+	}
+	SuccessResponse(c, nil)
+}
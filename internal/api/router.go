@@ -22,27 +22,139 @@ func SetupRouter(r *gin.Engine) {
 		// Keys
 		api.GET("/keys", ListKeys)
 		api.POST("/keys", AddKey)
+		api.PUT("/keys/:id", UpdateKey)
 		api.DELETE("/keys/:id", DeleteKey)
 		api.PUT("/keys/:id/default", SetDefaultKey)
+		api.POST("/keys/:id/reset", ResetKeyHealth)
 
 		// Voices
 		api.GET("/voices", ListVoices)
 		api.POST("/voices/clone", CloneVoice)
+		api.POST("/voices/clone/validate", ValidateCloneAudio)
+		api.GET("/voices/clone/samples", ListCloneSamples)
+		api.POST("/voices/clone/samples", PrepareCloneSample)
+		api.GET("/voices/clone/samples/:id/audio", GetCloneSampleAudio)
+		api.DELETE("/voices/clone/samples/:id", DeleteCloneSample)
+		api.GET("/voices/clone/jobs", ListCloneJobs)
+		api.GET("/voices/clone/jobs/:id", GetCloneJob)
+		api.POST("/voices/clone/jobs/:id/retry", RetryCloneJob)
+		api.DELETE("/voices/clone/jobs/:id", DeleteCloneJob)
 		api.POST("/voices/sync", SyncVoices)
+		api.POST("/voices/export", ExportVoices)
+		api.POST("/voices/import", ImportVoices)
 		api.POST("/voices/design", DesignVoice)
+		api.GET("/voices/design/sessions", ListDesignSessions)
+		api.POST("/voices/design/sessions", CreateDesignSession)
+		api.POST("/voices/design/sessions/cleanup", RetryDesignCleanup)
+		api.GET("/voices/design/sessions/:id", GetDesignSession)
+		api.DELETE("/voices/design/sessions/:id", DeleteDesignSession)
+		api.POST("/voices/design/sessions/:id/candidates", AddDesignCandidate)
+		api.DELETE("/voices/design/sessions/:id/candidates/:candidate_id", DiscardDesignCandidate)
+		api.POST("/voices/design/sessions/:id/promote", PromoteDesignCandidate)
 		api.POST("/voices/preview", GeneratePreview)
+		api.GET("/voices/preview/templates", ListPreviewTemplates)
+		api.POST("/voices/preview/templates", CreatePreviewTemplate)
+		api.PUT("/voices/preview/templates/:id", UpdatePreviewTemplate)
+		api.DELETE("/voices/preview/templates/:id", DeletePreviewTemplate)
+		api.GET("/voices/preview/jobs", ListPreviewJobs)
+		api.POST("/voices/preview/jobs", CreatePreviewJob)
+		api.GET("/voices/preview/jobs/:id", GetPreviewJob)
+		api.POST("/voices/preview/jobs/:id/pause", PausePreviewJob)
+		api.POST("/voices/preview/jobs/:id/resume", ResumePreviewJob)
+		api.DELETE("/voices/preview/jobs/:id", DeletePreviewJob)
+		api.PUT("/voices/previews/:id/default", SetDefaultVoicePreview)
+		api.DELETE("/voices/previews/:id", DeleteVoicePreview)
+		api.GET("/voices/:id/previews", ListVoicePreviews)
+		api.GET("/voices/compare", ListComparisons)
+		api.POST("/voices/compare", CompareVoices)
+		api.GET("/voices/compare/:id", GetComparison)
+		api.PUT("/voices/compare/:id", UpdateComparison)
+		api.DELETE("/voices/compare/:id", DeleteComparison)
+		api.PUT("/voices/compare/:id/candidates/:candidate_id", RateComparisonCandidate)
+		api.GET("/voices/expiring", ListExpiringVoices)
+		api.POST("/voices/:id/activate", ActivateVoice)
+		api.PUT("/voices/:id", UpdateVoice)
 		api.DELETE("/voices/:id", DeleteVoice)
 
 		// Favorites
 		api.GET("/favorites", ListFavorites)
 		api.POST("/favorites/:voice_id/toggle", ToggleFavorite)
 
+		// Synthesis presets
+		api.GET("/presets", ListPresets)
+		api.POST("/presets", CreatePreset)
+		api.PUT("/presets/:id", UpdatePreset)
+		api.DELETE("/presets/:id", DeletePreset)
+
+		// Pronunciation dictionaries
+		api.GET("/dictionaries", ListPronunciationDicts)
+		api.POST("/dictionaries", CreatePronunciationDict)
+		api.PUT("/dictionaries/:id", UpdatePronunciationDict)
+		api.DELETE("/dictionaries/:id", DeletePronunciationDict)
+
 		// Synthesis
 		api.GET("/synthesis", ListSynthesisTasks)
+		api.GET("/synthesis/search", SearchSynthesisTasks)
 		api.POST("/synthesis", GenerateSpeech)
 		api.POST("/synthesis/upload", UploadTextFile)
+		api.POST("/synthesis/markup", ConvertMarkup)
+		api.POST("/synthesis/normalize", NormalizeText)
+		api.POST("/synthesis/estimate", EstimateSpeech)
 		api.GET("/synthesis/:id/status", CheckTaskStatus)
+		api.GET("/synthesis/:id/input", GetSynthesisTaskInput)
+		api.GET("/synthesis/:id/history", GetSynthesisTaskHistory)
+		api.POST("/synthesis/:id/retry", RetrySynthesisTask)
+		api.POST("/synthesis/:id/duplicate", DuplicateSynthesisTask)
 		api.DELETE("/synthesis/:id", DeleteSynthesisTask)
+		api.POST("/synthesis/move", MoveSynthesisTasks)
+		api.POST("/synthesis/bulk", BulkUpdateSynthesisTasks)
+		api.PUT("/synthesis/:id", UpdateSynthesisTask)
+
+		// Projects
+		api.GET("/projects", ListProjects)
+		api.POST("/projects", CreateProject)
+		api.GET("/projects/:id", GetProject)
+		api.PUT("/projects/:id", UpdateProject)
+		api.DELETE("/projects/:id", DeleteProject)
+		api.GET("/projects/:id/export", ExportProject)
+		api.POST("/projects/:id/folders", CreateFolder)
+		api.PUT("/projects/:id/folders/:folder_id", UpdateFolder)
+		api.DELETE("/projects/:id/folders/:folder_id", DeleteFolder)
+
+		// Scripts
+		api.GET("/scripts", ListScripts)
+		api.POST("/scripts", CreateScript)
+		api.POST("/scripts/move", MoveScripts)
+		api.GET("/scripts/:id", GetScript)
+		api.PUT("/scripts/:id", UpdateScript)
+		api.DELETE("/scripts/:id", DeleteScript)
+
+		// Lines and their takes
+		api.GET("/lines", ListLines)
+		api.POST("/lines", CreateLine)
+		api.GET("/lines/:id", GetLine)
+		api.PUT("/lines/:id", UpdateLine)
+		api.DELETE("/lines/:id", DeleteLine)
+		api.POST("/lines/:id/takes", AddLineTakes)
+		api.DELETE("/lines/:id/takes/:task_id", RemoveLineTake)
+		api.PUT("/lines/:id/selected", SelectLineTake)
+		api.GET("/lines/:id/diff", DiffLineTakes)
+		api.GET("/lines/:id/export", ExportLine)
+
+		// Text normalization rules
+		api.GET("/normalization/rules", ListNormalizationRules)
+		api.POST("/normalization/rules", CreateNormalizationRule)
+		api.PUT("/normalization/rules/:id", UpdateNormalizationRule)
+		api.DELETE("/normalization/rules/:id", DeleteNormalizationRule)
+
+		// Audiobooks built from documents
+		api.GET("/audiobooks", ListAudiobooks)
+		api.POST("/audiobooks", CreateAudiobook)
+		api.GET("/audiobooks/:id", GetAudiobook)
+		api.DELETE("/audiobooks/:id", DeleteAudiobook)
+		api.PUT("/audiobooks/:id/chapters/:chapter_id", UpdateAudiobookChapter)
+		api.POST("/audiobooks/:id/build", BuildAudiobook)
+		api.POST("/audiobooks/:id/cancel", CancelAudiobookBuild)
 	}
 
 	// Static files for generated audio
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"minimax-voice-workbench/internal/database"
+	"minimax-voice-workbench/internal/model"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScriptRequest creates or updates a script. On update, omitted fields are
+// left unchanged.
+type ScriptRequest struct {
+	Name      *string `json:"name"`
+	Text      *string `json:"text"`
+	Notes     *string `json:"notes"`
+	ProjectID *uint   `json:"project_id"`
+	FolderID  *uint   `json:"folder_id"`
+}
+
+// MoveScriptsRequest moves scripts into a project folder. project_id 0
+// takes the scripts out of any project.
+type MoveScriptsRequest struct {
+	ScriptIDs []uint `json:"script_ids" binding:"required"`
+	ProjectID uint   `json:"project_id"`
+	FolderID  uint   `json:"folder_id"`
+}
+
+func loadScript(c *gin.Context) (*model.Script, error) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var script model.Script
+	if err := database.DB.First(&script, id).Error; err != nil {
+		return nil, err
+	}
+	return &script, nil
+}
+
+// ListScripts returns scripts without their text. project_id and
+// folder_id filter as for synthesis tasks; q matches names.
+func ListScripts(c *gin.Context) {
+	query := database.DB.Model(&model.Script{}).Omit("text")
+	if projectID := c.Query("project_id"); projectID == "none" {
+		query = query.Where("project_id = 0")
+	} else if id, _ := strconv.Atoi(projectID); id > 0 {
+		query = query.Where("project_id = ?", id)
+		if folderStr := c.Query("folder_id"); folderStr != "" {
+			folderID, _ := strconv.Atoi(folderStr)
+			if c.Query("recursive") == "true" && folderID > 0 {
+				query = query.Where("folder_id IN ?", folderSubtree(projectFolders(uint(id)), uint(folderID)))
+			} else {
+				query = query.Where("folder_id = ?", folderID)
+			}
+		}
+	}
+	if q := c.Query("q"); q != "" {
+		query = query.Where(`name LIKE ? ESCAPE '\'`, likeContains(q))
+	}
+
+	scripts, page, err := findPage[model.Script](c, query, listSort{
+		Fields:  map[string]string{"name": "name", "created_at": "created_at", "updated_at": "updated_at"},
+		Default: "updated_at",
+		Order:   "desc",
+	})
+	if err != nil {
+		listErrorResponse(c, err, 1, "Failed to fetch scripts")
+		return
+	}
+	PagedSuccessResponse(c, scripts, page)
+}
+
+// CreateScript creates a script
+func CreateScript(c *gin.Context) {
+	var req ScriptRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Name == nil || strings.TrimSpace(*req.Name) == "" {
+		ErrorResponse(c, http.StatusBadRequest, 1, "name is required")
+		return
+	}
+
+	script := model.Script{Name: strings.TrimSpace(*req.Name)}
+	if req.Text != nil {
+		script.Text = *req.Text
+	}
+	if req.Notes != nil {
+		script.Notes = *req.Notes
+	}
+	if req.ProjectID != nil {
+		script.ProjectID = *req.ProjectID
+	}
+	if req.FolderID != nil {
+		script.FolderID = *req.FolderID
+	}
+	if err := checkProjectPlace(script.ProjectID, script.FolderID); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 2, "Project or folder not found")
+		return
+	}
+	if err := database.DB.Create(&script).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 3, "Failed to create script")
+		return
+	}
+	SuccessResponse(c, script)
+}
+
+// GetScript returns a script with its text
+func GetScript(c *gin.Context) {
+	script, err := loadScript(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Script not found")
+		return
+	}
+	SuccessResponse(c, script)
+}
+
+// UpdateScript renames a script, changes its text or notes, or moves it
+func UpdateScript(c *gin.Context) {
+	script, err := loadScript(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Script not found")
+		return
+	}
+
+	var req ScriptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 2, "Invalid request")
+		return
+	}
+	if req.Name != nil {
+		if strings.TrimSpace(*req.Name) == "" {
+			ErrorResponse(c, http.StatusBadRequest, 2, "name cannot be empty")
+			return
+		}
+		script.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Text != nil {
+		script.Text = *req.Text
+	}
+	if req.Notes != nil {
+		script.Notes = *req.Notes
+	}
+	if req.ProjectID != nil {
+		script.ProjectID = *req.ProjectID
+		script.FolderID = 0
+	}
+	if req.FolderID != nil {
+		script.FolderID = *req.FolderID
+	}
+	if err := checkProjectPlace(script.ProjectID, script.FolderID); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 3, "Project or folder not found")
+		return
+	}
+	if err := database.DB.Save(script).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 4, "Failed to update script")
+		return
+	}
+	SuccessResponse(c, script)
+}
+
+// DeleteScript deletes a script
+func DeleteScript(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	if err := database.DB.Delete(&model.Script{}, id).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 1, "Failed to delete script")
+		return
+	}
+	SuccessResponse(c, nil)
+}
+
+// MoveScripts moves scripts into a project folder in one go
+func MoveScripts(c *gin.Context) {
+	var req MoveScriptsRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.ScriptIDs) == 0 {
+		ErrorResponse(c, http.StatusBadRequest, 1, "script_ids is required")
+		return
+	}
+	if err := checkProjectPlace(req.ProjectID, req.FolderID); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 2, "Project or folder not found")
+		return
+	}
+
+	result := database.DB.Model(&model.Script{}).Where("id IN ?", req.ScriptIDs).
+		Updates(map[string]interface{}{"project_id": req.ProjectID, "folder_id": req.FolderID})
+	if result.Error != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 3, "Failed to move scripts")
+		return
+	}
+	SuccessResponse(c, gin.H{"moved": result.RowsAffected})
+}
@@ -7,6 +7,7 @@ import (
 	"mime"
 	"mime/multipart"
 	"minimax-voice-workbench/internal/database"
+	"minimax-voice-workbench/internal/markup"
 	"minimax-voice-workbench/internal/model"
 	"minimax-voice-workbench/pkg/minimax"
 	"net/http"
@@ -18,23 +19,77 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// Sortable columns of ListSynthesisTasks
+var taskSortFields = map[string]string{
+	"id":            "id",
+	"created_at":    "created_at",
+	"updated_at":    "updated_at",
+	"status":        "status",
+	"voice_id":      "voice_id",
+	"review_status": "review_status",
+	"rating":        "rating",
+}
+
 // ListSynthesisTasks 获取语音合成任务列表
 func ListSynthesisTasks(c *gin.Context) {
-	var tasks []model.SynthesisTask
-	query := database.DB.Model(&model.SynthesisTask{})
-
-	// Filters
+	query := filterSynthesisTasks(c, database.DB.Model(&model.SynthesisTask{}))
 	if text := c.Query("text"); text != "" {
-		query = query.Where("text LIKE ?", "%"+text+"%")
+		query = matchTaskText(query, text)
+	}
+
+	tasks, page, err := findPage[model.SynthesisTask](c, query, listSort{Fields: taskSortFields, Default: "created_at", Order: "desc"})
+	if err != nil {
+		listErrorResponse(c, err, 1, "Failed to fetch tasks")
+		return
 	}
+	PagedSuccessResponse(c, tasks, page)
+}
+
+// filterSynthesisTasks applies the task filters of the query string other
+// than text
+func filterSynthesisTasks(c *gin.Context, query *gorm.DB) *gorm.DB {
 	if status := c.Query("status"); status != "" {
 		query = query.Where("status = ?", status)
 	}
 	if voiceID := c.Query("voice_id"); voiceID != "" {
 		query = query.Where("voice_id = ?", voiceID)
 	}
+	// project_id=none lists tasks outside any project
+	if projectID := c.Query("project_id"); projectID == "none" {
+		query = query.Where("project_id = 0")
+	} else if id, _ := strconv.Atoi(projectID); id > 0 {
+		query = query.Where("project_id = ?", id)
+		// folder_id=0 is the project root; recursive=true includes subfolders
+		if folderStr := c.Query("folder_id"); folderStr != "" {
+			folderID, _ := strconv.Atoi(folderStr)
+			if c.Query("recursive") == "true" && folderID > 0 {
+				query = query.Where("folder_id IN ?", folderSubtree(projectFolders(uint(id)), uint(folderID)))
+			} else {
+				query = query.Where("folder_id = ?", folderID)
+			}
+		}
+	}
+	if tag := c.Query("tag"); tag != "" {
+		query = whereTag(query, tag)
+	}
+	if reviewStatus := c.Query("review_status"); reviewStatus != "" {
+		query = query.Where("review_status = ?", reviewStatus)
+	}
+	if minRating, _ := strconv.Atoi(c.Query("min_rating")); minRating > 0 {
+		query = query.Where("rating >= ?", minRating)
+	}
+	if parentID, _ := strconv.Atoi(c.Query("parent_id")); parentID > 0 {
+		query = query.Where("parent_id = ?", parentID)
+	}
+	if lineID, _ := strconv.Atoi(c.Query("line_id")); lineID > 0 {
+		query = query.Where("line_id = ?", lineID)
+	}
+	if c.Query("favorite") == "true" {
+		query = query.Where("is_favorite = ?", true)
+	}
 	if startDate := c.Query("start_date"); startDate != "" {
 		if t, err := time.Parse("2006-01-02", startDate); err == nil {
 			query = query.Where("created_at >= ?", t)
@@ -46,17 +101,26 @@ func ListSynthesisTasks(c *gin.Context) {
 			query = query.Where("created_at < ?", t.Add(24*time.Hour))
 		}
 	}
-
-	if err := query.Order("created_at desc").Find(&tasks).Error; err != nil {
-		ErrorResponse(c, http.StatusInternalServerError, 1, "Failed to fetch tasks")
-		return
-	}
-	SuccessResponse(c, tasks)
+	return query
 }
 
 type GenerateSpeechRequest struct {
-	KeyID uint `json:"key_id"`
+	KeyID       uint `json:"key_id"`
+	ProjectID   uint `json:"project_id"` // Project defaults fill in omitted settings
+	PresetID    uint `json:"preset_id"`  // Preset settings fill in omitted settings, before project defaults
+	FolderID    uint `json:"folder_id"`
+	TextInputID uint `json:"text_input_id"` // Stored upload, used instead of text_file_id
+	LineID      uint `json:"line_id"`       // Records the task as a take of the line
+	// Text is written in the SSML subset of package markup and converted
+	// before submitting. Input files are sent as they are.
+	Markup *markup.Options `json:"markup"`
+	// Normalization rules run over the text: rules (the default) for the
+	// user rules, all to add the built-in normalizers, or off
+	Normalization string `json:"normalization"`
 	minimax.T2ARequest
+
+	sourceText string // Text as written, before markup and normalization
+	stored     bool   // Rebuilt from a task, with its dictionary entries in place
 }
 
 // GenerateSpeech 提交异步语音合成任务
@@ -67,35 +131,148 @@ func GenerateSpeech(c *gin.Context) {
 		return
 	}
 
-	if req.Text == "" && req.TextFileID == 0 {
-		ErrorResponse(c, http.StatusBadRequest, 5, "Text or TextFileID is required")
+	input, ok := checkSpeechRequest(c, &req)
+	if !ok {
 		return
 	}
+	submitSpeech(c, &req, input, 0)
+}
 
-	apiKey, err := getEffectiveKey(req.KeyID)
-	if err != nil {
-		ErrorResponse(c, http.StatusBadRequest, 3, "Invalid API Key or No Default Key")
-		return
+// checkSpeechRequest validates req and fills in the defaults of its project.
+// It returns the stored input to synthesize from, if any; problems are
+// reported to the client.
+func checkSpeechRequest(c *gin.Context, req *GenerateSpeechRequest) (*model.TextFile, bool) {
+	// Takes default to the text and place of their line
+	if req.LineID > 0 {
+		var line model.Line
+		if err := database.DB.First(&line, req.LineID).Error; err != nil {
+			ErrorResponse(c, http.StatusNotFound, 10, "Line not found")
+			return nil, false
+		}
+		if req.Text == "" && req.TextFileID == 0 && req.TextInputID == 0 {
+			req.Text = line.Text
+		}
+		if req.ProjectID == 0 {
+			req.ProjectID, req.FolderID = line.ProjectID, line.FolderID
+		}
+	}
+
+	if req.Text == "" && req.TextFileID == 0 && req.TextInputID == 0 {
+		ErrorResponse(c, http.StatusBadRequest, 5, "Text or TextFileID is required")
+		return nil, false
+	}
+
+	if !normalizationModes[req.Normalization] {
+		ErrorResponse(c, http.StatusBadRequest, 12, "normalization must be rules, all or off")
+		return nil, false
+	}
+
+	input := findTextInput(req.TextInputID, req.TextFileID)
+	if req.TextInputID > 0 && input == nil {
+		ErrorResponse(c, http.StatusNotFound, 8, "Text input not found")
+		return nil, false
+	}
+
+	if req.PresetID > 0 {
+		var preset model.Preset
+		if err := database.DB.First(&preset, req.PresetID).Error; err != nil {
+			ErrorResponse(c, http.StatusNotFound, 14, "Preset not found")
+			return nil, false
+		}
+		applyProjectDefaults(presetDefaults(&preset), &req.T2ARequest)
 	}
 
-	client := minimax.NewClient(apiKey.Key)
+	if req.ProjectID > 0 {
+		var project model.Project
+		if err := database.DB.First(&project, req.ProjectID).Error; err != nil {
+			ErrorResponse(c, http.StatusNotFound, 6, "Project not found")
+			return nil, false
+		}
+		if err := checkProjectFolder(project.ID, req.FolderID); err != nil {
+			ErrorResponse(c, http.StatusBadRequest, 7, "Folder not found in project")
+			return nil, false
+		}
+		applyProjectDefaults(&project, &req.T2ARequest)
+	}
 
+	if req.Text != "" {
+		if req.sourceText == "" {
+			req.sourceText = req.Text
+		}
+		if req.Markup != nil {
+			res, err := markup.Convert(req.Text, *req.Markup)
+			if err != nil {
+				ErrorResponseWithData(c, http.StatusBadRequest, 11, "Invalid markup: "+err.Error(), err)
+				return nil, false
+			}
+			req.Text = res.Text
+			addTones(&req.T2ARequest, res.Tone)
+			req.Markup = nil
+		}
+		// After markup, so that its pause markers are left alone
+		req.Text = normalizeText(req.Text, normalizationRules(req.ProjectID), req.LanguageBoost, req.Normalization)
+	}
+	// After markup too, whose readings win over the dictionaries
+	if !req.stored {
+		addDictTones(&req.T2ARequest, pronunciationDicts(req.ProjectID))
+	}
+	return input, true
+}
+
+// submitSpeech submits a checked request and records the task, linked to
+// parentID when it was retried or duplicated from another task. A failed
+// submission is recorded too.
+func submitSpeech(c *gin.Context, req *GenerateSpeechRequest, input *model.TextFile, parentID uint) {
 	t2aReq := &req.T2ARequest
+	var resp *minimax.T2AAsyncResponse
+	submit := func(client *minimax.Client) error {
+		var err error
+		resp, err = client.T2AAsync(t2aReq)
+		return err
+	}
 
-	payloadBytes, _ := json.Marshal(t2aReq)
+	// Uploaded text files belong to the account that uploaded them,
+	// so file-based tasks cannot fail over to another key
+	var apiKey *model.ApiKey
+	var err error
+	if input != nil {
+		apiKey, err = withTextInput(input, req.KeyID, t2aReq, submit)
+	} else if req.TextFileID > 0 {
+		apiKey, err = withKey(req.KeyID, submit)
+	} else {
+		apiKey, err = withVoiceKey(req.VoiceSetting.VoiceID, req.KeyID, submit)
+	}
+	if apiKey == nil {
+		ErrorResponse(c, http.StatusBadRequest, 3, "Invalid API Key or No Default Key")
+		return
+	}
 
-	resp, err := client.T2AAsync(t2aReq)
+	// Recorded after submitting, as a stored input may have been uploaded again
+	payloadBytes, _ := json.Marshal(t2aReq)
 	task := model.SynthesisTask{
-		Text:           req.Text,
+		Text:           req.sourceText,
 		VoiceID:        req.VoiceSetting.VoiceID,
 		Format:         req.AudioSetting.Format,
 		SampleRate:     req.AudioSetting.AudioSampleRate,
 		Channel:        req.AudioSetting.Channel,
 		Status:         "processing",
 		RequestPayload: string(payloadBytes),
+		KeyID:          apiKey.ID,
+		ProjectID:      req.ProjectID,
+		FolderID:       req.FolderID,
+		ParentID:       parentID,
+		LineID:         req.LineID,
 	}
-	if req.TextFileID > 0 {
-		task.Text = fmt.Sprintf("FileID: %d", req.TextFileID)
+	if req.Text != req.sourceText {
+		task.NormalizedText = req.Text
+	}
+	if t2aReq.TextFileID > 0 {
+		task.Text = fmt.Sprintf("FileID: %d", t2aReq.TextFileID)
+		task.TextFileID = t2aReq.TextFileID
+	}
+	if input != nil {
+		task.Text = inputPreview(input.Text)
+		task.TextInputID = input.ID
 	}
 
 	if err != nil {
@@ -113,6 +290,13 @@ func GenerateSpeech(c *gin.Context) {
 
 var taskMutexes sync.Map
 
+// lockTask serializes status checks of a task, so that its audio is only
+// downloaded once
+func lockTask(id uint) *sync.Mutex {
+	muVal, _ := taskMutexes.LoadOrStore(id, &sync.Mutex{})
+	return muVal.(*sync.Mutex)
+}
+
 // CheckTaskStatus 检查异步任务状态并下载结果
 func CheckTaskStatus(c *gin.Context) {
 	idStr := c.Param("id")
@@ -133,8 +317,7 @@ func CheckTaskStatus(c *gin.Context) {
 
 	// Concurrency control: Lock based on task ID
 	// This prevents multiple requests from triggering download simultaneously
-	muVal, _ := taskMutexes.LoadOrStore(id, &sync.Mutex{})
-	mu := muVal.(*sync.Mutex)
+	mu := lockTask(task.ID)
 	mu.Lock()
 	defer mu.Unlock()
 
@@ -156,32 +339,57 @@ func CheckTaskStatus(c *gin.Context) {
 	}
 
 	keyID, _ := strconv.Atoi(keyIDStr)
-	apiKey, err := getEffectiveKey(uint(keyID))
-	if err != nil {
+	apiKey, err := pollSynthesisTask(&task, uint(keyID))
+	if apiKey == nil {
 		ErrorResponse(c, http.StatusBadRequest, 3, "No valid API Key available")
 		return
 	}
-
-	client := minimax.NewClient(apiKey.Key)
-	qResp, err := client.T2AAsyncQuery(task.TaskID)
 	if err != nil {
 		ErrorResponse(c, http.StatusInternalServerError, 4, "Query Failed: "+err.Error())
 		return
 	}
+	SuccessResponse(c, task)
+}
+
+// pollSynthesisTask queries the Minimax status of a processing async task,
+// downloads its audio once it succeeded and saves the outcome. The caller
+// holds the lock of the task. apiKey is nil when no key is usable.
+func pollSynthesisTask(task *model.SynthesisTask, keyID uint) (*model.ApiKey, error) {
+	// Async tasks can only be queried with the key that submitted them
+	if task.KeyID > 0 {
+		keyID = task.KeyID
+	}
+
+	var qResp *minimax.T2AAsyncQueryResponse
+	var fResp *minimax.FileRetrieveResponse
+	var retrieveErr error
+	apiKey, err := withKey(keyID, func(client *minimax.Client) error {
+		var err error
+		qResp, err = client.T2AAsyncQuery(task.TaskID)
+		if err != nil || qResp.Status != "Success" {
+			return err
+		}
+		fResp, retrieveErr = client.RetrieveFile(qResp.FileID)
+		return nil
+	})
+	if apiKey == nil || err != nil {
+		return apiKey, err
+	}
 
 	statusLower := qResp.Status
 
 	switch statusLower {
 	case "Success":
-		fResp, err := client.RetrieveFile(qResp.FileID)
-		if err != nil {
-			task.Error = "Retrieve failed: " + err.Error()
+		if retrieveErr != nil {
+			task.Error = "Retrieve failed: " + retrieveErr.Error()
 		} else {
-			err := downloadFile(fResp.File.DownloadURL, &task)
+			err := downloadFile(fResp.File.DownloadURL, task)
 			if err != nil {
 				task.Error = "Download failed: " + err.Error()
 			} else {
 				task.Status = "success"
+				// Only a finished synthesis counts as using the voice
+				markVoiceUsed(task.VoiceID)
 			}
 		}
 	case "Failed", "Expired":
@@ -191,8 +399,8 @@ func CheckTaskStatus(c *gin.Context) {
 		task.Status = "processing"
 	}
 
-	database.DB.Save(&task)
-	SuccessResponse(c, task)
+	database.DB.Save(task)
+	return apiKey, nil
 }
 
 // downloadFile 从指定 URL 下载音频文件并保存到本地
@@ -264,20 +472,22 @@ func DeleteSynthesisTask(c *gin.Context) {
 		ErrorResponse(c, http.StatusInternalServerError, 7, "Failed to delete task")
 		return
 	}
+	database.DB.Model(&model.Line{}).Where("selected_task_id = ?", id).Update("selected_task_id", 0)
 	SuccessResponse(c, nil)
 }
 
+// UploadTextFileResponse 返回上传的文件信息及所用的 Key（后续合成需使用同一 Key）
+type UploadTextFileResponse struct {
+	minimax.UploadFileData
+	KeyID   uint `json:"key_id"`
+	InputID uint `json:"input_id"` // Stored copy; pass as text_input_id to synthesize from it
+}
+
 // UploadTextFile 上传文本文件用于异步语音合成
 func UploadTextFile(c *gin.Context) {
 	keyIDStr := c.PostForm("key_id")
 	keyID, _ := strconv.Atoi(keyIDStr)
 
-	apiKey, err := getEffectiveKey(uint(keyID))
-	if err != nil {
-		ErrorResponse(c, http.StatusBadRequest, 1, "Invalid API Key or No Default Key")
-		return
-	}
-
 	fileHeader, err := c.FormFile("file")
 	if err != nil {
 		ErrorResponse(c, http.StatusBadRequest, 2, "File upload required")
@@ -291,21 +501,51 @@ func UploadTextFile(c *gin.Context) {
 		return
 	}
 
-	tempDir := "uploads"
-	os.MkdirAll(tempDir, 0755)
-	tempPath := filepath.Join(tempDir, fmt.Sprintf("%d_%s", time.Now().Unix(), fileHeader.Filename))
-	if err := c.SaveUploadedFile(fileHeader, tempPath); err != nil {
+	// The original is kept, so it can be uploaded again once Minimax drops it
+	os.MkdirAll(textFileDir, 0755)
+	path := filepath.Join(textFileDir, fmt.Sprintf("%d_%s", time.Now().UnixNano(), filepath.Base(fileHeader.Filename)))
+	if err := c.SaveUploadedFile(fileHeader, path); err != nil {
 		ErrorResponse(c, http.StatusInternalServerError, 4, "Failed to save file")
 		return
 	}
-	defer os.Remove(tempPath)
 
-	client := minimax.NewClient(apiKey.Key)
-	resp, err := client.UploadFile(tempPath, "t2a_async_input")
+	text, err := extractInputText(path)
+	if err != nil {
+		os.Remove(path)
+		ErrorResponse(c, http.StatusBadRequest, 6, "Failed to read file: "+err.Error())
+		return
+	}
+
+	var resp *minimax.UploadResponse
+	apiKey, err := withKeyFailover(uint(keyID), func(client *minimax.Client) error {
+		var err error
+		resp, err = client.UploadFile(path, "t2a_async_input")
+		return err
+	})
+	if apiKey == nil || err != nil {
+		os.Remove(path)
+	}
+	if apiKey == nil {
+		ErrorResponse(c, http.StatusBadRequest, 1, "Invalid API Key or No Default Key")
+		return
+	}
 	if err != nil {
 		ErrorResponse(c, http.StatusInternalServerError, 5, "Minimax Upload Failed: "+err.Error())
 		return
 	}
 
-	SuccessResponse(c, resp.File)
+	input := model.TextFile{
+		FileID:   resp.File.FileID,
+		KeyID:    apiKey.ID,
+		Filename: fileHeader.Filename,
+		Path:     path,
+		Size:     fileHeader.Size,
+		Text:     text,
+	}
+	if err := database.DB.Create(&input).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 7, "Failed to save file record")
+		return
+	}
+
+	SuccessResponse(c, UploadTextFileResponse{UploadFileData: resp.File, KeyID: apiKey.ID, InputID: input.ID})
 }
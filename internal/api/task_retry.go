@@ -0,0 +1,189 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"minimax-voice-workbench/internal/database"
+	"minimax-voice-workbench/internal/model"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// storedSpeechRequest rebuilds the request a task was submitted with from
+// its RequestPayload, keeping its project, folder, input and line. The
+// stored text was normalized already, so normalization is off, and the
+// stored dictionary entries are kept as they were.
+func storedSpeechRequest(task *model.SynthesisTask) (*GenerateSpeechRequest, error) {
+	req := &GenerateSpeechRequest{
+		ProjectID:     task.ProjectID,
+		FolderID:      task.FolderID,
+		TextInputID:   task.TextInputID,
+		LineID:        task.LineID,
+		Normalization: "off",
+		stored:        true,
+	}
+	if err := json.Unmarshal([]byte(task.RequestPayload), &req.T2ARequest); err != nil {
+		return nil, err
+	}
+	if req.Text != "" {
+		req.sourceText = task.Text
+	}
+	return req, nil
+}
+
+// speechKey picks the key to resubmit req with when none is given. Files
+// uploaded before inputs were stored only exist for the account that
+// uploaded them; otherwise the voice or input decides.
+func speechKey(req *GenerateSpeechRequest, task *model.SynthesisTask) {
+	if req.KeyID == 0 && req.TextFileID > 0 && req.TextInputID == 0 {
+		req.KeyID = task.KeyID
+	}
+}
+
+// RetrySynthesisTaskRequest optionally overrides the key of the failed task
+type RetrySynthesisTaskRequest struct {
+	KeyID uint `json:"key_id"`
+}
+
+// RetrySynthesisTask submits a failed task again from its stored request.
+// The failed task is kept and becomes the parent of the new one.
+func RetrySynthesisTask(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	var body RetrySynthesisTaskRequest
+	if err := bindOptionalJSON(c, &body); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 10, "Invalid request body")
+		return
+	}
+
+	var task model.SynthesisTask
+	if err := database.DB.First(&task, id).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Task not found")
+		return
+	}
+	if task.Status != "failed" {
+		ErrorResponse(c, http.StatusBadRequest, 2, "Only failed tasks can be retried")
+		return
+	}
+
+	req, err := storedSpeechRequest(&task)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 9, "Stored request of the task is unreadable")
+		return
+	}
+	req.KeyID = body.KeyID
+	speechKey(req, &task)
+
+	input, ok := checkSpeechRequest(c, req)
+	if !ok {
+		return
+	}
+	submitSpeech(c, req, input, task.ID)
+}
+
+// DuplicateSynthesisTask submits a new task from the stored request of
+// another. The body takes the fields of GenerateSpeech, each replacing the
+// stored value; text replaces an input file and the other way round.
+func DuplicateSynthesisTask(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	var task model.SynthesisTask
+	if err := database.DB.First(&task, id).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Task not found")
+		return
+	}
+
+	req, err := storedSpeechRequest(&task)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 9, "Stored request of the task is unreadable")
+		return
+	}
+
+	body, _ := io.ReadAll(c.Request.Body)
+	var overrides struct {
+		Text              *string        `json:"text"`
+		TextFileID        *int64         `json:"text_file_id"`
+		TextInputID       *uint          `json:"text_input_id"`
+		Normalization     *string        `json:"normalization"`
+		PronunciationDict map[string]any `json:"pronunciation_dict"`
+	}
+	if len(body) > 0 {
+		// Nested settings are merged, so voice_setting.speed alone keeps the voice
+		if json.Unmarshal(body, req) != nil || json.Unmarshal(body, &overrides) != nil {
+			ErrorResponse(c, http.StatusBadRequest, 2, "Invalid request body")
+			return
+		}
+	}
+	switch {
+	case overrides.Text != nil && *overrides.Text != "":
+		req.TextFileID, req.TextInputID = 0, 0
+		// New text is normalized unless asked otherwise
+		req.sourceText = ""
+		if overrides.Normalization == nil {
+			req.Normalization = ""
+		}
+		// The stored tones were resolved for the old text; the project
+		// dictionaries are matched again unless tones are given
+		req.stored = false
+		if overrides.PronunciationDict["tone"] == nil {
+			delete(req.PronunciationDict, "tone")
+		}
+	case overrides.TextInputID != nil:
+		req.Text = ""
+	case overrides.TextFileID != nil:
+		req.Text, req.TextInputID = "", 0
+	}
+	speechKey(req, &task)
+
+	input, ok := checkSpeechRequest(c, req)
+	if !ok {
+		return
+	}
+	submitSpeech(c, req, input, task.ID)
+}
+
+// Most tasks walked when following parents, in case of a cycle
+const maxTaskLineage = 1000
+
+// GetSynthesisTaskHistory returns every task descending from the original
+// of a task, oldest first, so that its retries and variations can be traced
+func GetSynthesisTaskHistory(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	var root model.SynthesisTask
+	if err := database.DB.First(&root, id).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Task not found")
+		return
+	}
+	// Deleted ancestors end the walk
+	for i := 0; i < maxTaskLineage && root.ParentID > 0; i++ {
+		var parent model.SynthesisTask
+		if database.DB.First(&parent, root.ParentID).Error != nil {
+			break
+		}
+		root = parent
+	}
+
+	tasks := []model.SynthesisTask{root}
+	seen := map[uint]bool{root.ID: true}
+	for level := []uint{root.ID}; len(level) > 0 && len(tasks) < maxTaskLineage; {
+		var children []model.SynthesisTask
+		if err := database.DB.Where("parent_id IN ?", level).Order("id").Find(&children).Error; err != nil {
+			ErrorResponse(c, http.StatusInternalServerError, 2, "Failed to fetch history")
+			return
+		}
+		level = nil
+		for _, child := range children {
+			if !seen[child.ID] {
+				seen[child.ID] = true
+				tasks = append(tasks, child)
+				level = append(level, child.ID)
+			}
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	SuccessResponse(c, tasks)
+}
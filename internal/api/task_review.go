@@ -0,0 +1,159 @@
+package api
+
+import (
+	"minimax-voice-workbench/internal/database"
+	"minimax-voice-workbench/internal/model"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Review states of a synthesis task
+var taskReviewStatuses = []string{"draft", "approved", "rejected"}
+
+// UpdateTaskReviewRequest edits the review fields of a task.
+// Omitted fields are left unchanged.
+type UpdateTaskReviewRequest struct {
+	Tags         *[]string `json:"tags"`
+	ReviewStatus *string   `json:"review_status"` // draft, approved, rejected
+	Rating       *int      `json:"rating"`        // 1-5, 0 clears
+	Notes        *string   `json:"notes"`
+	IsFavorite   *bool     `json:"is_favorite"`
+}
+
+// BulkUpdateTasksRequest applies the same review changes to many tasks.
+// Tags are added and removed rather than replaced.
+type BulkUpdateTasksRequest struct {
+	TaskIDs      []uint   `json:"task_ids" binding:"required"`
+	AddTags      []string `json:"add_tags"`
+	RemoveTags   []string `json:"remove_tags"`
+	ReviewStatus *string  `json:"review_status"`
+	Rating       *int     `json:"rating"`
+	Notes        *string  `json:"notes"`
+	IsFavorite   *bool    `json:"is_favorite"`
+}
+
+// reviewUpdates validates the shared review fields and collects them as
+// column updates. It returns a message when a value is invalid.
+func reviewUpdates(status *string, rating *int, notes *string, favorite *bool) (map[string]interface{}, string) {
+	updates := map[string]interface{}{}
+	if status != nil {
+		if !slices.Contains(taskReviewStatuses, *status) {
+			return nil, "review_status must be draft, approved or rejected"
+		}
+		updates["review_status"] = *status
+	}
+	if rating != nil {
+		if *rating < 0 || *rating > 5 {
+			return nil, "rating must be between 0 and 5"
+		}
+		updates["rating"] = *rating
+	}
+	if notes != nil {
+		updates["notes"] = *notes
+	}
+	if favorite != nil {
+		updates["is_favorite"] = *favorite
+	}
+	return updates, ""
+}
+
+// UpdateSynthesisTask edits the tags, review status, rating, notes and
+// favorite flag of a task
+func UpdateSynthesisTask(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	var req UpdateTaskReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 1, "Invalid request")
+		return
+	}
+
+	var task model.SynthesisTask
+	if err := database.DB.First(&task, id).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, 2, "Task not found")
+		return
+	}
+
+	updates, msg := reviewUpdates(req.ReviewStatus, req.Rating, req.Notes, req.IsFavorite)
+	if msg != "" {
+		ErrorResponse(c, http.StatusBadRequest, 3, msg)
+		return
+	}
+	if req.Tags != nil {
+		updates["tags"] = cleanTags(*req.Tags)
+	}
+
+	if len(updates) > 0 {
+		if err := database.DB.Model(&task).Updates(updates).Error; err != nil {
+			ErrorResponse(c, http.StatusInternalServerError, 4, "Failed to update task")
+			return
+		}
+	}
+
+	database.DB.First(&task, id)
+	SuccessResponse(c, task)
+}
+
+// BulkUpdateSynthesisTasks applies review changes to a set of tasks
+func BulkUpdateSynthesisTasks(c *gin.Context) {
+	var req BulkUpdateTasksRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.TaskIDs) == 0 {
+		ErrorResponse(c, http.StatusBadRequest, 1, "task_ids is required")
+		return
+	}
+
+	updates, msg := reviewUpdates(req.ReviewStatus, req.Rating, req.Notes, req.IsFavorite)
+	if msg != "" {
+		ErrorResponse(c, http.StatusBadRequest, 2, msg)
+		return
+	}
+	addTags := cleanTags(req.AddTags)
+	removeTags := cleanTags(req.RemoveTags)
+
+	var updated int64
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			result := tx.Model(&model.SynthesisTask{}).Where("id IN ?", req.TaskIDs).Updates(updates)
+			if result.Error != nil {
+				return result.Error
+			}
+			updated = result.RowsAffected
+		}
+		if len(addTags) == 0 && len(removeTags) == 0 {
+			return nil
+		}
+
+		// Tags are a JSON list per row, so they are merged task by task
+		var tasks []model.SynthesisTask
+		if err := tx.Select("id", "tags").Where("id IN ?", req.TaskIDs).Find(&tasks).Error; err != nil {
+			return err
+		}
+		for _, task := range tasks {
+			tags := model.StringList{}
+			for _, t := range task.Tags {
+				if !slices.Contains(removeTags, t) {
+					tags = append(tags, t)
+				}
+			}
+			for _, t := range addTags {
+				if !slices.Contains(tags, t) {
+					tags = append(tags, t)
+				}
+			}
+			if err := tx.Model(&model.SynthesisTask{}).Where("id = ?", task.ID).Update("tags", tags).Error; err != nil {
+				return err
+			}
+		}
+		updated = max(updated, int64(len(tasks)))
+		return nil
+	})
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 3, "Failed to update tasks")
+		return
+	}
+	SuccessResponse(c, gin.H{"updated": updated})
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"html"
+	"minimax-voice-workbench/internal/database"
+	"minimax-voice-workbench/internal/model"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Shortest word the trigram index can match
+const minSearchWordLen = 3
+
+// Snippet markers, replaced by <mark> once the snippet is HTML-escaped
+const (
+	snippetOpen  = "\x02"
+	snippetClose = "\x03"
+)
+
+// TaskSearchResult is a task found by SearchSynthesisTasks
+type TaskSearchResult struct {
+	model.SynthesisTask
+	Snippet string  `json:"snippet"` // HTML with the matches in <mark>
+	Score   float64 `json:"score"`   // Higher is more relevant
+}
+
+// ftsQuery turns search input into an FTS5 query that requires every word.
+// ok is false when a word is too short for the trigram index.
+func ftsQuery(words []string) (match string, ok bool) {
+	if len(words) == 0 {
+		return "", false
+	}
+	phrases := make([]string, len(words))
+	for i, w := range words {
+		if utf8.RuneCountInString(w) < minSearchWordLen {
+			return "", false
+		}
+		phrases[i] = `"` + strings.ReplaceAll(w, `"`, `""`) + `"`
+	}
+	return strings.Join(phrases, " "), true
+}
+
+// matchTaskText limits query to tasks whose text, notes, tags or input file
+// contain every word of text
+func matchTaskText(query *gorm.DB, text string) *gorm.DB {
+	words := strings.Fields(text)
+	if match, ok := ftsQuery(words); ok {
+		return query.Where("id IN (?)", database.DB.Table(database.TaskSearchTable).
+			Select("rowid").Where(database.TaskSearchTable+" MATCH ?", match))
+	}
+	// Short words are not in the index, so the indexed columns are scanned
+	for _, w := range words {
+		like := likeContains(w)
+		query = query.Where("id IN (?)", database.DB.Table(database.TaskSearchTable).Select("rowid").
+			Where(`text LIKE ? ESCAPE '\' OR notes LIKE ? ESCAPE '\' OR tags LIKE ? ESCAPE '\' OR file_text LIKE ? ESCAPE '\'`, like, like, like, like))
+	}
+	return query
+}
+
+// markSnippet escapes a snippet with markers and turns the markers into
+// <mark> tags
+func markSnippet(s string) string {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, snippetOpen, "<mark>")
+	return strings.ReplaceAll(s, snippetClose, "</mark>")
+}
+
+// likeSnippet cuts the text around the first word found in it and marks
+// every word, for searches the index cannot rank
+func likeSnippet(text string, words []string) string {
+	const context = 24
+	runes := []rune(text)
+	start, end := 0, min(len(runes), 2*context)
+	for _, w := range words {
+		if i := strings.Index(text, w); i >= 0 {
+			at := utf8.RuneCountInString(text[:i])
+			start = max(0, at-context)
+			end = min(len(runes), at+utf8.RuneCountInString(w)+context)
+			break
+		}
+	}
+
+	snippet := string(runes[start:end])
+	for _, w := range words {
+		snippet = strings.ReplaceAll(snippet, w, snippetOpen+w+snippetClose)
+	}
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return markSnippet(snippet)
+}
+
+// SearchSynthesisTasks searches task text, notes, tags and uploaded input
+// files for every word of q, most relevant first, with a highlighted
+// snippet per task. The filters of ListSynthesisTasks apply as well.
+func SearchSynthesisTasks(c *gin.Context) {
+	words := strings.Fields(c.Query("q"))
+	if len(words) == 0 {
+		ErrorResponse(c, http.StatusBadRequest, 1, "q is required")
+		return
+	}
+	page, pageSize := pageParams(c)
+	pagination := &Pagination{Page: page, PageSize: pageSize, Sort: "rank", Order: "desc"}
+	tasks := filterSynthesisTasks(c, database.DB.Model(&model.SynthesisTask{})).Session(&gorm.Session{})
+
+	match, ok := ftsQuery(words)
+	if !ok {
+		// Without the index there is no ranking, so newest tasks come first
+		query := matchTaskText(tasks, c.Query("q"))
+		var found []model.SynthesisTask
+		query.Session(&gorm.Session{}).Count(&pagination.Total)
+		if err := query.Order("created_at desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&found).Error; err != nil {
+			ErrorResponse(c, http.StatusInternalServerError, 2, "Search failed")
+			return
+		}
+		results := make([]TaskSearchResult, len(found))
+		for i, task := range found {
+			results[i] = TaskSearchResult{SynthesisTask: task, Snippet: likeSnippet(task.Text, words)}
+		}
+		pagination.Sort = "created_at"
+		pagination.HasMore = int64(page*pageSize) < pagination.Total
+		PagedSuccessResponse(c, results, pagination)
+		return
+	}
+
+	hitQuery := func() *gorm.DB {
+		return database.DB.Table(database.TaskSearchTable).
+			Where(database.TaskSearchTable+" MATCH ?", match).
+			Where("rowid IN (?)", tasks.Select("id"))
+	}
+	if err := hitQuery().Count(&pagination.Total).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 2, "Search failed")
+		return
+	}
+
+	// Text matches weigh most, then notes and tags, then input files
+	var hits []struct {
+		ID      uint
+		Snippet string
+		BM25    float64 `gorm:"column:bm25"` // Lower is more relevant
+	}
+	err := hitQuery().
+		Select("rowid AS id, snippet("+database.TaskSearchTable+", -1, ?, ?, '…', 48) AS snippet, bm25("+database.TaskSearchTable+", 4.0, 2.0, 2.0, 1.0) AS bm25", snippetOpen, snippetClose).
+		Order("bm25").Offset((page - 1) * pageSize).Limit(pageSize).
+		Scan(&hits).Error
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 2, "Search failed")
+		return
+	}
+
+	ids := make([]uint, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ID
+	}
+	var found []model.SynthesisTask
+	database.DB.Where("id IN ?", ids).Find(&found)
+	byID := make(map[uint]model.SynthesisTask, len(found))
+	for _, task := range found {
+		byID[task.ID] = task
+	}
+
+	results := make([]TaskSearchResult, 0, len(hits))
+	for _, h := range hits {
+		if task, ok := byID[h.ID]; ok {
+			results = append(results, TaskSearchResult{SynthesisTask: task, Snippet: markSnippet(h.Snippet), Score: -h.BM25})
+		}
+	}
+	pagination.HasMore = int64(page*pageSize) < pagination.Total
+	PagedSuccessResponse(c, results, pagination)
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"minimax-voice-workbench/internal/database"
+	"minimax-voice-workbench/internal/model"
+	"minimax-voice-workbench/pkg/minimax"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Where uploaded async inputs are kept
+const textFileDir = "uploads/text_files"
+
+// Most text read from one uploaded input, to guard against zip bombs
+const maxInputTextBytes = 20 << 20
+
+// Characters of an input's text shown as the text of its task. The whole
+// text is searchable and served by GetSynthesisTaskInput.
+const inputPreviewLen = 500
+
+// extractInputText returns the text of an async input file. A .zip holds
+// one or more .txt files, which are joined in name order.
+func extractInputText(path string) (string, error) {
+	if strings.ToLower(filepath.Ext(path)) != ".zip" {
+		f, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		data, err := io.ReadAll(io.LimitReader(f, maxInputTextBytes))
+		return string(data), err
+	}
+
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return "", err
+	}
+	defer zr.Close()
+
+	var files []*zip.File
+	for _, f := range zr.File {
+		if !f.FileInfo().IsDir() && strings.ToLower(filepath.Ext(f.Name)) == ".txt" {
+			files = append(files, f)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	var parts []string
+	remaining := int64(maxInputTextBytes)
+	for _, f := range files {
+		if remaining <= 0 {
+			break
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return "", err
+		}
+		data, err := io.ReadAll(io.LimitReader(rc, remaining))
+		rc.Close()
+		if err != nil {
+			return "", err
+		}
+		remaining -= int64(len(data))
+		parts = append(parts, string(data))
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// inputPreview shortens the text of an input for display with its task
+func inputPreview(text string) string {
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) <= inputPreviewLen {
+		return text
+	}
+	return string([]rune(text)[:inputPreviewLen]) + "…"
+}
+
+// findTextInput returns the stored input with inputID, or else the one whose
+// Minimax copy is fileID. Files uploaded before inputs were stored have none.
+func findTextInput(inputID uint, fileID int64) *model.TextFile {
+	var input model.TextFile
+	var err error
+	switch {
+	case inputID > 0:
+		err = database.DB.First(&input, inputID).Error
+	case fileID > 0:
+		err = database.DB.Where("file_id = ?", fileID).Order("id desc").First(&input).Error
+	default:
+		return nil
+	}
+	if err != nil {
+		return nil
+	}
+	return &input
+}
+
+// withTextInput runs fn with req pointing at a Minimax copy of input. The
+// copy only exists for the account that uploaded it and expires after a
+// while, so the kept original is uploaded again when the copy is gone or
+// another key is used. Without keyID, the key of the last upload is used.
+func withTextInput(input *model.TextFile, keyID uint, req *minimax.T2ARequest, fn func(client *minimax.Client) error) (*model.ApiKey, error) {
+	chosen := keyID > 0
+	if !chosen {
+		keyID = input.KeyID
+	}
+	key, err := getEffectiveKey(keyID)
+	if err != nil && !chosen {
+		// The key of the last upload is gone; any key can upload again
+		key, err = getEffectiveKey(0)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return withKey(key.ID, func(client *minimax.Client) error {
+		if key.ID != input.KeyID || !textFileAvailable(client, input.FileID) {
+			if _, err := os.Stat(input.Path); err != nil {
+				return errors.New("the uploaded file has expired and its local copy is missing")
+			}
+			resp, err := client.UploadFile(input.Path, "t2a_async_input")
+			if err != nil {
+				return err
+			}
+			input.FileID = resp.File.FileID
+			input.KeyID = key.ID
+			database.DB.Model(input).Updates(map[string]interface{}{"file_id": input.FileID, "key_id": input.KeyID})
+		}
+		req.TextFileID = input.FileID
+		return fn(client)
+	})
+}
+
+// textFileAvailable reports whether Minimax still has the file
+func textFileAvailable(client *minimax.Client, fileID int64) bool {
+	if fileID == 0 {
+		return false
+	}
+	_, err := client.RetrieveFile(fileID)
+	return err == nil
+}
+
+// GetSynthesisTaskInput returns the uploaded input of a file-based task with
+// its full text. With download=true the original file is sent instead.
+func GetSynthesisTaskInput(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var task model.SynthesisTask
+	if err := database.DB.First(&task, id).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Task not found")
+		return
+	}
+
+	var input model.TextFile
+	if task.TextInputID == 0 || database.DB.First(&input, task.TextInputID).Error != nil {
+		ErrorResponse(c, http.StatusNotFound, 2, "Task has no stored input")
+		return
+	}
+
+	if c.Query("download") == "true" {
+		if _, err := os.Stat(input.Path); err != nil {
+			ErrorResponse(c, http.StatusNotFound, 3, "Input file is missing")
+			return
+		}
+		c.FileAttachment(input.Path, input.Filename)
+		return
+	}
+	SuccessResponse(c, input)
+}
@@ -0,0 +1,711 @@
+package api
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"minimax-voice-workbench/internal/database"
+	"minimax-voice-workbench/internal/model"
+	"minimax-voice-workbench/pkg/minimax"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	voiceBundleVersion     = 1
+	voiceBundleManifest    = "manifest.json"
+	voiceBundleMaxSize     = 200 << 20 // 200MB
+	voiceBundleMaxFileSize = 20 << 20  // Per preview file
+)
+
+// VoiceBundle is the manifest of an exported voice bundle. Preview files
+// are stored next to it in the zip under previews/.
+type VoiceBundle struct {
+	Version      int            `json:"version"`
+	ExportedAt   time.Time      `json:"exported_at"`
+	Voices       []BundleVoice  `json:"voices"`
+	Presets      []BundlePreset `json:"presets,omitempty"`
+	Dictionaries []BundleDict   `json:"dictionaries,omitempty"`
+}
+
+// BundleVoice is the portable part of a voice. Keys are not exported; the
+// importing instance binds custom voices to its own key.
+type BundleVoice struct {
+	VoiceID           string           `json:"voice_id"`
+	Name              string           `json:"name"`
+	Type              string           `json:"type"`
+	Description       string           `json:"description,omitempty"`
+	RemoteCreatedTime string           `json:"remote_created_time,omitempty"`
+	Language          string           `json:"language,omitempty"`
+	Gender            string           `json:"gender,omitempty"`
+	Age               string           `json:"age,omitempty"`
+	Tags              model.StringList `json:"tags"`
+	Notes             string           `json:"notes,omitempty"`
+	Favorite          bool             `json:"favorite"`
+	FirstUsedAt       *time.Time       `json:"first_used_at,omitempty"`
+	Preview           string           `json:"preview,omitempty"` // Bundle path of the default preview
+	Previews          []BundlePreview  `json:"previews"`
+}
+
+// BundlePreview is one stored preview of a voice
+type BundlePreview struct {
+	Source  string `json:"source"`
+	Text    string `json:"text,omitempty"`
+	Model   string `json:"model,omitempty"`
+	Emotion string `json:"emotion,omitempty"`
+	File    string `json:"file"` // Path inside the bundle
+}
+
+// BundlePreset is a global preset bound to one of the bundle voices
+type BundlePreset struct {
+	VoiceID  string                  `json:"voice_id"`
+	Name     string                  `json:"name"`
+	Settings model.SynthesisSettings `json:"settings"`
+}
+
+// BundleDict is a global pronunciation dictionary bound to one of the
+// bundle voices
+type BundleDict struct {
+	VoiceID string   `json:"voice_id"`
+	Name    string   `json:"name"`
+	Entries []string `json:"entries"`
+	Enabled bool     `json:"enabled"`
+}
+
+// ExportVoicesRequest selects the voices of a bundle
+type ExportVoicesRequest struct {
+	VoiceIDs []string `json:"voice_ids" binding:"required"`
+}
+
+// ImportConflict is a field whose local value differs from the bundle
+type ImportConflict struct {
+	VoiceID string `json:"voice_id"`
+	Field   string `json:"field"`
+	Local   string `json:"local"`
+	Bundle  string `json:"bundle"`
+}
+
+// ImportSkip is a bundle voice that was not imported
+type ImportSkip struct {
+	VoiceID string `json:"voice_id"`
+	Reason  string `json:"reason"`
+}
+
+// VoiceImportResult reports what an import did, or would do on a dry run
+type VoiceImportResult struct {
+	DryRun    bool             `json:"dry_run"`
+	Verified  bool             `json:"verified"`
+	Overwrite bool             `json:"overwrite"`
+	Created   []string         `json:"created"`
+	Updated   []string         `json:"updated"`
+	Unchanged []string         `json:"unchanged"`
+	Skipped   []ImportSkip     `json:"skipped"`
+	Conflicts []ImportConflict `json:"conflicts"`
+	Previews  int              `json:"previews"` // Preview files added
+
+	Presets      int `json:"presets"`      // Presets added or updated
+	Dictionaries int `json:"dictionaries"` // Dictionaries added or updated
+}
+
+// ExportVoices writes the selected voices with their previews and
+// favorite flags as a zip bundle, along with the global presets and
+// pronunciation dictionaries bound to them. Those of a project go with
+// the project export instead.
+func ExportVoices(c *gin.Context) {
+	var req ExportVoicesRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.VoiceIDs) == 0 {
+		ErrorResponse(c, http.StatusBadRequest, 1, "voice_ids is required")
+		return
+	}
+
+	var voices []model.Voice
+	if err := database.DB.Where("voice_id IN ?", req.VoiceIDs).Order("id asc").Find(&voices).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 2, "Failed to fetch voices")
+		return
+	}
+	if len(voices) == 0 {
+		ErrorResponse(c, http.StatusNotFound, 3, "No matching voices")
+		return
+	}
+
+	bundle := VoiceBundle{Version: voiceBundleVersion, ExportedAt: time.Now()}
+	files := map[string]string{} // Bundle path -> local path
+	var voiceIDs []string
+	for _, voice := range voices {
+		entry := BundleVoice{
+			VoiceID:           voice.VoiceID,
+			Name:              voice.Name,
+			Type:              voice.Type,
+			Description:       voice.Description,
+			RemoteCreatedTime: voice.RemoteCreatedTime,
+			Language:          voice.Language,
+			Gender:            voice.Gender,
+			Age:               voice.Age,
+			Tags:              voice.Tags,
+			Notes:             voice.Notes,
+			Favorite:          voice.IsFavorite,
+			FirstUsedAt:       voice.FirstUsedAt,
+			Previews:          []BundlePreview{},
+		}
+
+		var previews []model.VoicePreview
+		database.DB.Where("voice_id = ?", voice.VoiceID).Order("created_at asc").Find(&previews)
+		for _, p := range previews {
+			local := generatedFilePath(p.File)
+			if _, err := os.Stat(local); err != nil {
+				continue
+			}
+			bundlePath := fmt.Sprintf("previews/%d%s", len(files)+1, filepath.Ext(local))
+			files[bundlePath] = local
+			entry.Previews = append(entry.Previews, BundlePreview{
+				Source:  p.Source,
+				Text:    p.Text,
+				Model:   p.Model,
+				Emotion: p.Emotion,
+				File:    bundlePath,
+			})
+			if p.File == voice.Preview {
+				entry.Preview = bundlePath
+			}
+		}
+		bundle.Voices = append(bundle.Voices, entry)
+		voiceIDs = append(voiceIDs, voice.VoiceID)
+	}
+
+	var presets []model.Preset
+	database.DB.Where("project_id = 0 AND voice_id IN ?", voiceIDs).Order("id asc").Find(&presets)
+	for _, p := range presets {
+		bundle.Presets = append(bundle.Presets, BundlePreset{VoiceID: p.VoiceID, Name: p.Name, Settings: p.Settings})
+	}
+	var dicts []model.PronunciationDict
+	database.DB.Where("project_id = 0 AND voice_id IN ?", voiceIDs).Order("id asc").Find(&dicts)
+	for _, d := range dicts {
+		bundle.Dictionaries = append(bundle.Dictionaries, BundleDict{VoiceID: d.VoiceID, Name: d.Name, Entries: d.Entries, Enabled: d.Enabled})
+	}
+
+	filename := fmt.Sprintf("voices_%s.zip", time.Now().Format("20060102_150405"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+
+	zw := zip.NewWriter(c.Writer)
+	defer zw.Close()
+
+	w, err := zw.Create(voiceBundleManifest)
+	if err != nil {
+		return
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(bundle); err != nil {
+		return
+	}
+
+	for bundlePath, local := range files {
+		src, err := os.Open(local)
+		if err != nil {
+			continue
+		}
+		if w, err := zw.Create(bundlePath); err == nil {
+			io.Copy(w, src)
+		}
+		src.Close()
+	}
+}
+
+// readBundleFile returns the contents of one file in the bundle
+func readBundleFile(zr *zip.Reader, name string) ([]byte, error) {
+	f, err := zr.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, voiceBundleMaxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > voiceBundleMaxFileSize {
+		return nil, fmt.Errorf("%s is too large", name)
+	}
+	return data, nil
+}
+
+// remoteVoiceIDs lists the voices the account of a key can use, keyed by
+// voice ID with the voice type as value
+func remoteVoiceIDs(keyID uint) (map[string]string, *model.ApiKey, error) {
+	var resp *minimax.GetVoicesResponse
+	apiKey, err := withKey(keyID, func(client *minimax.Client) error {
+		var err error
+		resp, err = client.GetVoices("all")
+		return err
+	})
+	if err != nil {
+		return nil, apiKey, err
+	}
+
+	ids := map[string]string{}
+	for _, v := range resp.SystemVoices {
+		ids[v.VoiceID] = "system"
+	}
+	for _, v := range resp.VoiceCloning {
+		ids[v.VoiceID] = "cloned"
+	}
+	for _, v := range resp.VoiceGeneration {
+		ids[v.VoiceID] = "generated"
+	}
+	return ids, apiKey, nil
+}
+
+// fileHash returns the sha256 of a file's contents, or "" if unreadable
+func fileHash(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// mergeBundleVoice compares a bundle voice with the local one and returns
+// the updates to apply. Empty local fields are filled in; differing ones
+// are conflicts, resolved in favor of the bundle only when overwrite is set.
+// Tags are merged.
+func mergeBundleVoice(local *model.Voice, bv *BundleVoice, overwrite bool, result *VoiceImportResult) map[string]interface{} {
+	updates := map[string]interface{}{}
+	fields := []struct {
+		column string
+		local  string
+		bundle string
+	}{
+		{"name", local.Name, bv.Name},
+		{"description", local.Description, bv.Description},
+		{"language", local.Language, bv.Language},
+		{"gender", local.Gender, bv.Gender},
+		{"age", local.Age, bv.Age},
+		{"notes", local.Notes, bv.Notes},
+	}
+	for _, f := range fields {
+		switch {
+		case f.bundle == "" || f.bundle == f.local:
+		case f.local == "":
+			updates[f.column] = f.bundle
+		default:
+			result.Conflicts = append(result.Conflicts, ImportConflict{
+				VoiceID: local.VoiceID,
+				Field:   f.column,
+				Local:   f.local,
+				Bundle:  f.bundle,
+			})
+			if overwrite {
+				updates[f.column] = f.bundle
+			}
+		}
+	}
+	if local.Type != bv.Type {
+		result.Conflicts = append(result.Conflicts, ImportConflict{
+			VoiceID: local.VoiceID,
+			Field:   "type",
+			Local:   local.Type,
+			Bundle:  bv.Type,
+		})
+	}
+
+	tags := slices.Clone(local.Tags)
+	for _, tag := range bv.Tags {
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) != len(local.Tags) {
+		updates["tags"] = model.StringList(tags)
+	}
+	if bv.Favorite && !local.IsFavorite {
+		updates["is_favorite"] = true
+	}
+	return updates
+}
+
+// Audio formats accepted as bundle previews
+var bundlePreviewExts = map[string]bool{".mp3": true, ".wav": true, ".m4a": true}
+
+// validBundleVoiceID accepts the characters of Minimax voice IDs, system
+// ones included such as "Chinese (Mandarin)_Lyrical_Voice", so that a
+// bundle cannot smuggle path separators into file names
+func validBundleVoiceID(voiceID string) bool {
+	if voiceID == "" || len(voiceID) > voiceIDMaxLength || strings.Contains(voiceID, "..") {
+		return false
+	}
+	for _, r := range voiceID {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+			r == '-' || r == '_' || r == ' ' || r == '(' || r == ')') {
+			return false
+		}
+	}
+	return true
+}
+
+// safeFilenamePart keeps letters, digits, '-' and '_' of s for use in a
+// file name
+func safeFilenamePart(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// importBundlePreviews copies the bundle previews of a voice that are not
+// stored locally yet, and returns how many were added
+func importBundlePreviews(zr *zip.Reader, voice *model.Voice, bv *BundleVoice, keyID uint) int {
+	var existing []model.VoicePreview
+	database.DB.Where("voice_id = ?", voice.VoiceID).Find(&existing)
+	hashes := map[string]bool{}
+	for _, p := range existing {
+		if h := fileHash(generatedFilePath(p.File)); h != "" {
+			hashes[h] = true
+		}
+	}
+	_, statErr := os.Stat(generatedFilePath(voice.Preview))
+	hasPreview := voice.Preview != "" && statErr == nil
+
+	added := 0
+	outputDir := "generated/voices"
+	os.MkdirAll(outputDir, 0755)
+	for _, bp := range bv.Previews {
+		data, err := readBundleFile(zr, bp.File)
+		if err != nil {
+			continue
+		}
+		sum := sha256.Sum256(data)
+		if hashes[hex.EncodeToString(sum[:])] {
+			continue
+		}
+		hashes[hex.EncodeToString(sum[:])] = true
+
+		ext := strings.ToLower(path.Ext(bp.File))
+		if ext == "" {
+			ext = ".mp3"
+		}
+		if !bundlePreviewExts[ext] {
+			continue
+		}
+		filename := fmt.Sprintf("preview_%s_%d%s", safeFilenamePart(voice.VoiceID), time.Now().UnixNano(), ext)
+		target := filepath.Join(outputDir, filename)
+		if rel, err := filepath.Rel(outputDir, target); err != nil || rel != filename {
+			continue
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			continue
+		}
+
+		preview := &model.VoicePreview{
+			VoiceID: voice.VoiceID,
+			Source:  bp.Source,
+			Text:    bp.Text,
+			Model:   bp.Model,
+			Emotion: bp.Emotion,
+			File:    "/files/voices/" + filename,
+			KeyID:   keyID,
+		}
+		if !hasPreview && (bp.File == bv.Preview || bv.Preview == "") {
+			recordVoicePreview(voice, preview)
+			hasPreview = true
+		} else {
+			database.DB.Create(preview)
+		}
+		added++
+	}
+	return added
+}
+
+// importBundlePreset merges a bundle preset into the global presets of its
+// voice by name. Differing settings are a conflict, resolved in favor of
+// the bundle only when overwrite is set.
+func importBundlePreset(bp *BundlePreset, result *VoiceImportResult) {
+	name := strings.TrimSpace(bp.Name)
+	if name == "" {
+		return
+	}
+
+	var local model.Preset
+	if database.DB.Where("project_id = 0 AND voice_id = ? AND name = ?", bp.VoiceID, name).First(&local).Error != nil {
+		result.Presets++
+		if !result.DryRun {
+			database.DB.Create(&model.Preset{Name: name, VoiceID: bp.VoiceID, Settings: bp.Settings})
+		}
+		return
+	}
+	if local.Settings == bp.Settings {
+		return
+	}
+	localSettings, _ := json.Marshal(local.Settings)
+	bundleSettings, _ := json.Marshal(bp.Settings)
+	result.Conflicts = append(result.Conflicts, ImportConflict{
+		VoiceID: bp.VoiceID,
+		Field:   "preset " + name,
+		Local:   string(localSettings),
+		Bundle:  string(bundleSettings),
+	})
+	if result.Overwrite {
+		result.Presets++
+		if !result.DryRun {
+			database.DB.Model(&local).Update("settings", bp.Settings)
+		}
+	}
+}
+
+// importBundleDict merges a bundle dictionary into the global dictionary
+// of its voice by name. Entries for new texts are added; a differing
+// reading is a conflict, resolved in favor of the bundle only when
+// overwrite is set.
+func importBundleDict(bd *BundleDict, result *VoiceImportResult) {
+	name := strings.TrimSpace(bd.Name)
+	entries, err := dictEntries(bd.Entries)
+	if name == "" || err != nil {
+		result.Skipped = append(result.Skipped, ImportSkip{VoiceID: bd.VoiceID, Reason: "invalid dictionary " + name})
+		return
+	}
+
+	var local model.PronunciationDict
+	if database.DB.Where("project_id = 0 AND voice_id = ? AND name = ?", bd.VoiceID, name).First(&local).Error != nil {
+		result.Dictionaries++
+		if !result.DryRun {
+			database.DB.Create(&model.PronunciationDict{VoiceID: bd.VoiceID, Name: name, Entries: entries, Enabled: bd.Enabled})
+		}
+		return
+	}
+
+	merged := slices.Clone(local.Entries)
+	index := map[string]int{} // Text -> position in merged
+	for i, e := range merged {
+		index[toneText(e)] = i
+	}
+	changed := false
+	for _, e := range entries {
+		text := toneText(e)
+		i, ok := index[text]
+		switch {
+		case !ok:
+			index[text] = len(merged)
+			merged = append(merged, e)
+			changed = true
+		case merged[i] != e:
+			result.Conflicts = append(result.Conflicts, ImportConflict{
+				VoiceID: bd.VoiceID,
+				Field:   "dictionary " + name + ": " + text,
+				Local:   merged[i],
+				Bundle:  e,
+			})
+			if result.Overwrite {
+				merged[i] = e
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return
+	}
+	result.Dictionaries++
+	if !result.DryRun {
+		database.DB.Model(&local).Update("entries", model.StringList(merged))
+	}
+}
+
+// ImportVoices merges a voice bundle into the library on voice_id.
+// conflict=overwrite lets bundle values win over differing local ones,
+// verify=true only imports voices the key's account can actually use, and
+// dry_run=true reports the outcome without changing anything. Presets and
+// dictionaries are merged on voice and name, for the voices imported.
+func ImportVoices(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 1, "Bundle file is required")
+		return
+	}
+	if fileHeader.Size > voiceBundleMaxSize {
+		ErrorResponse(c, http.StatusBadRequest, 2, "Bundle is too large")
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 3, "Failed to read bundle")
+		return
+	}
+	defer file.Close()
+
+	zr, err := zip.NewReader(file, fileHeader.Size)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 4, "Bundle is not a zip file")
+		return
+	}
+	manifest, err := readBundleFile(zr, voiceBundleManifest)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 4, "Bundle has no manifest")
+		return
+	}
+	var bundle VoiceBundle
+	if err := json.Unmarshal(manifest, &bundle); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 4, "Invalid bundle manifest")
+		return
+	}
+	if bundle.Version > voiceBundleVersion {
+		ErrorResponse(c, http.StatusBadRequest, 5, "Bundle was made by a newer version")
+		return
+	}
+
+	keyID, _ := strconv.Atoi(c.PostForm("key_id"))
+	result := VoiceImportResult{
+		DryRun:    c.PostForm("dry_run") == "true",
+		Verified:  c.PostForm("verify") == "true",
+		Overwrite: c.PostForm("conflict") == "overwrite",
+		Created:   []string{},
+		Updated:   []string{},
+		Unchanged: []string{},
+		Skipped:   []ImportSkip{},
+		Conflicts: []ImportConflict{},
+	}
+
+	// Custom voices are bound to the verifying key, or to key_id as given
+	bindKey := uint(keyID)
+	var remote map[string]string
+	if result.Verified {
+		var apiKey *model.ApiKey
+		remote, apiKey, err = remoteVoiceIDs(uint(keyID))
+		if apiKey == nil {
+			ErrorResponse(c, http.StatusBadRequest, 6, "Invalid API Key or No Default Key")
+			return
+		}
+		if err != nil {
+			ErrorResponse(c, http.StatusBadGateway, 7, "Failed to verify voices: "+err.Error())
+			return
+		}
+		bindKey = apiKey.ID
+	}
+
+	for i := range bundle.Voices {
+		bv := &bundle.Voices[i]
+		if !validBundleVoiceID(bv.VoiceID) {
+			result.Skipped = append(result.Skipped, ImportSkip{VoiceID: bv.VoiceID, Reason: "invalid voice_id"})
+			continue
+		}
+		if bv.Type != "system" && bv.Type != "cloned" && bv.Type != "generated" {
+			result.Skipped = append(result.Skipped, ImportSkip{VoiceID: bv.VoiceID, Reason: "unknown type " + bv.Type})
+			continue
+		}
+		if result.Verified {
+			if _, ok := remote[bv.VoiceID]; !ok {
+				result.Skipped = append(result.Skipped, ImportSkip{VoiceID: bv.VoiceID, Reason: "not found on the account"})
+				continue
+			}
+		}
+		voiceKey := uint(0)
+		if bv.Type != "system" {
+			voiceKey = bindKey
+		}
+
+		var local model.Voice
+		found := database.DB.Unscoped().Where("voice_id = ?", bv.VoiceID).First(&local).Error == nil
+
+		if found && !local.DeletedAt.Valid {
+			updates := mergeBundleVoice(&local, bv, result.Overwrite, &result)
+			if result.Verified && voiceKey != 0 && local.KeyID != voiceKey {
+				updates["key_id"] = voiceKey
+			}
+			if len(updates) == 0 && len(bv.Previews) == 0 {
+				result.Unchanged = append(result.Unchanged, bv.VoiceID)
+				continue
+			}
+			if result.DryRun {
+				if len(updates) > 0 {
+					result.Updated = append(result.Updated, bv.VoiceID)
+				} else {
+					result.Unchanged = append(result.Unchanged, bv.VoiceID)
+				}
+				continue
+			}
+			if len(updates) > 0 {
+				database.DB.Model(&local).Updates(updates)
+			}
+			added := importBundlePreviews(zr, &local, bv, voiceKey)
+			result.Previews += added
+			if len(updates) > 0 || added > 0 {
+				result.Updated = append(result.Updated, bv.VoiceID)
+			} else {
+				result.Unchanged = append(result.Unchanged, bv.VoiceID)
+			}
+			continue
+		}
+
+		// New voice, or one deleted locally that the bundle restores
+		result.Created = append(result.Created, bv.VoiceID)
+		if result.DryRun {
+			continue
+		}
+		name := bv.Name
+		if name == "" {
+			name = bv.VoiceID
+		}
+		voice := model.Voice{
+			Name:              name,
+			VoiceID:           bv.VoiceID,
+			Type:              bv.Type,
+			KeyID:             voiceKey,
+			Description:       bv.Description,
+			RemoteCreatedTime: bv.RemoteCreatedTime,
+			Language:          bv.Language,
+			Gender:            bv.Gender,
+			Age:               bv.Age,
+			Tags:              bv.Tags,
+			Notes:             bv.Notes,
+			IsFavorite:        bv.Favorite,
+			FirstUsedAt:       bv.FirstUsedAt,
+		}
+		if voice.Tags == nil {
+			voice.Tags = model.StringList{}
+		}
+		voice.ComputeExpiry()
+		if found {
+			voice.ID = local.ID
+			voice.CreatedAt = local.CreatedAt
+			err = database.DB.Unscoped().Select("*").Omit("created_at").Save(&voice).Error
+		} else {
+			err = database.DB.Create(&voice).Error
+		}
+		if err != nil {
+			result.Created = result.Created[:len(result.Created)-1]
+			result.Skipped = append(result.Skipped, ImportSkip{VoiceID: bv.VoiceID, Reason: "failed to save"})
+			continue
+		}
+		result.Previews += importBundlePreviews(zr, &voice, bv, voiceKey)
+	}
+
+	imported := map[string]bool{}
+	for _, ids := range [][]string{result.Created, result.Updated, result.Unchanged} {
+		for _, id := range ids {
+			imported[id] = true
+		}
+	}
+	for i := range bundle.Presets {
+		if imported[bundle.Presets[i].VoiceID] {
+			importBundlePreset(&bundle.Presets[i], &result)
+		}
+	}
+	for i := range bundle.Dictionaries {
+		if imported[bundle.Dictionaries[i].VoiceID] {
+			importBundleDict(&bundle.Dictionaries[i], &result)
+		}
+	}
+
+	SuccessResponse(c, result)
+}
@@ -0,0 +1,393 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"minimax-voice-workbench/internal/database"
+	"minimax-voice-workbench/internal/model"
+	"minimax-voice-workbench/pkg/minimax"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Most candidates synthesized in one comparison
+const maxCompareCandidates = 10
+
+// CompareCandidateRequest is one voice with optional settings of its own.
+// A preset fills in the voice and settings left empty.
+type CompareCandidateRequest struct {
+	VoiceID  string  `json:"voice_id"`
+	PresetID uint    `json:"preset_id"`
+	Label    string  `json:"label"`
+	Speed    float64 `json:"speed"` // Defaults to 1
+	Vol      float64 `json:"vol"`   // Defaults to 1
+	Pitch    int     `json:"pitch"`
+	Emotion  string  `json:"emotion"`
+}
+
+// CompareVoicesRequest synthesizes one text with every candidate. Plain
+// voice_ids, preset_ids and candidates with settings may be mixed.
+type CompareVoicesRequest struct {
+	Name       string                    `json:"name"`
+	Text       string                    `json:"text" binding:"required"`
+	Model      string                    `json:"model"` // Defaults to speech-2.6-hd
+	KeyID      uint                      `json:"key_id"`
+	VoiceIDs   []string                  `json:"voice_ids"`
+	PresetIDs  []uint                    `json:"preset_ids"`
+	Candidates []CompareCandidateRequest `json:"candidates"`
+	BatchLimit
+}
+
+// UpdateComparisonRequest changes the name or notes of a comparison
+type UpdateComparisonRequest struct {
+	Name  *string `json:"name"`
+	Notes *string `json:"notes"`
+}
+
+// RateCandidateRequest records the verdict on one candidate
+type RateCandidateRequest struct {
+	Rating *int    `json:"rating"` // 1-5, 0 clears
+	Notes  *string `json:"notes"`
+	Chosen *bool   `json:"chosen"` // Only one candidate can be chosen
+}
+
+// compareT2ARequest builds the synthesis request of a candidate
+func compareT2ARequest(text, modelName string, candidate *model.VoiceComparisonCandidate) *minimax.T2ARequest {
+	return &minimax.T2ARequest{
+		Model:         modelName,
+		Text:          text,
+		LanguageBoost: candidate.LanguageBoost,
+		VoiceSetting: minimax.VoiceSetting{
+			VoiceID: candidate.VoiceID,
+			Speed:   candidate.Speed,
+			Vol:     candidate.Vol,
+			Pitch:   candidate.Pitch,
+			Emotion: candidate.Emotion,
+		},
+		AudioSetting: minimax.AudioSetting{
+			AudioSampleRate: 32000,
+			Bitrate:         128000,
+			Format:          "mp3",
+			Channel:         1,
+		},
+	}
+}
+
+// compareCacheKey identifies a synthesis request and the preset it came
+// from, so identical candidates share one audio file
+func compareCacheKey(req *minimax.T2ARequest, presetID uint) string {
+	payload, _ := json.Marshal(struct {
+		PresetID uint                `json:"preset_id,omitempty"`
+		Request  *minimax.T2ARequest `json:"request"`
+	}{presetID, req})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// synthesizeCandidate fills in the audio of a candidate, reusing the file
+// of an identical earlier synthesis when it still exists
+func synthesizeCandidate(text, modelName string, keyID uint, candidate *model.VoiceComparisonCandidate) {
+	req := compareT2ARequest(text, modelName, candidate)
+	candidate.CacheKey = compareCacheKey(req, candidate.PresetID)
+
+	outputDir := "generated/compare"
+	filename := candidate.CacheKey + ".mp3"
+	if _, err := os.Stat(filepath.Join(outputDir, filename)); err == nil {
+		candidate.Audio = "/files/compare/" + filename
+		candidate.Cached = true
+		candidate.Status = "success"
+		return
+	}
+
+	var resp *minimax.T2AResponse
+	apiKey, err := withVoiceKey(candidate.VoiceID, keyID, func(client *minimax.Client) error {
+		var err error
+		resp, err = client.T2A(req)
+		return err
+	})
+	if apiKey != nil {
+		candidate.KeyID = apiKey.ID
+	}
+	if err == nil && apiKey == nil {
+		err = fmt.Errorf("no API key available")
+	}
+	if err != nil {
+		candidate.Status = "failed"
+		candidate.Error = err.Error()
+		return
+	}
+
+	audioBytes, err := hex.DecodeString(resp.Data.Audio)
+	if err != nil {
+		candidate.Status = "failed"
+		candidate.Error = "Failed to decode audio"
+		return
+	}
+	os.MkdirAll(outputDir, 0755)
+	if err := os.WriteFile(filepath.Join(outputDir, filename), audioBytes, 0644); err != nil {
+		candidate.Status = "failed"
+		candidate.Error = "Failed to save file"
+		return
+	}
+	candidate.Audio = "/files/compare/" + filename
+	candidate.Status = "success"
+	markVoiceUsed(candidate.VoiceID)
+}
+
+// CompareVoices synthesizes the same text with every candidate and keeps
+// the results as a comparison set to rate
+func CompareVoices(c *gin.Context) {
+	var req CompareVoicesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 1, "text is required")
+		return
+	}
+
+	requested := req.Candidates
+	for _, voiceID := range req.VoiceIDs {
+		requested = append(requested, CompareCandidateRequest{VoiceID: voiceID})
+	}
+	for _, presetID := range req.PresetIDs {
+		requested = append(requested, CompareCandidateRequest{PresetID: presetID})
+	}
+	if len(requested) < 2 || len(requested) > maxCompareCandidates {
+		ErrorResponse(c, http.StatusBadRequest, 2, fmt.Sprintf("Between 2 and %d candidates are required", maxCompareCandidates))
+		return
+	}
+	if req.Model == "" {
+		req.Model = "speech-2.6-hd"
+	}
+
+	candidates := make([]model.VoiceComparisonCandidate, len(requested))
+	for i, r := range requested {
+		// The comparison keeps one model and audio format, so a preset
+		// only brings its voice, voice settings and language boost
+		t2aReq := minimax.T2ARequest{
+			Model: req.Model,
+			VoiceSetting: minimax.VoiceSetting{
+				VoiceID: r.VoiceID,
+				Speed:   r.Speed,
+				Vol:     r.Vol,
+				Pitch:   r.Pitch,
+				Emotion: r.Emotion,
+			},
+		}
+		if r.PresetID > 0 {
+			var preset model.Preset
+			if err := database.DB.First(&preset, r.PresetID).Error; err != nil {
+				ErrorResponse(c, http.StatusNotFound, 6, "Preset not found")
+				return
+			}
+			applyProjectDefaults(presetDefaults(&preset), &t2aReq)
+		}
+		v := t2aReq.VoiceSetting
+		if v.VoiceID == "" {
+			ErrorResponse(c, http.StatusBadRequest, 3, "Every candidate needs a voice_id")
+			return
+		}
+		if v.Speed == 0 {
+			v.Speed = 1
+		}
+		if v.Vol == 0 {
+			v.Vol = 1
+		}
+		candidates[i] = model.VoiceComparisonCandidate{
+			VoiceID:       v.VoiceID,
+			PresetID:      r.PresetID,
+			Label:         r.Label,
+			Speed:         v.Speed,
+			Vol:           v.Vol,
+			Pitch:         v.Pitch,
+			Emotion:       v.Emotion,
+			LanguageBoost: t2aReq.LanguageBoost,
+		}
+	}
+
+	// Candidates whose audio is cached cost nothing
+	e := newSpeechEstimate()
+	for i := range candidates {
+		t2aReq := compareT2ARequest(req.Text, req.Model, &candidates[i])
+		if _, err := os.Stat(filepath.Join("generated/compare", compareCacheKey(t2aReq, candidates[i].PresetID)+".mp3")); err != nil {
+			e.add(t2aReq)
+		}
+	}
+	if !req.check(c, e, true, 5) {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := range candidates {
+		wg.Add(1)
+		go func(candidate *model.VoiceComparisonCandidate) {
+			defer wg.Done()
+			synthesizeCandidate(req.Text, req.Model, req.KeyID, candidate)
+		}(&candidates[i])
+	}
+	wg.Wait()
+
+	comparison := model.VoiceComparison{
+		Name:       req.Name,
+		Text:       req.Text,
+		Model:      req.Model,
+		Candidates: candidates,
+	}
+	if err := database.DB.Create(&comparison).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 4, "Failed to save comparison")
+		return
+	}
+	SuccessResponse(c, comparison)
+}
+
+// loadComparison loads the comparison of the :id parameter with its
+// candidates in request order
+func loadComparison(c *gin.Context) (*model.VoiceComparison, error) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var comparison model.VoiceComparison
+	err := database.DB.Preload("Candidates", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id asc")
+	}).First(&comparison, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &comparison, nil
+}
+
+// ListComparisons returns comparisons with their candidates, newest first
+func ListComparisons(c *gin.Context) {
+	query := database.DB.Preload("Candidates", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id asc")
+	})
+	if voiceID := c.Query("voice_id"); voiceID != "" {
+		query = query.Where("id IN (?)", database.DB.Model(&model.VoiceComparisonCandidate{}).
+			Select("comparison_id").Where("voice_id = ?", voiceID))
+	}
+	comparisons, page, err := findPage[model.VoiceComparison](c, query, newestFirst)
+	if err != nil {
+		listErrorResponse(c, err, 1, "Failed to fetch comparisons")
+		return
+	}
+	PagedSuccessResponse(c, comparisons, page)
+}
+
+// GetComparison returns one comparison with its candidates
+func GetComparison(c *gin.Context) {
+	comparison, err := loadComparison(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Comparison not found")
+		return
+	}
+	SuccessResponse(c, comparison)
+}
+
+// UpdateComparison renames a comparison or changes its notes
+func UpdateComparison(c *gin.Context) {
+	comparison, err := loadComparison(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Comparison not found")
+		return
+	}
+
+	var req UpdateComparisonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 2, "Invalid request")
+		return
+	}
+	if req.Name != nil {
+		comparison.Name = *req.Name
+	}
+	if req.Notes != nil {
+		comparison.Notes = *req.Notes
+	}
+	if err := database.DB.Omit("Candidates").Save(comparison).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 3, "Failed to update comparison")
+		return
+	}
+	SuccessResponse(c, comparison)
+}
+
+// RateComparisonCandidate stores the rating, notes or choice of a candidate
+func RateComparisonCandidate(c *gin.Context) {
+	var candidate model.VoiceComparisonCandidate
+	err := database.DB.Where("id = ? AND comparison_id = ?", c.Param("candidate_id"), c.Param("id")).
+		First(&candidate).Error
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Candidate not found")
+		return
+	}
+
+	var req RateCandidateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 2, "Invalid request")
+		return
+	}
+	if req.Rating != nil {
+		if *req.Rating < 0 || *req.Rating > 5 {
+			ErrorResponse(c, http.StatusBadRequest, 3, "rating must be between 0 and 5")
+			return
+		}
+		candidate.Rating = *req.Rating
+	}
+	if req.Notes != nil {
+		candidate.Notes = *req.Notes
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if req.Chosen != nil {
+			if *req.Chosen {
+				if err := tx.Model(&model.VoiceComparisonCandidate{}).
+					Where("comparison_id = ? AND id <> ?", candidate.ComparisonID, candidate.ID).
+					Update("chosen", false).Error; err != nil {
+					return err
+				}
+			}
+			candidate.Chosen = *req.Chosen
+		}
+		return tx.Save(&candidate).Error
+	})
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 4, "Failed to update candidate")
+		return
+	}
+	SuccessResponse(c, candidate)
+}
+
+// DeleteComparison removes a comparison. Audio files are kept while
+// another comparison still uses them.
+func DeleteComparison(c *gin.Context) {
+	comparison, err := loadComparison(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Comparison not found")
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("comparison_id = ?", comparison.ID).Delete(&model.VoiceComparisonCandidate{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.VoiceComparison{}, comparison.ID).Error
+	})
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 2, "Failed to delete comparison")
+		return
+	}
+
+	for _, candidate := range comparison.Candidates {
+		if candidate.Audio == "" {
+			continue
+		}
+		var users int64
+		database.DB.Model(&model.VoiceComparisonCandidate{}).Where("audio = ?", candidate.Audio).Count(&users)
+		if users == 0 {
+			os.Remove(generatedFilePath(candidate.Audio))
+		}
+	}
+	SuccessResponse(c, nil)
+}
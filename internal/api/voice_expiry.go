@@ -0,0 +1,116 @@
+package api
+
+import (
+	"minimax-voice-workbench/internal/database"
+	"minimax-voice-workbench/internal/model"
+	"minimax-voice-workbench/pkg/minimax"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// markVoiceUsed records the first real synthesis with a voice, which makes
+// a temporary voice permanent on Minimax
+func markVoiceUsed(voiceID string) {
+	now := time.Now()
+	database.DB.Model(&model.Voice{}).
+		Where("voice_id = ? AND first_used_at IS NULL", voiceID).
+		Updates(map[string]interface{}{
+			"first_used_at": now,
+			"expires_at":    nil,
+		})
+}
+
+// ListExpiringVoices returns temporary voices that were never used,
+// soonest deadline first. within (e.g. 48h) limits the list to voices
+// expiring before then; already expired voices are included.
+func ListExpiringVoices(c *gin.Context) {
+	within := model.VoiceRetention
+	if w := c.Query("within"); w != "" {
+		d, err := time.ParseDuration(w)
+		if err != nil {
+			ErrorResponse(c, http.StatusBadRequest, 1, "Invalid within duration")
+			return
+		}
+		within = d
+	}
+
+	query := database.DB.Model(&model.Voice{}).Where("expires_at IS NOT NULL AND expires_at < ?", time.Now().Add(within))
+	voices, page, err := findPage[model.Voice](c, query, listSort{
+		Fields:  map[string]string{"expires_at": "expires_at", "name": "name", "created_at": "created_at"},
+		Default: "expires_at",
+		Order:   "asc",
+	})
+	if err != nil {
+		listErrorResponse(c, err, 2, "Failed to fetch voices")
+		return
+	}
+	PagedSuccessResponse(c, voices, page)
+}
+
+// ActivateVoiceRequest optionally overrides the key used for activation
+type ActivateVoiceRequest struct {
+	KeyID uint `json:"key_id"`
+}
+
+// ActivateVoice runs a minimal synthesis with a temporary voice so that
+// Minimax keeps it. The audio is discarded.
+func ActivateVoice(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	var req ActivateVoiceRequest
+	if err := bindOptionalJSON(c, &req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 5, "Invalid request body")
+		return
+	}
+
+	var voice model.Voice
+	if err := database.DB.First(&voice, id).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Voice not found")
+		return
+	}
+
+	if !voice.IsTemporary() || voice.FirstUsedAt != nil {
+		SuccessResponse(c, voice)
+		return
+	}
+
+	if voice.ExpiresAt != nil && voice.ExpiresAt.Before(time.Now()) {
+		ErrorResponse(c, http.StatusGone, 2, "Voice has already expired")
+		return
+	}
+
+	t2aReq := &minimax.T2ARequest{
+		Model: "speech-2.6-turbo",
+		Text:  "你好。",
+		VoiceSetting: minimax.VoiceSetting{
+			VoiceID: voice.VoiceID,
+			Speed:   1,
+			Vol:     1,
+		},
+		AudioSetting: minimax.AudioSetting{
+			AudioSampleRate: 16000,
+			Format:          "mp3",
+			Channel:         1,
+		},
+	}
+
+	apiKey, err := withVoiceKey(voice.VoiceID, req.KeyID, func(client *minimax.Client) error {
+		_, err := client.T2A(t2aReq)
+		return err
+	})
+	if apiKey == nil {
+		ErrorResponse(c, http.StatusBadRequest, 3, "Invalid API Key")
+		return
+	}
+	if err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 4, "Activation Failed: "+err.Error())
+		return
+	}
+
+	markVoiceUsed(voice.VoiceID)
+	database.DB.First(&voice, id)
+	SuccessResponse(c, voice)
+}
@@ -11,93 +11,192 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
-	"time"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-// ListVoices returns combined list of voices (DB)
+// Sortable columns of ListVoices
+var voiceSortFields = map[string]string{
+	"id":         "id",
+	"name":       "name",
+	"voice_id":   "voice_id",
+	"type":       "type",
+	"language":   "language",
+	"gender":     "gender",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// ListVoices returns combined list of voices (DB).
+// Supports filtering (type, key_id, language, gender, age, tag, favorite),
+// search over name, ID, description and notes (q), sorting (sort, order)
+// and pagination (page, page_size or cursor). With key_id, only voices
+// usable with that key are returned.
 func ListVoices(c *gin.Context) {
-	var voices []model.Voice
-	if err := database.DB.Find(&voices).Error; err != nil {
-		ErrorResponse(c, http.StatusInternalServerError, 1, "Failed to fetch voices")
+	query := database.DB.Model(&model.Voice{})
+	if keyID, _ := strconv.Atoi(c.Query("key_id")); keyID > 0 {
+		query = query.Where("type = ? OR key_id = 0 OR key_id = ?", "system", keyID)
+	}
+	if vType := c.Query("type"); vType != "" {
+		query = query.Where("type = ?", vType)
+	}
+	if language := c.Query("language"); language != "" {
+		query = query.Where("language = ?", language)
+	}
+	if gender := c.Query("gender"); gender != "" {
+		query = query.Where("gender = ?", gender)
+	}
+	if age := c.Query("age"); age != "" {
+		query = query.Where("age = ?", age)
+	}
+	if tag := c.Query("tag"); tag != "" {
+		query = whereTag(query, tag)
+	}
+	if c.Query("favorite") == "true" {
+		query = query.Where("is_favorite = ?", true)
+	}
+	if q := c.Query("q"); q != "" {
+		like := likeContains(q)
+		query = query.Where(`name LIKE ? ESCAPE '\' OR voice_id LIKE ? ESCAPE '\' OR description LIKE ? ESCAPE '\' OR notes LIKE ? ESCAPE '\'`, like, like, like, like)
+	}
+
+	voices, page, err := findPage[model.Voice](c, query, listSort{Fields: voiceSortFields, Default: "id", Order: "asc"})
+	if err != nil {
+		listErrorResponse(c, err, 1, "Failed to fetch voices")
 		return
 	}
-	SuccessResponse(c, voices)
+	PagedSuccessResponse(c, voices, page)
 }
 
-// SyncVoices fetches voices from Minimax and updates DB
-func SyncVoices(c *gin.Context) {
-	keyIDStr := c.Query("key_id")
-	keyID, _ := strconv.Atoi(keyIDStr)
+// UpdateVoiceRequest holds the locally editable voice metadata.
+// Omitted fields are left unchanged.
+type UpdateVoiceRequest struct {
+	Name     *string   `json:"name"`
+	Language *string   `json:"language"`
+	Gender   *string   `json:"gender"`
+	Age      *string   `json:"age"`
+	Tags     *[]string `json:"tags"`
+	Notes    *string   `json:"notes"`
+}
 
-	apiKey, err := getEffectiveKey(uint(keyID))
-	if err != nil {
-		ErrorResponse(c, http.StatusBadRequest, 2, "Invalid API Key or No Default Key")
+// cleanTags trims tags and drops empty and duplicate ones
+func cleanTags(raw []string) model.StringList {
+	tags := model.StringList{}
+	seen := map[string]bool{}
+	for _, t := range raw {
+		t = strings.TrimSpace(t)
+		if t != "" && !seen[t] {
+			seen[t] = true
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// UpdateVoice edits the local metadata of a voice
+func UpdateVoice(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	var req UpdateVoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 1, "Invalid request")
 		return
 	}
 
-	client := minimax.NewClient(apiKey.Key)
-	resp, err := client.GetVoices("all")
-	if err != nil {
-		ErrorResponse(c, http.StatusInternalServerError, 3, "Sync Failed: "+err.Error())
+	var voice model.Voice
+	if err := database.DB.First(&voice, id).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, 2, "Voice not found")
 		return
 	}
 
-	count := 0
-	// Helper to upsert
-	upsert := func(vInfo minimax.VoiceInfo, vType string) {
-		var existing model.Voice
-		// Use Unscoped to include soft-deleted records
-		err := database.DB.Unscoped().Where("voice_id = ?", vInfo.VoiceID).First(&existing).Error
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" || len(name) > 100 {
+			ErrorResponse(c, http.StatusBadRequest, 3, "name must be 1-100 characters")
+			return
+		}
+		updates["name"] = name
+	}
+	if req.Language != nil {
+		updates["language"] = strings.TrimSpace(*req.Language)
+	}
+	if req.Gender != nil {
+		updates["gender"] = strings.TrimSpace(*req.Gender)
+	}
+	if req.Age != nil {
+		updates["age"] = strings.TrimSpace(*req.Age)
+	}
+	if req.Tags != nil {
+		updates["tags"] = cleanTags(*req.Tags)
+	}
+	if req.Notes != nil {
+		updates["notes"] = *req.Notes
+	}
 
-		if err == nil {
-			// Record exists (may be soft-deleted)
-			if existing.DeletedAt.Valid {
-				// Restore soft-deleted record
-				database.DB.Unscoped().Model(&existing).Updates(map[string]interface{}{
-					"deleted_at": nil,
-					"name":       vInfo.VoiceName,
-					"type":       vType,
-				})
-				count++
-			}
-			// If not deleted, just skip (already exists)
+	if len(updates) > 0 {
+		if err := database.DB.Model(&voice).Updates(updates).Error; err != nil {
+			ErrorResponse(c, http.StatusInternalServerError, 4, "Failed to update voice")
 			return
 		}
+	}
+
+	database.DB.First(&voice, id)
+	SuccessResponse(c, voice)
+}
+
+// SyncVoices reconciles the local library with MiniMax.
+// Cloned and designed voices are bound to the key whose account lists them.
+// Without key_id, every key is synced in turn. dry_run=true only reports the
+// diff, prune=true deletes voices that no longer exist remotely.
+func SyncVoices(c *gin.Context) {
+	keyIDStr := c.Query("key_id")
+	keyID, _ := strconv.Atoi(keyIDStr)
+	dryRun := c.Query("dry_run") == "true"
+	prune := c.Query("prune") == "true"
 
-		// Create new record
-		newVoice := model.Voice{
-			Name:    vInfo.VoiceName,
-			VoiceID: vInfo.VoiceID,
-			Type:    vType,
+	var keys []model.ApiKey
+	if keyID > 0 {
+		apiKey, err := getEffectiveKey(uint(keyID))
+		if err != nil {
+			ErrorResponse(c, http.StatusBadRequest, 2, "Invalid API Key or No Default Key")
+			return
 		}
-		if newVoice.Name == "" {
-			newVoice.Name = vInfo.VoiceID
+		keys = append(keys, *apiKey)
+	} else {
+		database.DB.Order("is_default desc, created_at asc").Find(&keys)
+		if len(keys) == 0 {
+			ErrorResponse(c, http.StatusBadRequest, 2, "Invalid API Key or No Default Key")
+			return
 		}
-
-		database.DB.Create(&newVoice)
-		count++
 	}
 
-	for _, v := range resp.SystemVoices {
-		upsert(v, "system")
-	}
-	for _, v := range resp.VoiceCloning {
-		upsert(v, "cloned")
+	diff := reconcileVoices(keys, keyID == 0, dryRun, prune)
+
+	// Nothing could be fetched at all
+	failed := 0
+	for _, k := range diff.Keys {
+		if k.Error != "" {
+			failed++
+		}
 	}
-	for _, v := range resp.VoiceGeneration {
-		upsert(v, "generated")
+	if failed == len(diff.Keys) {
+		ErrorResponse(c, http.StatusInternalServerError, 3, "Sync Failed: "+diff.Keys[0].Error)
+		return
 	}
 
-	SuccessResponse(c, map[string]int{"added": count})
+	SuccessResponse(c, diff)
 }
 
-// CloneVoice requests...
+// CloneVoice records a clone job from the uploaded sample, or a prepared
+// sample given by sample_id, and runs it. The sample and prompt audio are
+// kept with the job so it can be retried.
 func CloneVoice(c *gin.Context) {
 	// 1. Parse Form
 	name := c.PostForm("name")
+	requestedVoiceID := c.PostForm("voice_id") // Optional: custom Minimax voice ID
 	keyIDStr := c.PostForm("key_id")
 	promptText := c.PostForm("prompt_text") // Optional: text for prompt audio
 	demoText := c.PostForm("demo_text")     // Optional: text for demo generation
@@ -112,107 +211,80 @@ func CloneVoice(c *gin.Context) {
 	}
 
 	keyID, _ := strconv.Atoi(keyIDStr)
-	apiKey, err := getEffectiveKey(uint(keyID))
+
+	voiceID, err := resolveVoiceID(requestedVoiceID, "Clone")
 	if err != nil {
-		ErrorResponse(c, http.StatusBadRequest, 3, "Invalid API Key or No Default Key")
+		voiceIDErrorResponse(c, err, 12, 13)
 		return
 	}
 
 	// 2. Get main clone audio file
-	fileHeader, err := c.FormFile("file")
+	source, err := cloneSourceFromRequest(c)
 	if err != nil {
-		ErrorResponse(c, http.StatusBadRequest, 4, "File upload required")
+		ErrorResponse(c, http.StatusNotFound, 11, "Sample not found")
 		return
 	}
-
-	// 3. Save main file temporarily
-	tempDir := "uploads"
-	os.MkdirAll(tempDir, 0755)
-	tempPath := filepath.Join(tempDir, fmt.Sprintf("%d_%s", time.Now().Unix(), fileHeader.Filename))
-	if err := c.SaveUploadedFile(fileHeader, tempPath); err != nil {
-		ErrorResponse(c, http.StatusInternalServerError, 5, "Failed to save file")
+	if source == nil {
+		ErrorResponse(c, http.StatusBadRequest, 4, "File upload required")
 		return
 	}
-	defer os.Remove(tempPath)
 
-	// 4. Upload main clone audio to Minimax
-	client := minimax.NewClient(apiKey.Key)
-	uploadResp, err := client.UploadFile(tempPath, "voice_clone")
-	if err != nil {
-		ErrorResponse(c, http.StatusInternalServerError, 6, "Minimax Upload Failed: "+err.Error())
+	// Check the audio locally before anything is uploaded
+	promptFileHeader, _ := c.FormFile("prompt_file")
+	validation := validateCloneAudio(source, promptFileHeader, promptText)
+	if !validation.Valid() {
+		ErrorResponseWithData(c, http.StatusBadRequest, 10, "Audio validation failed", validation)
 		return
 	}
 
-	// 5. Handle optional prompt audio
-	var clonePrompt *minimax.ClonePrompt
-	promptFileHeader, err := c.FormFile("prompt_file")
-	if err == nil && promptFileHeader != nil {
-		// Save prompt file temporarily
-		promptTempPath := filepath.Join(tempDir, fmt.Sprintf("%d_prompt_%s", time.Now().Unix(), promptFileHeader.Filename))
-		if err := c.SaveUploadedFile(promptFileHeader, promptTempPath); err == nil {
-			defer os.Remove(promptTempPath)
-
-			// Upload prompt audio
-			promptUploadResp, err := client.UploadFile(promptTempPath, "prompt_audio")
-			if err == nil {
-				clonePrompt = &minimax.ClonePrompt{
-					PromptAudio: promptUploadResp.File.FileID,
-					PromptText:  promptText,
-				}
-			}
-		}
-	}
-
-	// 6. Call Voice Clone
-	voiceID := fmt.Sprintf("Clone_%d", time.Now().Unix())
-
 	// Set default model if demo text provided
 	if demoText != "" && speechModel == "" {
 		speechModel = "speech-2.6-hd"
 	}
 
-	cloneReq := &minimax.VoiceCloneRequest{
-		FileID:                  uploadResp.File.FileID,
+	// 3. Record the job with all clone parameters
+	job := model.CloneJob{
+		Name:                    name,
 		VoiceID:                 voiceID,
-		ClonePrompt:             clonePrompt,
-		Text:                    demoText,
+		KeyID:                   uint(keyID),
+		SampleID:                source.sampleID(),
+		SourceFilename:          source.filename(),
+		PromptText:              promptText,
+		DemoText:                demoText,
 		Model:                   speechModel,
 		LanguageBoost:           "auto",
 		NeedNoiseReduction:      noiseReduction,
 		NeedVolumeNormalization: volumeNorm,
 		AigcWatermark:           watermark,
+		Status:                  "pending",
+		Warnings:                validation.WarningMessages(),
+	}
+	if err := database.DB.Create(&job).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 8, "Failed to save clone job")
+		return
 	}
 
-	cloneResp, err := client.VoiceClone(cloneReq)
+	// 4. Keep the sample and optional prompt audio with the job
+	job.SourceFile, err = source.save(c, job.ID)
 	if err != nil {
-		ErrorResponse(c, http.StatusInternalServerError, 7, "Minimax Voice Clone Failed: "+err.Error())
+		job.Status = "failed"
+		job.Error = "Failed to save file: " + err.Error()
+		database.DB.Save(&job)
+		ErrorResponse(c, http.StatusInternalServerError, 5, "Failed to save file")
 		return
 	}
-
-	// 7. Download demo audio if available
-	var demoAudioPath string
-	if cloneResp.DemoAudio != "" {
-		outputDir := "generated/voices"
-		os.MkdirAll(outputDir, 0755)
-		filename := fmt.Sprintf("demo_%s.mp3", voiceID)
-		demoFilePath := filepath.Join(outputDir, filename)
-
-		// Download from URL
-		if err := downloadAudioFromURL(cloneResp.DemoAudio, demoFilePath); err == nil {
-			demoAudioPath = "/files/voices/" + filename
+	if promptFileHeader != nil {
+		if path, err := saveCloneJobFile(c, job.ID, "prompt", promptFileHeader); err == nil {
+			job.PromptFile = path
+			job.PromptFilename = promptFileHeader.Filename
 		}
 	}
+	database.DB.Save(&job)
 
-	// 8. Save to DB
-	voice := model.Voice{
-		Name:    name,
-		VoiceID: voiceID,
-		Type:    "cloned",
-		Preview: demoAudioPath,
-	}
-
-	if err := database.DB.Create(&voice).Error; err != nil {
-		ErrorResponse(c, http.StatusInternalServerError, 8, "Failed to save voice to DB")
+	// 5. Upload, clone and save the voice
+	voice, err := runCloneJob(&job, uint(keyID))
+	if err != nil {
+		cloneJobErrorResponse(c, &job)
 		return
 	}
 
@@ -243,6 +315,7 @@ type DesignVoiceRequest struct {
 	PreviewText string `json:"preview_text" binding:"required"`
 	KeyID       uint   `json:"key_id"`
 	Name        string `json:"name"`
+	VoiceID     string `json:"voice_id"` // Optional custom Minimax voice ID
 	Watermark   bool   `json:"watermark"`
 }
 
@@ -253,20 +326,29 @@ func DesignVoice(c *gin.Context) {
 		return
 	}
 
-	apiKey, err := getEffectiveKey(req.KeyID)
-	if err != nil {
-		ErrorResponse(c, http.StatusBadRequest, 2, "Invalid API Key or No Default Key")
-		return
+	if req.VoiceID != "" {
+		if err := checkVoiceID(req.VoiceID); err != nil {
+			voiceIDErrorResponse(c, err, 5, 6)
+			return
+		}
 	}
 
-	client := minimax.NewClient(apiKey.Key)
-
 	designReq := &minimax.VoiceDesignRequest{
 		Prompt:      req.Prompt,
 		PreviewText: req.PreviewText,
+		VoiceID:     req.VoiceID,
 	}
 
-	resp, err := client.VoiceDesign(designReq)
+	var resp *minimax.VoiceDesignResponse
+	apiKey, err := withKeyFailover(req.KeyID, func(client *minimax.Client) error {
+		var err error
+		resp, err = client.VoiceDesign(designReq)
+		return err
+	})
+	if apiKey == nil {
+		ErrorResponse(c, http.StatusBadRequest, 2, "Invalid API Key or No Default Key")
+		return
+	}
 	if err != nil {
 		ErrorResponse(c, http.StatusInternalServerError, 3, "Design Failed: "+err.Error())
 		return
@@ -291,15 +373,25 @@ func DesignVoice(c *gin.Context) {
 		VoiceID: resp.VoiceID,
 		Type:    "generated",
 		Preview: previewPath,
+		KeyID:   apiKey.ID,
 	}
 	if voice.Name == "" {
 		voice.Name = "Designed " + resp.VoiceID[:8]
 	}
+	voice.ComputeExpiry()
 
 	if err := database.DB.Create(&voice).Error; err != nil {
 		ErrorResponse(c, http.StatusInternalServerError, 4, "Failed to save generated voice")
 		return
 	}
+	if previewPath != "" {
+		recordVoicePreview(&voice, &model.VoicePreview{
+			Source: "design",
+			Text:   req.PreviewText,
+			File:   previewPath,
+			KeyID:  apiKey.ID,
+		})
+	}
 
 	SuccessResponse(c, voice)
 }
@@ -321,19 +413,27 @@ func DeleteVoice(c *gin.Context) {
 		return
 	}
 
-	// Attempt remote delete if key_id provided and voice is remote type
-	if voice.Type == "cloned" || voice.Type == "generated" {
+	// Remote voices are deleted on Minimax first. If that fails the local
+	// row is kept, unless force asks to drop it anyway.
+	force := c.Query("force") == "true"
+	mapping := map[string]string{
+		"cloned":    "voice_cloning",
+		"generated": "voice_generation",
+	}
+	if vType, ok := mapping[voice.Type]; ok {
 		keyID, _ := strconv.Atoi(keyIDStr)
+		if voice.KeyID > 0 {
+			keyID = int(voice.KeyID)
+		}
 		apiKey, err := getEffectiveKey(uint(keyID))
+		if err != nil && !force {
+			ErrorResponse(c, http.StatusBadRequest, 3, "No API key to delete the voice on Minimax with; pass force=true to delete it locally only")
+			return
+		}
 		if err == nil {
-			client := minimax.NewClient(apiKey.Key)
-			mapping := map[string]string{
-				"cloned":    "voice_cloning",
-				"generated": "voice_generation",
-			}
-			if vType, ok := mapping[voice.Type]; ok {
-				client.DeleteVoice(vType, voice.VoiceID)
-				// Log error but proceed to delete local?
+			if err := minimax.NewClient(apiKey.Key).DeleteVoice(vType, voice.VoiceID); err != nil && !force {
+				ErrorResponse(c, http.StatusBadGateway, 4, "Failed to delete voice on Minimax: "+err.Error()+"; pass force=true to delete it locally only")
+				return
 			}
 		}
 	}
@@ -346,9 +446,11 @@ func DeleteVoice(c *gin.Context) {
 }
 
 func ListFavorites(c *gin.Context) {
-	var rows []model.Voice
-	if err := database.DB.Model(&model.Voice{}).Select("voice_id").Where("is_favorite = ?", true).Find(&rows).Error; err != nil {
-		ErrorResponse(c, http.StatusInternalServerError, 1, "Failed to list favorites")
+	// The id is selected too, for the page cursor
+	query := database.DB.Model(&model.Voice{}).Select("id, voice_id").Where("is_favorite = ?", true)
+	rows, page, err := findPage[model.Voice](c, query, listSort{Fields: map[string]string{"id": "id"}, Default: "id", Order: "asc"})
+	if err != nil {
+		listErrorResponse(c, err, 1, "Failed to list favorites")
 		return
 	}
 
@@ -357,7 +459,7 @@ func ListFavorites(c *gin.Context) {
 		ids = append(ids, r.VoiceID)
 	}
 
-	SuccessResponse(c, ids)
+	PagedSuccessResponse(c, ids, page)
 }
 
 func ToggleFavorite(c *gin.Context) {
@@ -385,85 +487,3 @@ func ToggleFavorite(c *gin.Context) {
 
 	SuccessResponse(c, gin.H{"voice_id": voiceID, "favorite": next})
 }
-
-// GeneratePreviewRequest for generating preview audio
-type GeneratePreviewRequest struct {
-	VoiceID string `json:"voice_id"`
-	KeyID   uint   `json:"key_id"`
-}
-
-// GeneratePreview generates a standard preview audio for a voice
-func GeneratePreview(c *gin.Context) {
-	var req GeneratePreviewRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		ErrorResponse(c, http.StatusBadRequest, 1, "Invalid request")
-		return
-	}
-
-	var voice model.Voice
-	if err := database.DB.Where("voice_id = ?", req.VoiceID).First(&voice).Error; err != nil {
-		ErrorResponse(c, http.StatusNotFound, 2, "Voice not found")
-		return
-	}
-
-	// Check if preview already exists
-	if voice.Preview != "" {
-		SuccessResponse(c, voice)
-		return
-	}
-
-	apiKey, err := getEffectiveKey(req.KeyID)
-	if err != nil {
-		ErrorResponse(c, http.StatusBadRequest, 3, "Invalid API Key")
-		return
-	}
-
-	client := minimax.NewClient(apiKey.Key)
-
-	// T2A Request
-	t2aReq := &minimax.T2ARequest{
-		Model: "speech-2.6-hd",
-		Text:  "你好，欢迎来到赛博笔记的专属频道，让我们一起探索互联网的世界吧。",
-		VoiceSetting: minimax.VoiceSetting{
-			VoiceID: voice.VoiceID,
-			Speed:   1,
-			Vol:     1,
-			Pitch:   0,
-			Emotion: "happy",
-		},
-		AudioSetting: minimax.AudioSetting{
-			AudioSampleRate: 32000,
-			Bitrate:         128000,
-			Format:          "mp3",
-			Channel:         1,
-		},
-	}
-
-	resp, err := client.T2A(t2aReq)
-	if err != nil {
-		ErrorResponse(c, http.StatusInternalServerError, 4, "T2A Failed: "+err.Error())
-		return
-	}
-
-	// Decode Hex Audio
-	audioBytes, err := hex.DecodeString(resp.Data.Audio)
-	if err != nil {
-		ErrorResponse(c, http.StatusInternalServerError, 5, "Failed to decode audio")
-		return
-	}
-
-	// Save File
-	outputDir := "generated/voices"
-	os.MkdirAll(outputDir, 0755)
-	filename := fmt.Sprintf("preview_%s.mp3", voice.VoiceID)
-	filepathStr := filepath.Join(outputDir, filename)
-	if err := os.WriteFile(filepathStr, audioBytes, 0644); err != nil {
-		ErrorResponse(c, http.StatusInternalServerError, 6, "Failed to save file")
-		return
-	}
-
-	voice.Preview = "/files/voices/" + filename
-	database.DB.Save(&voice)
-
-	SuccessResponse(c, voice)
-}
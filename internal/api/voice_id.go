@@ -0,0 +1,95 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"minimax-voice-workbench/internal/database"
+	"minimax-voice-workbench/internal/model"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Minimax voice ID rules
+const (
+	voiceIDMinLength = 8
+	voiceIDMaxLength = 256
+)
+
+var (
+	errVoiceIDInvalid = errors.New("invalid voice_id")
+	errVoiceIDTaken   = errors.New("voice_id already in use")
+)
+
+// validateVoiceIDFormat checks a custom voice ID against the Minimax rules:
+// 8-256 characters, starting with a letter, only letters, digits, '-' and
+// '_', and not ending with '-' or '_'
+func validateVoiceIDFormat(voiceID string) error {
+	if len(voiceID) < voiceIDMinLength || len(voiceID) > voiceIDMaxLength {
+		return fmt.Errorf("%w: must be %d to %d characters", errVoiceIDInvalid, voiceIDMinLength, voiceIDMaxLength)
+	}
+	first := voiceID[0]
+	if !(first >= 'a' && first <= 'z' || first >= 'A' && first <= 'Z') {
+		return fmt.Errorf("%w: must start with a letter", errVoiceIDInvalid)
+	}
+	for _, r := range voiceID {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return fmt.Errorf("%w: only letters, digits, '-' and '_' are allowed", errVoiceIDInvalid)
+		}
+	}
+	if last := voiceID[len(voiceID)-1]; last == '-' || last == '_' {
+		return fmt.Errorf("%w: must not end with '-' or '_'", errVoiceIDInvalid)
+	}
+	return nil
+}
+
+// checkVoiceIDAvailable fails if a voice, including a deleted one, an
+// unfinished clone job or an active design candidate already uses the ID
+func checkVoiceIDAvailable(voiceID string) error {
+	var count int64
+	database.DB.Unscoped().Model(&model.Voice{}).Where("voice_id = ?", voiceID).Count(&count)
+	if count > 0 {
+		return errVoiceIDTaken
+	}
+	// Failed jobs keep their ID so that a retry creates the same voice
+	database.DB.Model(&model.CloneJob{}).Where("voice_id = ? AND status <> ?", voiceID, "success").Count(&count)
+	if count > 0 {
+		return errVoiceIDTaken
+	}
+	database.DB.Model(&model.DesignCandidate{}).Where("voice_id = ? AND status = ?", voiceID, "active").Count(&count)
+	if count > 0 {
+		return errVoiceIDTaken
+	}
+	return nil
+}
+
+// checkVoiceID validates a custom voice ID before anything is uploaded
+func checkVoiceID(voiceID string) error {
+	if err := validateVoiceIDFormat(voiceID); err != nil {
+		return err
+	}
+	return checkVoiceIDAvailable(voiceID)
+}
+
+// resolveVoiceID validates a requested voice ID. An empty request gets a
+// generated ID with the given prefix.
+func resolveVoiceID(requested, prefix string) (string, error) {
+	if requested == "" {
+		return fmt.Sprintf("%s_%d", prefix, time.Now().UnixNano()), nil
+	}
+	if err := checkVoiceID(requested); err != nil {
+		return "", err
+	}
+	return requested, nil
+}
+
+// voiceIDErrorResponse reports an invalid or taken voice ID with the
+// handler's error codes
+func voiceIDErrorResponse(c *gin.Context, err error, invalidCode, takenCode int) {
+	if errors.Is(err, errVoiceIDTaken) {
+		ErrorResponse(c, http.StatusConflict, takenCode, "voice_id already in use")
+		return
+	}
+	ErrorResponse(c, http.StatusBadRequest, invalidCode, err.Error())
+}
@@ -0,0 +1,402 @@
+package api
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"minimax-voice-workbench/internal/database"
+	"minimax-voice-workbench/internal/model"
+	"minimax-voice-workbench/pkg/minimax"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GeneratePreviewRequest for generating preview audio
+type GeneratePreviewRequest struct {
+	VoiceID    string `json:"voice_id"`
+	KeyID      uint   `json:"key_id"`
+	TemplateID uint   `json:"template_id"` // Defaults to the template of the voice language
+	Text       string `json:"text"`        // Overrides the template text
+	Emotion    string `json:"emotion"`     // Overrides the template emotion
+	Force      bool   `json:"force"`       // Generate even if a matching preview exists
+}
+
+// selectPreviewTemplate picks the template for a voice: the default one of
+// its language, any one of its language, then the fallback template
+func selectPreviewTemplate(language string) (*model.PreviewTemplate, error) {
+	var tpl model.PreviewTemplate
+	if language != "" {
+		err := database.DB.Where("language = ?", language).Order("is_default desc, id asc").First(&tpl).Error
+		if err == nil {
+			return &tpl, nil
+		}
+	}
+	if err := database.DB.Where("language = ?", "").Order("is_default desc, id asc").First(&tpl).Error; err != nil {
+		return nil, err
+	}
+	return &tpl, nil
+}
+
+// recordVoicePreview stores a preview of a voice and makes it the default
+func recordVoicePreview(voice *model.Voice, preview *model.VoicePreview) {
+	preview.VoiceID = voice.VoiceID
+	database.DB.Create(preview)
+	voice.Preview = preview.File
+	database.DB.Model(voice).Update("preview", preview.File)
+}
+
+// previewOptions selects the template and overrides of a preview
+type previewOptions struct {
+	KeyID      uint
+	TemplateID uint
+	Text       string
+	Emotion    string
+	Force      bool
+}
+
+// previewError carries the handler error code of a failed preview
+type previewError struct {
+	httpCode int
+	code     int
+	message  string
+	err      error
+}
+
+func (e *previewError) Error() string {
+	return e.message
+}
+
+func (e *previewError) Unwrap() error {
+	return e.err
+}
+
+// generateVoicePreview makes a preview of a voice from a template and
+// stores it as the voice default. An existing preview with the same text,
+// emotion and model is reused unless opts.Force is set.
+func generateVoicePreview(voice *model.Voice, opts previewOptions) error {
+	var tpl *model.PreviewTemplate
+	var err error
+	if opts.TemplateID > 0 {
+		tpl = &model.PreviewTemplate{}
+		err = database.DB.First(tpl, opts.TemplateID).Error
+	} else {
+		tpl, err = selectPreviewTemplate(voice.Language)
+	}
+	if err != nil {
+		return &previewError{http.StatusNotFound, 7, "Preview template not found", err}
+	}
+
+	text := tpl.Text
+	if opts.Text != "" {
+		text = opts.Text
+	}
+	emotion := tpl.Emotion
+	if opts.Emotion != "" {
+		emotion = opts.Emotion
+	}
+
+	// Reuse a matching preview unless a new take is requested
+	if !opts.Force {
+		var existing model.VoicePreview
+		err := database.DB.Where("voice_id = ? AND text = ? AND emotion = ? AND model = ?", voice.VoiceID, text, emotion, tpl.Model).
+			Order("created_at desc").First(&existing).Error
+		if err == nil {
+			if _, statErr := os.Stat(generatedFilePath(existing.File)); statErr == nil {
+				if voice.Preview != existing.File {
+					voice.Preview = existing.File
+					database.DB.Model(voice).Update("preview", existing.File)
+				}
+				return nil
+			}
+		}
+	}
+
+	// T2A Request
+	t2aReq := &minimax.T2ARequest{
+		Model: tpl.Model,
+		Text:  text,
+		VoiceSetting: minimax.VoiceSetting{
+			VoiceID: voice.VoiceID,
+			Speed:   tpl.Speed,
+			Vol:     tpl.Vol,
+			Pitch:   tpl.Pitch,
+			Emotion: emotion,
+		},
+		AudioSetting: minimax.AudioSetting{
+			AudioSampleRate: int64(tpl.SampleRate),
+			Bitrate:         int64(tpl.Bitrate),
+			Format:          tpl.Format,
+			Channel:         1,
+		},
+	}
+
+	var resp *minimax.T2AResponse
+	apiKey, err := withVoiceKey(voice.VoiceID, opts.KeyID, func(client *minimax.Client) error {
+		var err error
+		resp, err = client.T2A(t2aReq)
+		return err
+	})
+	if apiKey == nil {
+		return &previewError{http.StatusBadRequest, 3, "Invalid API Key", err}
+	}
+	if err != nil {
+		return &previewError{http.StatusInternalServerError, 4, "T2A Failed: " + err.Error(), err}
+	}
+
+	// Decode Hex Audio
+	audioBytes, err := hex.DecodeString(resp.Data.Audio)
+	if err != nil {
+		return &previewError{http.StatusInternalServerError, 5, "Failed to decode audio", err}
+	}
+
+	// Save File
+	outputDir := "generated/voices"
+	os.MkdirAll(outputDir, 0755)
+	filename := fmt.Sprintf("preview_%s_%d.%s", voice.VoiceID, time.Now().UnixNano(), tpl.Format)
+	if err := os.WriteFile(filepath.Join(outputDir, filename), audioBytes, 0644); err != nil {
+		return &previewError{http.StatusInternalServerError, 6, "Failed to save file", err}
+	}
+
+	source := "template"
+	if opts.Text != "" {
+		source = "custom"
+	}
+	recordVoicePreview(voice, &model.VoicePreview{
+		TemplateID: tpl.ID,
+		Source:     source,
+		Text:       text,
+		Model:      tpl.Model,
+		Emotion:    emotion,
+		File:       "/files/voices/" + filename,
+		KeyID:      apiKey.ID,
+	})
+	markVoiceUsed(voice.VoiceID)
+	return nil
+}
+
+// GeneratePreview generates a preview audio for a voice from a template.
+// An existing preview with the same text, emotion and model is reused
+// unless force is set.
+func GeneratePreview(c *gin.Context) {
+	var req GeneratePreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 1, "Invalid request")
+		return
+	}
+
+	var voice model.Voice
+	if err := database.DB.Where("voice_id = ?", req.VoiceID).First(&voice).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, 2, "Voice not found")
+		return
+	}
+
+	err := generateVoicePreview(&voice, previewOptions{
+		KeyID:      req.KeyID,
+		TemplateID: req.TemplateID,
+		Text:       req.Text,
+		Emotion:    req.Emotion,
+		Force:      req.Force,
+	})
+	var pErr *previewError
+	if errors.As(err, &pErr) {
+		ErrorResponse(c, pErr.httpCode, pErr.code, pErr.message)
+		return
+	}
+	database.DB.First(&voice, voice.ID)
+
+	SuccessResponse(c, voice)
+}
+
+// ListVoicePreviews returns every stored preview of a voice, newest first
+func ListVoicePreviews(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var voice model.Voice
+	if err := database.DB.First(&voice, id).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Voice not found")
+		return
+	}
+
+	query := database.DB.Model(&model.VoicePreview{}).Where("voice_id = ?", voice.VoiceID)
+	previews, page, err := findPage[model.VoicePreview](c, query, newestFirst)
+	if err != nil {
+		listErrorResponse(c, err, 2, "Failed to fetch previews")
+		return
+	}
+	PagedSuccessResponse(c, previews, page)
+}
+
+// SetDefaultVoicePreview makes a stored preview the one shown for its voice
+func SetDefaultVoicePreview(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var preview model.VoicePreview
+	if err := database.DB.First(&preview, id).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Preview not found")
+		return
+	}
+
+	var voice model.Voice
+	if err := database.DB.Where("voice_id = ?", preview.VoiceID).First(&voice).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, 2, "Voice not found")
+		return
+	}
+	database.DB.Model(&voice).Update("preview", preview.File)
+	SuccessResponse(c, voice)
+}
+
+// DeleteVoicePreview removes a stored preview and its file. If it was the
+// default, the newest remaining preview takes its place.
+func DeleteVoicePreview(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var preview model.VoicePreview
+	if err := database.DB.First(&preview, id).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, 1, "Preview not found")
+		return
+	}
+
+	os.Remove(generatedFilePath(preview.File))
+	if err := database.DB.Delete(&preview).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 2, "Failed to delete preview")
+		return
+	}
+
+	var voice model.Voice
+	if err := database.DB.Where("voice_id = ? AND preview = ?", preview.VoiceID, preview.File).First(&voice).Error; err == nil {
+		var next model.VoicePreview
+		file := ""
+		if err := database.DB.Where("voice_id = ?", preview.VoiceID).Order("created_at desc").First(&next).Error; err == nil {
+			file = next.File
+		}
+		database.DB.Model(&voice).Update("preview", file)
+	}
+	SuccessResponse(c, nil)
+}
+
+// ListPreviewTemplates returns preview templates grouped by language
+func ListPreviewTemplates(c *gin.Context) {
+	templates, page, err := findPage[model.PreviewTemplate](c, database.DB.Model(&model.PreviewTemplate{}), listSort{
+		Fields:  map[string]string{"language": "language", "name": "name", "created_at": "created_at"},
+		Default: "language",
+		Order:   "asc",
+	})
+	if err != nil {
+		listErrorResponse(c, err, 1, "Failed to fetch templates")
+		return
+	}
+	PagedSuccessResponse(c, templates, page)
+}
+
+// PreviewTemplateRequest creates or updates a preview template
+type PreviewTemplateRequest struct {
+	Name       string  `json:"name" binding:"required"`
+	Language   string  `json:"language"`
+	Text       string  `json:"text" binding:"required"`
+	Model      string  `json:"model"`
+	Emotion    string  `json:"emotion"`
+	Speed      float64 `json:"speed"`
+	Vol        float64 `json:"vol"`
+	Pitch      int     `json:"pitch"`
+	SampleRate int     `json:"sample_rate"`
+	Bitrate    int     `json:"bitrate"`
+	Format     string  `json:"format"`
+	IsDefault  bool    `json:"is_default"`
+}
+
+// apply copies the request onto a template, filling in defaults
+func (r *PreviewTemplateRequest) apply(tpl *model.PreviewTemplate) {
+	tpl.Name = r.Name
+	tpl.Language = r.Language
+	tpl.Text = r.Text
+	tpl.Model = r.Model
+	tpl.Emotion = r.Emotion
+	tpl.Speed = r.Speed
+	tpl.Vol = r.Vol
+	tpl.Pitch = r.Pitch
+	tpl.SampleRate = r.SampleRate
+	tpl.Bitrate = r.Bitrate
+	tpl.Format = r.Format
+	tpl.IsDefault = r.IsDefault
+	if tpl.Model == "" {
+		tpl.Model = "speech-2.6-hd"
+	}
+	if tpl.Speed == 0 {
+		tpl.Speed = 1
+	}
+	if tpl.Vol == 0 {
+		tpl.Vol = 1
+	}
+	if tpl.SampleRate == 0 {
+		tpl.SampleRate = 32000
+	}
+	if tpl.Bitrate == 0 {
+		tpl.Bitrate = 128000
+	}
+	if tpl.Format == "" {
+		tpl.Format = "mp3"
+	}
+}
+
+// saveDefaultTemplate saves a template, keeping one default per language
+func saveDefaultTemplate(tpl *model.PreviewTemplate) error {
+	if tpl.IsDefault {
+		database.DB.Model(&model.PreviewTemplate{}).
+			Where("language = ? AND id <> ?", tpl.Language, tpl.ID).
+			Update("is_default", false)
+	}
+	return database.DB.Save(tpl).Error
+}
+
+// CreatePreviewTemplate adds a preview template
+func CreatePreviewTemplate(c *gin.Context) {
+	var req PreviewTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 1, "name and text are required")
+		return
+	}
+
+	var tpl model.PreviewTemplate
+	req.apply(&tpl)
+	if err := saveDefaultTemplate(&tpl); err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 2, "Failed to save template")
+		return
+	}
+	SuccessResponse(c, tpl)
+}
+
+// UpdatePreviewTemplate replaces a preview template
+func UpdatePreviewTemplate(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var tpl model.PreviewTemplate
+	if err := database.DB.First(&tpl, id).Error; err != nil {
+		ErrorResponse(c, http.StatusNotFound, 3, "Template not found")
+		return
+	}
+
+	var req PreviewTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, 1, "name and text are required")
+		return
+	}
+
+	req.apply(&tpl)
+	if err := saveDefaultTemplate(&tpl); err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 2, "Failed to save template")
+		return
+	}
+	SuccessResponse(c, tpl)
+}
+
+// DeletePreviewTemplate removes a preview template. Previews made from it
+// are kept.
+func DeletePreviewTemplate(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	if err := database.DB.Delete(&model.PreviewTemplate{}, id).Error; err != nil {
+		ErrorResponse(c, http.StatusInternalServerError, 1, "Failed to delete template")
+		return
+	}
+	SuccessResponse(c, nil)
+}
@@ -0,0 +1,337 @@
+package api
+
+import (
+	"log"
+	"minimax-voice-workbench/internal/database"
+	"minimax-voice-workbench/internal/model"
+	"minimax-voice-workbench/pkg/minimax"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VoiceChange describes one voice in a sync diff
+type VoiceChange struct {
+	VoiceID string `json:"voice_id"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	KeyID   uint   `json:"key_id"`
+	OldName string `json:"old_name,omitempty"`
+	OldType string `json:"old_type,omitempty"`
+	OldKey  uint   `json:"old_key_id,omitempty"`
+}
+
+// KeySyncResult reports whether the voice list of one key could be fetched
+type KeySyncResult struct {
+	KeyID uint   `json:"key_id"`
+	Error string `json:"error,omitempty"`
+}
+
+// VoiceSyncDiff is the outcome of reconciling local voices with MiniMax
+type VoiceSyncDiff struct {
+	DryRun   bool            `json:"dry_run"`
+	Pruned   bool            `json:"pruned"`
+	Added    []VoiceChange   `json:"added"`    // New remotely, or restored after a local delete
+	Updated  []VoiceChange   `json:"updated"`  // Renamed, retyped or moved to another key
+	Removed  []VoiceChange   `json:"removed"`  // Gone from the account that owned them
+	Orphaned []VoiceChange   `json:"orphaned"` // Custom voices whose owning key no longer exists
+	Keys     []KeySyncResult `json:"keys"`
+}
+
+// remoteVoice is a voice as listed by one key's account
+type remoteVoice struct {
+	info  minimax.VoiceInfo
+	vType string
+	keyID uint // 0 for system voices
+}
+
+// voiceSyncMu keeps manual and scheduled syncs from interleaving
+var voiceSyncMu sync.Mutex
+
+// reconcileVoices compares the voices of the given keys' accounts with the
+// local library and, unless dryRun, applies the difference. Voices missing
+// remotely are only reported unless prune is set, in which case their
+// records and preview files are deleted for good.
+func reconcileVoices(keys []model.ApiKey, fullSync, dryRun, prune bool) *VoiceSyncDiff {
+	voiceSyncMu.Lock()
+	defer voiceSyncMu.Unlock()
+
+	diff := &VoiceSyncDiff{
+		DryRun:   dryRun,
+		Pruned:   prune && !dryRun,
+		Added:    []VoiceChange{},
+		Updated:  []VoiceChange{},
+		Removed:  []VoiceChange{},
+		Orphaned: []VoiceChange{},
+	}
+
+	// 1. Fetch remote lists. Keys that fail are left out of removal decisions.
+	remote := map[string]remoteVoice{}
+	listedBy := map[string]map[uint]bool{} // Keys listing each account voice
+	synced := map[uint]bool{}
+	for _, key := range keys {
+		var resp *minimax.GetVoicesResponse
+		_, err := withKey(key.ID, func(client *minimax.Client) error {
+			var err error
+			resp, err = client.GetVoices("all")
+			return err
+		})
+		result := KeySyncResult{KeyID: key.ID}
+		if err != nil {
+			result.Error = err.Error()
+			diff.Keys = append(diff.Keys, result)
+			continue
+		}
+		diff.Keys = append(diff.Keys, result)
+		synced[key.ID] = true
+
+		for _, v := range resp.SystemVoices {
+			remote[v.VoiceID] = remoteVoice{info: v, vType: "system"}
+		}
+		list := func(v minimax.VoiceInfo, vType string) {
+			remote[v.VoiceID] = remoteVoice{info: v, vType: vType, keyID: key.ID}
+			if listedBy[v.VoiceID] == nil {
+				listedBy[v.VoiceID] = map[uint]bool{}
+			}
+			listedBy[v.VoiceID][key.ID] = true
+		}
+		for _, v := range resp.VoiceCloning {
+			list(v, "cloned")
+		}
+		for _, v := range resp.VoiceGeneration {
+			list(v, "generated")
+		}
+	}
+	if len(synced) == 0 {
+		return diff
+	}
+
+	// Design candidates stay out of the library until promoted
+	for voiceID := range designCandidateVoiceIDs() {
+		delete(remote, voiceID)
+	}
+
+	// 2. Load local voices, including soft-deleted ones so they can be restored
+	var locals []model.Voice
+	database.DB.Unscoped().Find(&locals)
+	localByID := make(map[string]*model.Voice, len(locals))
+	for i := range locals {
+		localByID[locals[i].VoiceID] = &locals[i]
+	}
+
+	var existingKeys []model.ApiKey
+	database.DB.Select("id").Find(&existingKeys)
+	keyExists := map[uint]bool{}
+	for _, k := range existingKeys {
+		keyExists[k.ID] = true
+	}
+
+	// 3. Remote voices that are new or changed
+	for voiceID, r := range remote {
+		change := VoiceChange{VoiceID: voiceID, Name: r.info.VoiceName, Type: r.vType, KeyID: r.keyID}
+		if change.Name == "" {
+			change.Name = voiceID
+		}
+
+		description := strings.Join(r.info.Description, "\n")
+
+		local, ok := localByID[voiceID]
+		if !ok {
+			diff.Added = append(diff.Added, change)
+			if !dryRun {
+				voice := model.Voice{
+					Name:              change.Name,
+					VoiceID:           voiceID,
+					Type:              r.vType,
+					KeyID:             r.keyID,
+					Description:       description,
+					RemoteCreatedTime: r.info.CreatedTime,
+					Language:          inferVoiceLanguage(voiceID),
+					Gender:            inferVoiceGender(voiceID),
+				}
+				voice.ComputeExpiry()
+				database.DB.Create(&voice)
+			}
+			continue
+		}
+
+		// Remote metadata is refreshed silently, it does not count as an update
+		meta := map[string]interface{}{}
+		if description != local.Description {
+			meta["description"] = description
+		}
+		if r.info.CreatedTime != local.RemoteCreatedTime {
+			meta["remote_created_time"] = r.info.CreatedTime
+			expiring := *local
+			expiring.Type = r.vType
+			expiring.RemoteCreatedTime = r.info.CreatedTime
+			expiring.ComputeExpiry()
+			meta["expires_at"] = expiring.ExpiresAt
+		}
+		if local.Language == "" {
+			if lang := inferVoiceLanguage(voiceID); lang != "" {
+				meta["language"] = lang
+			}
+		}
+		if local.Gender == "" {
+			if gender := inferVoiceGender(voiceID); gender != "" {
+				meta["gender"] = gender
+			}
+		}
+
+		updates := map[string]interface{}{}
+		// Cloned voices usually come back without a name; keep the local one then
+		if r.info.VoiceName != "" && r.info.VoiceName != local.Name {
+			updates["name"] = r.info.VoiceName
+			change.OldName = local.Name
+		} else {
+			change.Name = local.Name
+		}
+		if r.vType != local.Type {
+			updates["type"] = r.vType
+			change.OldType = local.Type
+		}
+		// Keys sharing an account list the same voices: the voice stays with
+		// its key unless that key was synced and no longer lists it
+		owner := local.KeyID
+		if r.keyID != 0 && owner != 0 && keyExists[owner] && (listedBy[voiceID][owner] || !synced[owner]) {
+			r.keyID = owner
+			change.KeyID = owner
+		}
+		if r.keyID != local.KeyID {
+			updates["key_id"] = r.keyID
+			change.OldKey = local.KeyID
+		}
+
+		if local.DeletedAt.Valid {
+			diff.Added = append(diff.Added, change)
+			updates["deleted_at"] = nil
+		} else if len(updates) > 0 {
+			diff.Updated = append(diff.Updated, change)
+		}
+		for k, v := range meta {
+			updates[k] = v
+		}
+		if !dryRun && len(updates) > 0 {
+			database.DB.Unscoped().Model(local).Updates(updates)
+		}
+	}
+
+	// 4. Local voices the remote side no longer lists
+	for i := range locals {
+		local := &locals[i]
+		if local.DeletedAt.Valid {
+			continue
+		}
+		if _, ok := remote[local.VoiceID]; ok {
+			continue
+		}
+
+		change := VoiceChange{VoiceID: local.VoiceID, Name: local.Name, Type: local.Type, KeyID: local.KeyID}
+		switch {
+		case local.Type == "system":
+			// Every account lists the same system voices
+			diff.Removed = append(diff.Removed, change)
+		case local.KeyID > 0 && synced[local.KeyID]:
+			diff.Removed = append(diff.Removed, change)
+		case local.KeyID > 0 && !keyExists[local.KeyID]:
+			diff.Orphaned = append(diff.Orphaned, change)
+		case local.KeyID == 0 && fullSync:
+			// Not found under any key and never bound to one
+			diff.Orphaned = append(diff.Orphaned, change)
+		default:
+			continue
+		}
+
+		if diff.Pruned {
+			pruneVoice(local)
+		}
+	}
+
+	return diff
+}
+
+// pruneVoice permanently deletes a voice record and its preview files
+func pruneVoice(voice *model.Voice) {
+	var previews []model.VoicePreview
+	database.DB.Where("voice_id = ?", voice.VoiceID).Find(&previews)
+	files := []string{voice.Preview}
+	for _, p := range previews {
+		files = append(files, p.File)
+	}
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+		if err := os.Remove(generatedFilePath(file)); err != nil && !os.IsNotExist(err) {
+			log.Printf("Voice sync: failed to remove preview of %s: %v", voice.VoiceID, err)
+		}
+	}
+	database.DB.Where("voice_id = ?", voice.VoiceID).Delete(&model.VoicePreview{})
+	database.DB.Unscoped().Delete(&model.Voice{}, voice.ID)
+}
+
+// StartVoiceSyncScheduler reconciles voices for all keys every interval.
+// Scheduled runs never prune; stale voices are only logged.
+func StartVoiceSyncScheduler(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			var keys []model.ApiKey
+			database.DB.Order("is_default desc, created_at asc").Find(&keys)
+			if len(keys) == 0 {
+				continue
+			}
+			diff := reconcileVoices(keys, true, false, false)
+			log.Printf("Voice sync: %d added, %d updated, %d removed, %d orphaned",
+				len(diff.Added), len(diff.Updated), len(diff.Removed), len(diff.Orphaned))
+		}
+	}()
+	log.Println("Voice sync scheduled every", interval)
+}
+
+// Language prefixes used by MiniMax system voice IDs, e.g. "English_Graceful_Lady"
+var voiceLanguages = []string{
+	"Chinese (Mandarin)", "Cantonese", "English", "Japanese", "Korean",
+	"Spanish", "Portuguese", "French", "Indonesian", "German", "Russian",
+	"Italian", "Arabic", "Turkish", "Ukrainian", "Dutch", "Vietnamese",
+	"Thai", "Polish", "Romanian", "Greek", "Czech", "Finnish", "Hindi",
+}
+
+// inferVoiceLanguage guesses the language of a voice from its ID
+func inferVoiceLanguage(voiceID string) string {
+	for _, lang := range voiceLanguages {
+		if strings.HasPrefix(voiceID, lang+"_") {
+			return lang
+		}
+	}
+	// Early system voices such as "male-qn-qingse" are all Mandarin
+	if strings.HasPrefix(voiceID, "male-") || strings.HasPrefix(voiceID, "female-") {
+		return "Chinese (Mandarin)"
+	}
+	return ""
+}
+
+var genderWords = map[string]string{
+	"male": "male", "man": "male", "boy": "male", "gentleman": "male", "guy": "male",
+	"female": "female", "woman": "female", "girl": "female", "lady": "female",
+}
+
+// inferVoiceGender guesses the gender of a voice from the words in its ID
+func inferVoiceGender(voiceID string) string {
+	words := strings.FieldsFunc(strings.ToLower(voiceID), func(r rune) bool {
+		return r < 'a' || r > 'z'
+	})
+	for _, w := range words {
+		if gender, ok := genderWords[w]; ok {
+			return gender
+		}
+	}
+	return ""
+}
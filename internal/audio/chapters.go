@@ -0,0 +1,149 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf16"
+)
+
+// Chapter marks a titled span of a combined track, in seconds
+type Chapter struct {
+	Title string  `json:"title"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// mp3FrameRange returns the span of a MP3 file holding its audio frames,
+// leaving out its ID3v2 and ID3v1 tags and any Xing or Info header frame
+func mp3FrameRange(r io.ReaderAt, size int64) (start, end int64) {
+	end = size
+	head := make([]byte, 10)
+	if n, _ := r.ReadAt(head, 0); n == 10 && string(head[0:3]) == "ID3" {
+		tagSize := int64(head[6])<<21 | int64(head[7])<<14 | int64(head[8])<<7 | int64(head[9])
+		start = 10 + tagSize
+		if head[5]&0x10 != 0 {
+			start += 10
+		}
+		start = min(start, size)
+	}
+	h := make([]byte, 4)
+	if _, err := r.ReadAt(h, start); err == nil {
+		if frame, ok := parseMP3Header(h); ok && mp3InfoFrame(r, start, frame) {
+			start = min(start+int64(frame.length), size)
+		}
+	}
+	if end-start >= 128 {
+		tail := make([]byte, 3)
+		if _, err := r.ReadAt(tail, end-128); err == nil && string(tail) == "TAG" {
+			end -= 128
+		}
+	}
+	return start, end
+}
+
+// ConcatMP3 copies the audio frames of the MP3 files at paths to w, one
+// after the other, and returns the duration of each file in seconds. The
+// files should share sample rate and channels. Files are streamed, never
+// held in memory whole.
+func ConcatMP3(w io.Writer, paths []string) ([]float64, error) {
+	durations := make([]float64, len(paths))
+	for i, p := range paths {
+		d, err := copyMP3Frames(w, p)
+		if err != nil {
+			return nil, err
+		}
+		durations[i] = d
+	}
+	return durations, nil
+}
+
+func copyMP3Frames(w io.Writer, path string) (float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	start, end := mp3FrameRange(f, st.Size())
+	frames := io.NewSectionReader(f, start, end-start)
+	info, err := probeMP3(frames, end-start)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+	if _, err := io.Copy(w, frames); err != nil {
+		return 0, err
+	}
+	return info.Duration, nil
+}
+
+// MaxChapters is the most chapters a chapter tag holds: ID3v2.3 allows 255
+// entries in a table of contents
+const MaxChapters = 255
+
+// ChapterTag builds an ID3v2.3 tag with the title of a combined track and
+// a CHAP frame for each chapter, listed in an ordered CTOC frame. Players
+// that support chapters show them from this tag placed before the audio.
+// The size of the tag does not depend on the chapter times, so a tag built
+// with zero times reserves the space of the final one.
+func ChapterTag(title string, chapters []Chapter) ([]byte, error) {
+	if len(chapters) > MaxChapters {
+		return nil, errors.New("id3: too many chapters")
+	}
+
+	var frames bytes.Buffer
+	if title != "" {
+		frames.Write(id3Frame("TIT2", id3Text(title)))
+	}
+
+	// Top-level, ordered table of contents
+	toc := []byte("toc\x00")
+	toc = append(toc, 0x03, byte(len(chapters)))
+	for i := range chapters {
+		toc = append(toc, []byte(fmt.Sprintf("ch%d\x00", i))...)
+	}
+	frames.Write(id3Frame("CTOC", toc))
+
+	for i, ch := range chapters {
+		body := []byte(fmt.Sprintf("ch%d\x00", i))
+		body = binary.BigEndian.AppendUint32(body, uint32(ch.Start*1000))
+		body = binary.BigEndian.AppendUint32(body, uint32(ch.End*1000))
+		// Byte offsets are not given
+		body = binary.BigEndian.AppendUint32(body, 0xFFFFFFFF)
+		body = binary.BigEndian.AppendUint32(body, 0xFFFFFFFF)
+		body = append(body, id3Frame("TIT2", id3Text(ch.Title))...)
+		frames.Write(id3Frame("CHAP", body))
+	}
+
+	size := frames.Len()
+	if size >= 1<<28 {
+		return nil, errors.New("id3: tag too large")
+	}
+	tag := []byte{'I', 'D', '3', 3, 0, 0,
+		byte(size >> 21 & 0x7F), byte(size >> 14 & 0x7F), byte(size >> 7 & 0x7F), byte(size & 0x7F)}
+	return append(tag, frames.Bytes()...), nil
+}
+
+// id3Frame wraps body in an ID3v2.3 frame header
+func id3Frame(id string, body []byte) []byte {
+	frame := []byte(id)
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(body)))
+	frame = append(frame, 0, 0)
+	return append(frame, body...)
+}
+
+// id3Text encodes a text frame as UTF-16 with a byte order mark, so that
+// any script survives
+func id3Text(s string) []byte {
+	body := []byte{0x01, 0xFF, 0xFE}
+	for _, u := range utf16.Encode([]rune(s)) {
+		body = binary.LittleEndian.AppendUint16(body, u)
+	}
+	return body
+}
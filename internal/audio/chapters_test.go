@@ -0,0 +1,104 @@
+package audio
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// mp3File builds n silent MPEG-1 Layer III frames at 128 kbps and 44.1 kHz,
+// wrapped in an ID3v2 tag and an ID3v1 tag
+func mp3File(n int) (file, frames []byte) {
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xFF, 0xFB, 0x90, 0x00})
+	frames = bytes.Repeat(frame, n)
+	id3 := []byte{'I', 'D', '3', 3, 0, 0, 0, 0, 0, 4, 1, 2, 3, 4}
+	v1 := append([]byte("TAG"), make([]byte, 125)...)
+	file = append(append(append([]byte{}, id3...), frames...), v1...)
+	return file, frames
+}
+
+func TestConcatMP3(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	var want []byte
+	for i, n := range []int{40, 25} {
+		file, frames := mp3File(n)
+		p := filepath.Join(dir, string(rune('a'+i))+".mp3")
+		if err := os.WriteFile(p, file, 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+		want = append(want, frames...)
+	}
+
+	var out bytes.Buffer
+	durations, err := ConcatMP3(&out, paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("ConcatMP3 wrote %d bytes, want the %d bytes of frames only", out.Len(), len(want))
+	}
+	for i, n := range []int{40, 25} {
+		if d := float64(n) * 1152 / 44100; math.Abs(durations[i]-d) > 0.01 {
+			t.Errorf("duration %d = %v, want %v", i, durations[i], d)
+		}
+	}
+}
+
+func TestConcatMP3SkipsInfoFrame(t *testing.T) {
+	file, frames := mp3File(30)
+	// LAME writes an Info frame first: after the header and the 32 bytes
+	// of stereo side information
+	info := make([]byte, 417)
+	copy(info, []byte{0xFF, 0xFB, 0x90, 0x00})
+	copy(info[36:], "Info")
+	file = append(append(append([]byte{}, file[:14]...), info...), file[14:]...)
+
+	p, err := probeMP3(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := float64(30) * 1152 / 44100
+	if math.Abs(p.Duration-want) > 0.01 {
+		t.Errorf("probed duration = %v, want %v without the Info frame", p.Duration, want)
+	}
+
+	path := filepath.Join(t.TempDir(), "a.mp3")
+	if err := os.WriteFile(path, file, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	durations, err := ConcatMP3(&out, []string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), frames) {
+		t.Errorf("ConcatMP3 wrote %d bytes, want the %d bytes of audio frames only", out.Len(), len(frames))
+	}
+	if math.Abs(durations[0]-want) > 0.01 {
+		t.Errorf("duration = %v, want %v", durations[0], want)
+	}
+}
+
+func TestChapterTagSize(t *testing.T) {
+	chapters := []Chapter{{Title: "序章"}, {Title: "Chapter 1"}}
+	reserved, err := ChapterTag("书", chapters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chapters[0].End, chapters[1].Start, chapters[1].End = 61.5, 61.5, 3600
+	final, err := ChapterTag("书", chapters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(final) != len(reserved) {
+		t.Errorf("tag size changed with the times: %d, reserved %d", len(final), len(reserved))
+	}
+	if _, err := ChapterTag("", make([]Chapter, MaxChapters+1)); err == nil {
+		t.Error("want an error above MaxChapters")
+	}
+}
@@ -0,0 +1,37 @@
+package audio
+
+import "math"
+
+// silenceDB is reported for digital silence instead of -Inf
+const silenceDB = -96.0
+
+// levelMeter accumulates peak and RMS over a stream of samples
+type levelMeter struct {
+	peak  float64
+	sumSq float64
+	count int64
+}
+
+func (m *levelMeter) add(s float32) {
+	v := math.Abs(float64(s))
+	if v > m.peak {
+		m.peak = v
+	}
+	m.sumSq += v * v
+	m.count++
+}
+
+// levels returns peak and RMS in dBFS
+func (m *levelMeter) levels() (peak, rms float64) {
+	if m.count == 0 {
+		return silenceDB, silenceDB
+	}
+	return toDB(m.peak), toDB(math.Sqrt(m.sumSq / float64(m.count)))
+}
+
+func toDB(v float64) float64 {
+	if v <= 0 {
+		return silenceDB
+	}
+	return math.Max(20*math.Log10(v), silenceDB)
+}
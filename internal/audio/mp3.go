@@ -0,0 +1,146 @@
+package audio
+
+import (
+	"errors"
+	"io"
+)
+
+// Bitrates in kbps by [version is MPEG1][layer-1][index]
+var mp3Bitrates = [2][3][16]int{
+	{ // MPEG2 / 2.5
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256, 0},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+	},
+	{ // MPEG1
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, 0},
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 0},
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+	},
+}
+
+// Sample rates by version bits (0 = MPEG2.5, 2 = MPEG2, 3 = MPEG1)
+var mp3SampleRates = map[byte][3]int{
+	0: {11025, 12000, 8000},
+	2: {22050, 24000, 16000},
+	3: {44100, 48000, 32000},
+}
+
+// mp3Frame is a decoded MPEG audio frame header
+type mp3Frame struct {
+	mpeg1      bool
+	layer      int
+	sampleRate int
+	channels   int
+	samples    int // Samples per frame
+	length     int // Frame length in bytes
+}
+
+func parseMP3Header(h []byte) (*mp3Frame, bool) {
+	if h[0] != 0xFF || h[1]&0xE0 != 0xE0 {
+		return nil, false
+	}
+	version := (h[1] >> 3) & 0x03
+	layer := 4 - int((h[1]>>1)&0x03) // 1, 2 or 3
+	bitrateIndex := h[2] >> 4
+	rateIndex := (h[2] >> 2) & 0x03
+	padding := int((h[2] >> 1) & 0x01)
+	if version == 1 || layer == 4 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+		return nil, false
+	}
+
+	mpeg1 := 0
+	if version == 3 {
+		mpeg1 = 1
+	}
+	bitrate := mp3Bitrates[mpeg1][layer-1][bitrateIndex] * 1000
+	sampleRate := mp3SampleRates[version][rateIndex]
+
+	f := &mp3Frame{mpeg1: mpeg1 == 1, layer: layer, sampleRate: sampleRate, channels: 2}
+	if h[3]>>6 == 3 {
+		f.channels = 1
+	}
+	switch {
+	case layer == 1:
+		f.samples = 384
+		f.length = (12*bitrate/sampleRate + padding) * 4
+	case layer == 3 && mpeg1 == 0:
+		f.samples = 576
+		f.length = 72*bitrate/sampleRate + padding
+	default:
+		f.samples = 1152
+		f.length = 144*bitrate/sampleRate + padding
+	}
+	return f, f.length > 4
+}
+
+// mp3InfoFrame reports whether the frame at offset is a Xing, Info or VBRI
+// header, which encoders such as LAME write before the audio. It holds no
+// samples, and decoders meeting one inside a joined file play it as a gap.
+func mp3InfoFrame(r io.ReaderAt, offset int64, frame *mp3Frame) bool {
+	if frame.layer != 3 {
+		return false
+	}
+	// The Xing tag follows the side information
+	sideInfo := 17
+	switch {
+	case frame.mpeg1 && frame.channels == 2:
+		sideInfo = 32
+	case !frame.mpeg1 && frame.channels == 1:
+		sideInfo = 9
+	}
+	id := make([]byte, 4)
+	if _, err := r.ReadAt(id, offset+4+int64(sideInfo)); err == nil && (string(id) == "Xing" || string(id) == "Info") {
+		return true
+	}
+	_, err := r.ReadAt(id, offset+36)
+	return err == nil && string(id) == "VBRI"
+}
+
+// probeMP3 walks every frame header, which gives exact durations for
+// both constant and variable bitrate files
+func probeMP3(r io.ReaderAt, size int64) (*Info, error) {
+	offset := int64(0)
+	head := make([]byte, 10)
+	if _, err := r.ReadAt(head, 0); err == nil && string(head[0:3]) == "ID3" {
+		// Syncsafe tag size, plus footer when flagged
+		tagSize := int64(head[6])<<21 | int64(head[7])<<14 | int64(head[8])<<7 | int64(head[9])
+		offset = 10 + tagSize
+		if head[5]&0x10 != 0 {
+			offset += 10
+		}
+	}
+
+	var info *Info
+	var totalSamples int64
+	h := make([]byte, 4)
+	for offset+4 <= size {
+		if _, err := r.ReadAt(h, offset); err != nil {
+			break
+		}
+		frame, ok := parseMP3Header(h)
+		if !ok {
+			// Resync byte by byte, e.g. after junk or a trailing tag
+			if info != nil && string(h[0:3]) == "TAG" {
+				break
+			}
+			offset++
+			continue
+		}
+		if info == nil {
+			info = &Info{Format: "mp3", SampleRate: frame.sampleRate, Channels: frame.channels}
+			if mp3InfoFrame(r, offset, frame) {
+				offset += int64(frame.length)
+				continue
+			}
+		}
+		totalSamples += int64(frame.samples)
+		offset += int64(frame.length)
+	}
+
+	if info == nil {
+		return nil, errors.New("mp3: no audio frames")
+	}
+	info.Duration = float64(totalSamples) / float64(info.SampleRate)
+	return info, nil
+}
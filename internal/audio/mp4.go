@@ -0,0 +1,124 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// mp4Box is a box header inside an MP4/M4A file
+type mp4Box struct {
+	kind   string
+	offset int64 // Start of the box body
+	size   int64 // Size of the body
+}
+
+// mp4Boxes lists the child boxes within [start, end)
+func mp4Boxes(r io.ReaderAt, start, end int64) []mp4Box {
+	var boxes []mp4Box
+	h := make([]byte, 16)
+	for pos := start; pos+8 <= end; {
+		if _, err := r.ReadAt(h[:8], pos); err != nil {
+			break
+		}
+		size := int64(binary.BigEndian.Uint32(h[0:4]))
+		kind := string(h[4:8])
+		header := int64(8)
+		switch size {
+		case 0: // Extends to the end
+			size = end - pos
+		case 1: // 64-bit size follows
+			if _, err := r.ReadAt(h[8:16], pos+8); err != nil {
+				return boxes
+			}
+			size = int64(binary.BigEndian.Uint64(h[8:16]))
+			header = 16
+		}
+		if size < header || pos+size > end {
+			break
+		}
+		boxes = append(boxes, mp4Box{kind: kind, offset: pos + header, size: size - header})
+		pos += size
+	}
+	return boxes
+}
+
+func findMP4Box(boxes []mp4Box, kind string) (mp4Box, bool) {
+	for _, b := range boxes {
+		if b.kind == kind {
+			return b, true
+		}
+	}
+	return mp4Box{}, false
+}
+
+// mp4Path descends through nested boxes, e.g. "mdia", "minf", "stbl"
+func mp4Path(r io.ReaderAt, parent mp4Box, kinds ...string) (mp4Box, bool) {
+	box := parent
+	for _, kind := range kinds {
+		var ok bool
+		box, ok = findMP4Box(mp4Boxes(r, box.offset, box.offset+box.size), kind)
+		if !ok {
+			return mp4Box{}, false
+		}
+	}
+	return box, true
+}
+
+// probeMP4 reads the duration from mvhd and the audio format from the
+// sample description of the first sound track
+func probeMP4(r io.ReaderAt, size int64) (*Info, error) {
+	moov, ok := findMP4Box(mp4Boxes(r, 0, size), "moov")
+	if !ok {
+		return nil, errors.New("m4a: no moov box")
+	}
+
+	info := &Info{Format: "m4a"}
+
+	if mvhd, ok := mp4Path(r, moov, "mvhd"); ok {
+		buf := make([]byte, min(mvhd.size, 32))
+		if _, err := r.ReadAt(buf, mvhd.offset); err == nil && len(buf) >= 20 {
+			if buf[0] == 1 && len(buf) >= 32 {
+				timescale := binary.BigEndian.Uint32(buf[20:24])
+				duration := binary.BigEndian.Uint64(buf[24:32])
+				if timescale > 0 {
+					info.Duration = float64(duration) / float64(timescale)
+				}
+			} else {
+				timescale := binary.BigEndian.Uint32(buf[12:16])
+				duration := binary.BigEndian.Uint32(buf[16:20])
+				if timescale > 0 {
+					info.Duration = float64(duration) / float64(timescale)
+				}
+			}
+		}
+	}
+
+	for _, trak := range mp4Boxes(r, moov.offset, moov.offset+moov.size) {
+		if trak.kind != "trak" {
+			continue
+		}
+		hdlr, ok := mp4Path(r, trak, "mdia", "hdlr")
+		if !ok {
+			continue
+		}
+		handler := make([]byte, 12)
+		if _, err := r.ReadAt(handler, hdlr.offset); err != nil || string(handler[8:12]) != "soun" {
+			continue
+		}
+		stsd, ok := mp4Path(r, trak, "mdia", "minf", "stbl", "stsd")
+		if !ok {
+			continue
+		}
+		// Full box header (4) + entry count (4), then the first AudioSampleEntry
+		entry := make([]byte, 36)
+		if _, err := r.ReadAt(entry, stsd.offset+8); err != nil {
+			continue
+		}
+		info.Channels = int(binary.BigEndian.Uint16(entry[24:26]))
+		info.SampleRate = int(binary.BigEndian.Uint32(entry[32:36]) >> 16)
+		return info, nil
+	}
+
+	return nil, errors.New("m4a: no audio track")
+}
@@ -0,0 +1,51 @@
+// Package audio inspects and processes audio files in pure Go.
+// WAV is fully decoded; MP3 and M4A are only probed from their headers.
+package audio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// ErrUnsupportedFormat is returned for files that are not WAV, MP3 or M4A
+var ErrUnsupportedFormat = errors.New("unsupported audio format")
+
+// Info describes an audio file
+type Info struct {
+	Format        string   `json:"format"`   // wav, mp3, m4a
+	Duration      float64  `json:"duration"` // Seconds
+	SampleRate    int      `json:"sample_rate"`
+	Channels      int      `json:"channels"`
+	BitsPerSample int      `json:"bits_per_sample,omitempty"` // WAV only
+	Size          int64    `json:"size"`                      // Bytes
+	PeakDB        *float64 `json:"peak_db,omitempty"`         // dBFS, WAV only
+	RMSDB         *float64 `json:"rms_db,omitempty"`          // dBFS, WAV only
+}
+
+// Probe detects the format of r and reads its properties. Levels are
+// only measured for WAV, other formats would need a full decoder.
+func Probe(r io.ReaderAt, size int64) (*Info, error) {
+	head := make([]byte, 12)
+	n, _ := r.ReadAt(head, 0)
+	head = head[:n]
+
+	var info *Info
+	var err error
+	switch {
+	case len(head) >= 12 && bytes.Equal(head[0:4], []byte("RIFF")) && bytes.Equal(head[8:12], []byte("WAVE")):
+		info, err = probeWAV(r, size)
+	case len(head) >= 8 && bytes.Equal(head[4:8], []byte("ftyp")):
+		info, err = probeMP4(r, size)
+	case len(head) >= 3 && bytes.Equal(head[0:3], []byte("ID3")),
+		len(head) >= 2 && head[0] == 0xFF && head[1]&0xE0 == 0xE0:
+		info, err = probeMP3(r, size)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	info.Size = size
+	return info, nil
+}
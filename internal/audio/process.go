@@ -0,0 +1,61 @@
+package audio
+
+import "math"
+
+// Defaults for StripSilence
+const (
+	DefaultSilenceThresholdDB = -45.0
+	DefaultSilencePadding     = 0.15 // Seconds of silence kept at each edge
+	silenceWindow             = 0.01 // Seconds per analysis window
+)
+
+// Mono downmixes pcm to a single channel by averaging
+func (p *PCM) Mono() *PCM {
+	if p.Channels == 1 {
+		return p
+	}
+	frames := p.Frames()
+	mono := &PCM{SampleRate: p.SampleRate, Channels: 1, Samples: make([]float32, frames)}
+	for i := 0; i < frames; i++ {
+		var sum float32
+		for ch := 0; ch < p.Channels; ch++ {
+			sum += p.Samples[i*p.Channels+ch]
+		}
+		mono.Samples[i] = sum / float32(p.Channels)
+	}
+	return mono
+}
+
+// StripSilence removes leading and trailing audio quieter than thresholdDB,
+// keeping padding seconds at each edge. It returns nil if everything is silent.
+func (p *PCM) StripSilence(thresholdDB, padding float64) *PCM {
+	window := max(int(silenceWindow*float64(p.SampleRate)), 1) * p.Channels
+	threshold := math.Pow(10, thresholdDB/20)
+
+	loud := func(start int) bool {
+		end := min(start+window, len(p.Samples))
+		var sumSq float64
+		for _, s := range p.Samples[start:end] {
+			sumSq += float64(s) * float64(s)
+		}
+		return math.Sqrt(sumSq/float64(end-start)) >= threshold
+	}
+
+	first, last := -1, -1
+	for i := 0; i < len(p.Samples); i += window {
+		if loud(i) {
+			if first < 0 {
+				first = i
+			}
+			last = min(i+window, len(p.Samples))
+		}
+	}
+	if first < 0 {
+		return nil
+	}
+
+	pad := int(padding*float64(p.SampleRate)) * p.Channels
+	first = max(first-pad, 0)
+	last = min(last+pad, len(p.Samples))
+	return &PCM{SampleRate: p.SampleRate, Channels: p.Channels, Samples: p.Samples[first:last]}
+}
@@ -0,0 +1,312 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// WAV format tags
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xFFFE
+)
+
+// Most channels accepted, which bounds the size of a frame
+const maxWAVChannels = 32
+
+// wavFormat describes the sample stream of a WAV file
+type wavFormat struct {
+	audioFormat   uint16
+	channels      int
+	sampleRate    int
+	bitsPerSample int
+	blockAlign    int
+	dataOffset    int64
+	dataSize      int64
+}
+
+func (f *wavFormat) frameSize() int {
+	return f.channels * f.bitsPerSample / 8
+}
+
+func (f *wavFormat) frames() int64 {
+	return f.dataSize / int64(f.frameSize())
+}
+
+// validate rejects formats whose frames cannot be decoded, so that frame
+// arithmetic never divides by zero
+func (f *wavFormat) validate() error {
+	if f.channels == 0 || f.channels > maxWAVChannels || f.sampleRate == 0 {
+		return errors.New("wav: invalid format")
+	}
+	switch f.audioFormat {
+	case wavFormatPCM:
+		if f.bitsPerSample != 8 && f.bitsPerSample != 16 && f.bitsPerSample != 24 && f.bitsPerSample != 32 {
+			return errors.New("wav: unsupported PCM sample size")
+		}
+	case wavFormatFloat:
+		if f.bitsPerSample != 32 && f.bitsPerSample != 64 {
+			return errors.New("wav: unsupported float sample size")
+		}
+	default:
+		return errors.New("wav: only PCM and float samples are supported")
+	}
+	if f.frameSize() <= 0 || f.blockAlign != f.frameSize() {
+		return errors.New("wav: block alignment does not match the sample format")
+	}
+	return nil
+}
+
+// readWAVFormat walks the RIFF chunks up to the data chunk
+func readWAVFormat(r io.ReaderAt, size int64) (*wavFormat, error) {
+	var f wavFormat
+	var haveFmt bool
+	offset := int64(12)
+	chunk := make([]byte, 8)
+	for offset+8 <= size {
+		if _, err := r.ReadAt(chunk, offset); err != nil {
+			return nil, err
+		}
+		id := string(chunk[0:4])
+		chunkSize := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+		body := offset + 8
+
+		switch id {
+		case "fmt ":
+			if chunkSize < 16 {
+				return nil, errors.New("wav: fmt chunk too short")
+			}
+			buf := make([]byte, min(chunkSize, 40))
+			if _, err := r.ReadAt(buf, body); err != nil {
+				return nil, err
+			}
+			f.audioFormat = binary.LittleEndian.Uint16(buf[0:2])
+			f.channels = int(binary.LittleEndian.Uint16(buf[2:4]))
+			f.sampleRate = int(binary.LittleEndian.Uint32(buf[4:8]))
+			f.blockAlign = int(binary.LittleEndian.Uint16(buf[12:14]))
+			f.bitsPerSample = int(binary.LittleEndian.Uint16(buf[14:16]))
+			// WAVE_FORMAT_EXTENSIBLE keeps the real format in the sub-format GUID
+			if f.audioFormat == wavFormatExtensible && len(buf) >= 26 {
+				f.audioFormat = binary.LittleEndian.Uint16(buf[24:26])
+			}
+			haveFmt = true
+		case "data":
+			if !haveFmt {
+				return nil, errors.New("wav: data chunk before fmt chunk")
+			}
+			f.dataOffset = body
+			// Streamed files may leave the size unset
+			if chunkSize == 0 || body+chunkSize > size {
+				chunkSize = size - body
+			}
+			f.dataSize = chunkSize
+			if err := f.validate(); err != nil {
+				return nil, err
+			}
+			return &f, nil
+		}
+
+		// Chunks are padded to an even size
+		offset = body + chunkSize + chunkSize%2
+	}
+	return nil, errors.New("wav: no data chunk")
+}
+
+// sampleDecoder converts one encoded sample to [-1, 1]
+func (f *wavFormat) sampleDecoder() (func(b []byte) float32, error) {
+	switch {
+	case f.audioFormat == wavFormatPCM && f.bitsPerSample == 8:
+		return func(b []byte) float32 { return (float32(b[0]) - 128) / 128 }, nil
+	case f.audioFormat == wavFormatPCM && f.bitsPerSample == 16:
+		return func(b []byte) float32 {
+			return float32(int16(binary.LittleEndian.Uint16(b))) / 32768
+		}, nil
+	case f.audioFormat == wavFormatPCM && f.bitsPerSample == 24:
+		return func(b []byte) float32 {
+			v := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+			return float32(v) / 8388608
+		}, nil
+	case f.audioFormat == wavFormatPCM && f.bitsPerSample == 32:
+		return func(b []byte) float32 {
+			return float32(float64(int32(binary.LittleEndian.Uint32(b))) / 2147483648)
+		}, nil
+	case f.audioFormat == wavFormatFloat && f.bitsPerSample == 32:
+		return func(b []byte) float32 {
+			return math.Float32frombits(binary.LittleEndian.Uint32(b))
+		}, nil
+	case f.audioFormat == wavFormatFloat && f.bitsPerSample == 64:
+		return func(b []byte) float32 {
+			return float32(math.Float64frombits(binary.LittleEndian.Uint64(b)))
+		}, nil
+	}
+	return nil, errors.New("wav: unsupported sample format")
+}
+
+// eachSample streams the samples of frames [from, to) through fn
+func (f *wavFormat) eachSample(r io.ReaderAt, from, to int64, fn func(s float32)) error {
+	decode, err := f.sampleDecoder()
+	if err != nil {
+		return err
+	}
+	sampleSize := f.bitsPerSample / 8
+	frameSize := int64(f.frameSize())
+
+	const framesPerRead = 8192
+	buf := make([]byte, framesPerRead*frameSize)
+	for pos := from; pos < to; pos += framesPerRead {
+		n := min(framesPerRead, to-pos)
+		chunk := buf[:n*frameSize]
+		read, err := r.ReadAt(chunk, f.dataOffset+pos*frameSize)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		chunk = chunk[:int64(read)/frameSize*frameSize]
+		for i := 0; i+sampleSize <= len(chunk); i += sampleSize {
+			fn(decode(chunk[i : i+sampleSize]))
+		}
+		if int64(read) < n*frameSize {
+			break
+		}
+	}
+	return nil
+}
+
+func probeWAV(r io.ReaderAt, size int64) (*Info, error) {
+	f, err := readWAVFormat(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &Info{
+		Format:        "wav",
+		SampleRate:    f.sampleRate,
+		Channels:      f.channels,
+		BitsPerSample: f.bitsPerSample,
+		Duration:      float64(f.frames()) / float64(f.sampleRate),
+	}
+
+	var meter levelMeter
+	if err := f.eachSample(r, 0, f.frames(), meter.add); err != nil {
+		return nil, err
+	}
+	peak, rms := meter.levels()
+	info.PeakDB, info.RMSDB = &peak, &rms
+	return info, nil
+}
+
+// PCM holds decoded audio as interleaved samples in [-1, 1]
+type PCM struct {
+	SampleRate int
+	Channels   int
+	Samples    []float32
+}
+
+// Frames returns the number of sample frames
+func (p *PCM) Frames() int {
+	return len(p.Samples) / p.Channels
+}
+
+// Duration returns the length in seconds
+func (p *PCM) Duration() float64 {
+	return float64(p.Frames()) / float64(p.SampleRate)
+}
+
+// wavRange reads the format of a WAV file and the frames [from, to) between
+// start and end seconds. end <= 0 means up to the end of the file.
+func wavRange(r io.ReaderAt, size int64, start, end float64) (f *wavFormat, from, to int64, err error) {
+	head := make([]byte, 12)
+	if _, err := r.ReadAt(head, 0); err != nil {
+		return nil, 0, 0, err
+	}
+	if !bytes.Equal(head[0:4], []byte("RIFF")) || !bytes.Equal(head[8:12], []byte("WAVE")) {
+		return nil, 0, 0, ErrUnsupportedFormat
+	}
+
+	f, err = readWAVFormat(r, size)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	total := f.frames()
+	from = int64(math.Max(start, 0) * float64(f.sampleRate))
+	to = total
+	if end > 0 {
+		to = min(int64(end*float64(f.sampleRate)), total)
+	}
+	if from >= to {
+		return nil, 0, 0, errors.New("wav: empty range")
+	}
+	return f, from, to, nil
+}
+
+// WAVRangeDuration returns the seconds DecodeWAV would decode for the same
+// arguments, reading only the header
+func WAVRangeDuration(r io.ReaderAt, size int64, start, end float64) (float64, error) {
+	f, from, to, err := wavRange(r, size, start, end)
+	if err != nil {
+		return 0, err
+	}
+	return float64(to-from) / float64(f.sampleRate), nil
+}
+
+// DecodeWAV decodes the part of a WAV file between start and end seconds.
+// end <= 0 means up to the end of the file.
+func DecodeWAV(r io.ReaderAt, size int64, start, end float64) (*PCM, error) {
+	f, from, to, err := wavRange(r, size, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	pcm := &PCM{
+		SampleRate: f.sampleRate,
+		Channels:   f.channels,
+		Samples:    make([]float32, 0, (to-from)*int64(f.channels)),
+	}
+	err = f.eachSample(r, from, to, func(s float32) {
+		pcm.Samples = append(pcm.Samples, s)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pcm, nil
+}
+
+// EncodeWAV writes pcm as a 16-bit PCM WAV file
+func EncodeWAV(w io.Writer, pcm *PCM) error {
+	dataSize := uint32(len(pcm.Samples) * 2)
+	header := make([]byte, 44)
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], 36+dataSize)
+	copy(header[8:12], "WAVE")
+	copy(header[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:20], 16)
+	binary.LittleEndian.PutUint16(header[20:22], wavFormatPCM)
+	binary.LittleEndian.PutUint16(header[22:24], uint16(pcm.Channels))
+	binary.LittleEndian.PutUint32(header[24:28], uint32(pcm.SampleRate))
+	binary.LittleEndian.PutUint32(header[28:32], uint32(pcm.SampleRate*pcm.Channels*2))
+	binary.LittleEndian.PutUint16(header[32:34], uint16(pcm.Channels*2))
+	binary.LittleEndian.PutUint16(header[34:36], 16)
+	copy(header[36:40], "data")
+	binary.LittleEndian.PutUint32(header[40:44], dataSize)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 0, 64*1024)
+	for _, s := range pcm.Samples {
+		s = max(-1, min(1, s))
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(int16(math.Round(float64(s)*32767))))
+		if len(buf) == cap(buf) {
+			if _, err := w.Write(buf); err != nil {
+				return err
+			}
+			buf = buf[:0]
+		}
+	}
+	_, err := w.Write(buf)
+	return err
+}
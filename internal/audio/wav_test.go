@@ -0,0 +1,77 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// wavHeader builds a WAV file with the given fmt fields and data bytes
+func wavHeader(format, channels, bits, blockAlign uint16, data []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(36+len(data)))
+	b.WriteString("WAVEfmt ")
+	binary.Write(&b, binary.LittleEndian, uint32(16))
+	binary.Write(&b, binary.LittleEndian, format)
+	binary.Write(&b, binary.LittleEndian, channels)
+	binary.Write(&b, binary.LittleEndian, uint32(16000))
+	binary.Write(&b, binary.LittleEndian, uint32(16000)*uint32(blockAlign))
+	binary.Write(&b, binary.LittleEndian, blockAlign)
+	binary.Write(&b, binary.LittleEndian, bits)
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(len(data)))
+	b.Write(data)
+	return b.Bytes()
+}
+
+func TestProbeWAVFormats(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   []byte
+		wantOK bool
+	}{
+		{"16-bit mono", wavHeader(wavFormatPCM, 1, 16, 2, make([]byte, 3200)), true},
+		{"24-bit stereo", wavHeader(wavFormatPCM, 2, 24, 6, make([]byte, 600)), true},
+		{"64-bit float", wavHeader(wavFormatFloat, 1, 64, 8, make([]byte, 800)), true},
+		{"4-bit PCM", wavHeader(wavFormatPCM, 1, 4, 0, make([]byte, 8)), false},
+		{"12-bit PCM", wavHeader(wavFormatPCM, 1, 12, 2, make([]byte, 8)), false},
+		{"16-bit float", wavHeader(wavFormatFloat, 1, 16, 2, make([]byte, 8)), false},
+		{"block align mismatch", wavHeader(wavFormatPCM, 2, 16, 2, make([]byte, 8)), false},
+		{"no channels", wavHeader(wavFormatPCM, 0, 16, 0, make([]byte, 8)), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Probe(bytes.NewReader(tt.file), int64(len(tt.file)))
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("Probe() error = %v, want ok %v", err, tt.wantOK)
+			}
+			_, err = DecodeWAV(bytes.NewReader(tt.file), int64(len(tt.file)), 0, 0)
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("DecodeWAV() error = %v, want ok %v", err, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestWAVRangeDuration(t *testing.T) {
+	// 10 seconds of 16 kHz 16-bit mono
+	file := wavHeader(wavFormatPCM, 1, 16, 2, make([]byte, 16000*2*10))
+	tests := []struct {
+		start, end, want float64
+	}{
+		{0, 0, 10},
+		{2, 0, 8},
+		{2, 5, 3},
+		{4, 60, 6},
+	}
+	for _, tt := range tests {
+		got, err := WAVRangeDuration(bytes.NewReader(file), int64(len(file)), tt.start, tt.end)
+		if err != nil || got != tt.want {
+			t.Errorf("WAVRangeDuration(%v, %v) = %v, %v; want %v", tt.start, tt.end, got, err, tt.want)
+		}
+	}
+	if _, err := WAVRangeDuration(bytes.NewReader(file), int64(len(file)), 20, 0); err == nil {
+		t.Error("range past the end: want an error")
+	}
+}
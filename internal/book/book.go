@@ -0,0 +1,141 @@
+// Package book splits Markdown, plain text and EPUB documents into chapters
+// and paragraphs, ready to be synthesized as an audiobook.
+package book
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrUnsupportedFormat is returned for files that are not Markdown, plain
+// text or EPUB
+var ErrUnsupportedFormat = errors.New("unsupported document format")
+
+// ErrNoText is returned for documents without any readable text
+var ErrNoText = errors.New("document has no text")
+
+// Book is a parsed document
+type Book struct {
+	Title    string    `json:"title"`
+	Format   string    `json:"format"` // markdown, text, epub
+	Chapters []Chapter `json:"chapters"`
+}
+
+// Chapter is a titled run of paragraphs
+type Chapter struct {
+	Title      string   `json:"title"`
+	Paragraphs []string `json:"paragraphs"`
+}
+
+// Chars counts the characters of the chapter's paragraphs
+func (c *Chapter) Chars() int {
+	n := 0
+	for _, p := range c.Paragraphs {
+		n += utf8.RuneCountInString(p)
+	}
+	return n
+}
+
+// Parse reads a document by the extension of filename. Paragraphs longer
+// than maxParagraph characters are split between sentences; 0 keeps them
+// whole.
+func Parse(filename string, data []byte, maxParagraph int) (*Book, error) {
+	var b *Book
+	var err error
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".md", ".markdown":
+		b = parseMarkdown(string(data))
+	case ".txt":
+		b = parseText(string(data))
+	case ".epub":
+		b, err = parseEPUB(data)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if b.Title == "" {
+		b.Title = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	}
+	chapters := b.Chapters[:0]
+	for _, c := range b.Chapters {
+		var paragraphs []string
+		for _, p := range c.Paragraphs {
+			paragraphs = append(paragraphs, splitParagraph(p, maxParagraph)...)
+		}
+		if len(paragraphs) == 0 {
+			continue
+		}
+		c.Paragraphs = paragraphs
+		if c.Title == "" {
+			c.Title = b.Title
+		}
+		chapters = append(chapters, c)
+	}
+	if len(chapters) == 0 {
+		return nil, ErrNoText
+	}
+	b.Chapters = chapters
+	return b, nil
+}
+
+// Sentence endings a long paragraph may be split after
+const sentenceEnds = ".!?;。！？；…"
+
+// splitParagraph cuts p into pieces of at most max characters, preferring
+// to cut after the end of a sentence
+func splitParagraph(p string, max int) []string {
+	runes := []rune(strings.TrimSpace(p))
+	if len(runes) == 0 {
+		return nil
+	}
+	var pieces []string
+	for max > 0 && len(runes) > max {
+		cut := max
+		for i := max - 1; i > max/2; i-- {
+			if strings.ContainsRune(sentenceEnds, runes[i]) {
+				cut = i + 1
+				break
+			}
+		}
+		if piece := strings.TrimSpace(string(runes[:cut])); piece != "" {
+			pieces = append(pieces, piece)
+		}
+		runes = []rune(strings.TrimSpace(string(runes[cut:])))
+	}
+	if len(runes) > 0 {
+		pieces = append(pieces, string(runes))
+	}
+	return pieces
+}
+
+// joinLines joins the lines of a paragraph, with a space only between
+// words of scripts that use them
+func joinLines(lines []string) string {
+	var sb strings.Builder
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if sb.Len() > 0 {
+			last, _ := utf8.DecodeLastRuneInString(sb.String())
+			first, _ := utf8.DecodeRuneInString(line)
+			if !isCJK(last) && !isCJK(first) {
+				sb.WriteByte(' ')
+			}
+		}
+		sb.WriteString(line)
+	}
+	return sb.String()
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		(r >= 0x3000 && r <= 0x303F) || (r >= 0xFF00 && r <= 0xFFEF)
+}
@@ -0,0 +1,185 @@
+package book
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Most bytes read from one file inside an EPUB
+const maxEPUBEntry = 16 << 20
+
+// Elements whose text is a paragraph of its own
+var epubBlocks = map[string]bool{
+	"p": true, "div": true, "li": true, "blockquote": true, "pre": true, "dt": true, "dd": true,
+	"td": true, "th": true, "caption": true, "figcaption": true, "section": true, "article": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "br": true, "hr": true,
+}
+
+// Elements whose text is not read aloud
+var epubSkipped = map[string]bool{"script": true, "style": true, "head": true, "rt": true, "rp": true}
+
+// epubPackage is the part of the OPF package document used for reading order
+type epubPackage struct {
+	Title    []string `xml:"metadata>title"`
+	Manifest []struct {
+		ID         string `xml:"id,attr"`
+		Href       string `xml:"href,attr"`
+		MediaType  string `xml:"media-type,attr"`
+		Properties string `xml:"properties,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef  string `xml:"idref,attr"`
+		Linear string `xml:"linear,attr"`
+	} `xml:"spine>itemref"`
+}
+
+// parseEPUB reads the documents of an EPUB in reading order. Every document
+// with text is a chapter, titled by its first heading.
+func parseEPUB(data []byte) (*Book, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("epub: %w", err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	read := func(name string) ([]byte, error) {
+		f, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("epub: %s is missing", name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(io.LimitReader(rc, maxEPUBEntry))
+	}
+
+	container, err := read("META-INF/container.xml")
+	if err != nil {
+		return nil, err
+	}
+	var rootfiles struct {
+		Paths []string `xml:"rootfiles>rootfile>full-path,attr"`
+	}
+	if err := xml.Unmarshal(container, &rootfiles); err != nil || len(rootfiles.Paths) == 0 {
+		return nil, errors.New("epub: no package document")
+	}
+	opfPath := rootfiles.Paths[0]
+	opf, err := read(opfPath)
+	if err != nil {
+		return nil, err
+	}
+	var pkg epubPackage
+	if err := xml.Unmarshal(opf, &pkg); err != nil {
+		return nil, fmt.Errorf("epub: %w", err)
+	}
+
+	b := &Book{Format: "epub"}
+	if len(pkg.Title) > 0 {
+		b.Title = strings.TrimSpace(pkg.Title[0])
+	}
+	hrefs := map[string]string{}
+	for _, item := range pkg.Manifest {
+		// The navigation document repeats the table of contents
+		if strings.Contains(item.Properties, "nav") || !strings.Contains(item.MediaType, "html") {
+			continue
+		}
+		href, err := url.PathUnescape(item.Href)
+		if err != nil {
+			href = item.Href
+		}
+		hrefs[item.ID] = path.Join(path.Dir(opfPath), href)
+	}
+	for _, ref := range pkg.Spine {
+		name, ok := hrefs[ref.IDRef]
+		if !ok || ref.Linear == "no" {
+			continue
+		}
+		doc, err := read(name)
+		if err != nil {
+			return nil, err
+		}
+		if chapter := parseXHTML(doc); len(chapter.Paragraphs) > 0 {
+			b.Chapters = append(b.Chapters, chapter)
+		}
+	}
+	return b, nil
+}
+
+// parseXHTML collects the paragraphs of a content document. Its first
+// heading, or else its title, names the chapter.
+func parseXHTML(doc []byte) Chapter {
+	d := xml.NewDecoder(bytes.NewReader(doc))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	var chapter Chapter
+	var title string
+	var text strings.Builder
+	skipped, heading, inTitle := 0, false, false
+	flush := func() {
+		p := strings.Join(strings.Fields(text.String()), " ")
+		text.Reset()
+		if p == "" {
+			return
+		}
+		if heading && chapter.Title == "" && len(chapter.Paragraphs) == 0 {
+			chapter.Title = p
+			return
+		}
+		chapter.Paragraphs = append(chapter.Paragraphs, p)
+	}
+
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			switch {
+			case name == "title":
+				inTitle = true
+			case epubSkipped[name]:
+				skipped++
+			case epubBlocks[name]:
+				flush()
+				heading = len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6'
+			}
+		case xml.EndElement:
+			name := strings.ToLower(t.Name.Local)
+			switch {
+			case name == "title":
+				inTitle = false
+			case epubSkipped[name]:
+				skipped = max(skipped-1, 0)
+			case epubBlocks[name]:
+				flush()
+				heading = false
+			}
+		case xml.CharData:
+			if inTitle {
+				title += string(t)
+			} else if skipped == 0 {
+				text.Write(t)
+			}
+		}
+	}
+	flush()
+	if chapter.Title == "" {
+		chapter.Title = strings.TrimSpace(title)
+	}
+	return chapter
+}
@@ -0,0 +1,226 @@
+package book
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Markdown headings, and the syntax stripped from the text read aloud
+var (
+	mdHeading  = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdFence    = regexp.MustCompile("^\\s*(```|~~~)")
+	mdRule     = regexp.MustCompile(`^\s*([-*_]\s*){3,}$`)
+	mdListItem = regexp.MustCompile(`^\s*([-*+]|\d+[.)])\s+`)
+	mdQuote    = regexp.MustCompile(`^\s*>\s?`)
+	mdImage    = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink     = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	mdHTML     = regexp.MustCompile(`<[^>]+>`)
+	mdEmphasis = regexp.MustCompile("(\\*{1,3}|_{2,3}|`+|~~)")
+)
+
+// Lines of plain text that open a chapter, such as "Chapter 12", "PART IV:
+// The Return" or "第三章 归来". The heading is the whole line, optionally
+// followed by a separator and a short title.
+var (
+	enChapter = regexp.MustCompile(`(?i)^(?:chapter|part|book)\s+([0-9]+|[a-z]+(?:-[a-z]+)?)(?:\s*[:.\-–—]\s*(.*))?$`)
+	zhChapter = regexp.MustCompile(`^第[0-9零一二三四五六七八九十百千两]+[章节回卷部篇](?:[\s:：、]+(.*))?$`)
+	romanNum  = regexp.MustCompile(`(?i)^m{0,3}(cm|cd|d?c{0,3})(xc|xl|l?x{0,3})(ix|iv|v?i{0,3})$`)
+)
+
+// Number words taken after Chapter, Part or Book; compounds such as
+// twenty-one join two of them
+var numberWords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`one two three four five six seven eight nine ten eleven twelve
+		thirteen fourteen fifteen sixteen seventeen eighteen nineteen twenty thirty forty fifty
+		sixty seventy eighty ninety hundred first second third fourth fifth sixth seventh eighth
+		ninth tenth eleventh twelfth thirteenth fourteenth fifteenth sixteenth seventeenth
+		eighteenth nineteenth twentieth thirtieth fortieth fiftieth last final`) {
+		numberWords[w] = true
+	}
+}
+
+// Longest title after a chapter number
+const maxHeadingTitleLen = 50
+
+// isChapterHeading reports whether a trimmed line of plain text opens a
+// chapter
+func isChapterHeading(line string) bool {
+	if m := zhChapter.FindStringSubmatch(line); m != nil {
+		return utf8.RuneCountInString(m[1]) <= maxHeadingTitleLen
+	}
+	m := enChapter.FindStringSubmatch(line)
+	if m == nil || utf8.RuneCountInString(m[2]) > maxHeadingTitleLen {
+		return false
+	}
+	number := strings.ToLower(m[1])
+	if number[0] >= '0' && number[0] <= '9' || romanNum.MatchString(number) {
+		return true
+	}
+	for _, w := range strings.Split(number, "-") {
+		if !numberWords[w] {
+			return false
+		}
+	}
+	return true
+}
+
+// Quotes that may follow the end of a sentence
+const closingQuotes = `"'”’」』)）`
+
+// Longest line taken for a chapter heading in plain text
+const maxHeadingLen = 60
+
+// cleanMarkdown removes inline Markdown syntax from a line
+func cleanMarkdown(line string) string {
+	line = mdQuote.ReplaceAllString(line, "")
+	line = mdListItem.ReplaceAllString(line, "")
+	line = mdImage.ReplaceAllString(line, "$1")
+	line = mdLink.ReplaceAllString(line, "$1")
+	line = mdHTML.ReplaceAllString(line, "")
+	return mdEmphasis.ReplaceAllString(line, "")
+}
+
+// parseMarkdown splits Markdown at its chapter headings: the top heading
+// level, unless it is used only once, in which case it titles the book and
+// the next level splits chapters. Deeper headings are read as paragraphs.
+func parseMarkdown(src string) *Book {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+
+	// Find the heading levels outside code blocks
+	counts := map[int]int{}
+	inFence := false
+	for _, line := range lines {
+		if mdFence.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if m := mdHeading.FindStringSubmatch(line); m != nil && !inFence {
+			counts[len(m[1])]++
+		}
+	}
+	titleLevel, chapterLevel := 0, 0
+	for level := 1; level <= 6; level++ {
+		if counts[level] == 0 {
+			continue
+		}
+		if chapterLevel == 0 && titleLevel == 0 && counts[level] == 1 {
+			titleLevel = level
+			continue
+		}
+		chapterLevel = level
+		break
+	}
+	if chapterLevel == 0 {
+		// A single heading both titles the book and opens its only chapter
+		chapterLevel, titleLevel = titleLevel, 0
+	}
+
+	b := &Book{Format: "markdown"}
+	current := &Chapter{}
+	var block []string
+	flush := func() {
+		if p := joinLines(block); p != "" {
+			current.Paragraphs = append(current.Paragraphs, p)
+		}
+		block = nil
+	}
+
+	inFence = false
+	for _, line := range lines {
+		if mdFence.MatchString(line) {
+			flush()
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue // Code is not read aloud
+		}
+		if m := mdHeading.FindStringSubmatch(line); m != nil {
+			flush()
+			title := strings.TrimSpace(cleanMarkdown(m[2]))
+			switch len(m[1]) {
+			case titleLevel:
+				b.Title = title
+			case chapterLevel:
+				if counts[chapterLevel] == 1 && b.Title == "" {
+					b.Title = title
+				}
+				b.Chapters = append(b.Chapters, *current)
+				current = &Chapter{Title: title}
+			default:
+				if title != "" {
+					current.Paragraphs = append(current.Paragraphs, title)
+				}
+			}
+			continue
+		}
+		if strings.TrimSpace(line) == "" || mdRule.MatchString(line) {
+			flush()
+			continue
+		}
+		// List items and quotes are paragraphs of their own
+		if mdListItem.MatchString(line) {
+			flush()
+		}
+		block = append(block, cleanMarkdown(line))
+	}
+	flush()
+	b.Chapters = append(b.Chapters, *current)
+	return b
+}
+
+// parseText splits plain text at lines that look like chapter headings.
+// Paragraphs are separated by blank lines, unless nearly every line ends a
+// sentence: such text, common for Chinese novels, has a paragraph per line
+// while hard-wrapped text is joined up to the next blank line.
+func parseText(src string) *Book {
+	src = strings.TrimPrefix(strings.ReplaceAll(src, "\r\n", "\n"), "\ufeff")
+	lines := strings.Split(src, "\n")
+
+	ended, total := 0, 0
+	for _, line := range lines {
+		line = strings.TrimRight(strings.TrimSpace(line), closingQuotes)
+		if line == "" || isChapterHeading(line) {
+			continue
+		}
+		total++
+		if last, _ := utf8.DecodeLastRuneInString(line); strings.ContainsRune(sentenceEnds, last) {
+			ended++
+		}
+	}
+	perLine := ended*5 >= total*4
+
+	b := &Book{Format: "text"}
+	current := &Chapter{}
+	var block []string
+	flush := func() {
+		if p := joinLines(block); p != "" {
+			current.Paragraphs = append(current.Paragraphs, p)
+		}
+		block = nil
+	}
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && utf8.RuneCountInString(trimmed) <= maxHeadingLen && isChapterHeading(trimmed) {
+			flush()
+			b.Chapters = append(b.Chapters, *current)
+			current = &Chapter{Title: trimmed}
+			continue
+		}
+		if trimmed == "" {
+			flush()
+			continue
+		}
+		block = append(block, trimmed)
+		if perLine {
+			flush()
+		}
+	}
+	flush()
+	b.Chapters = append(b.Chapters, *current)
+	return b
+}
@@ -0,0 +1,123 @@
+package book
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsChapterHeading(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"Chapter 12", true},
+		{"CHAPTER XIV", true},
+		{"Part IV: The Return", true},
+		{"Book One", true},
+		{"Chapter twenty-one. A Long Night", true},
+		{"Chapter the Last", false},
+		{"第三章", true},
+		{"第十二回 风雪山神庙", true},
+		{"第一章：归来", true},
+		{"Part of me wanted to stay.", false},
+		{"Book a table for two.", false},
+		{"Part two of the plan failed.", false},
+		{"Chapter and verse, he said.", false},
+		{"Part I want to forget.", false},
+		{"第一节课我们学了拼音。", false},
+		{"He read chapter 3 twice.", false},
+	}
+	for _, tt := range tests {
+		if got := isChapterHeading(tt.line); got != tt.want {
+			t.Errorf("isChapterHeading(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseText(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []Chapter
+	}{
+		{
+			name: "wrapped paragraphs",
+			src:  "Chapter 1\n\nIt was a dark\nand stormy night.\n\nThe end came\nsoon after.\n\nChapter 2\n\nMorning.\n",
+			want: []Chapter{
+				{Title: "Chapter 1", Paragraphs: []string{"It was a dark and stormy night.", "The end came soon after."}},
+				{Title: "Chapter 2", Paragraphs: []string{"Morning."}},
+			},
+		},
+		{
+			name: "a paragraph per line with dialogue",
+			src:  "Part One\n\"Where are you going?\"\nPart of me wanted to stay.\nBook a table, she said.\nPart Two\nWe left.\n",
+			want: []Chapter{
+				{Title: "Part One", Paragraphs: []string{"\"Where are you going?\"", "Part of me wanted to stay.", "Book a table, she said."}},
+				{Title: "Part Two", Paragraphs: []string{"We left."}},
+			},
+		},
+		{
+			name: "chinese novel",
+			src:  "\ufeff第一章 风起\n天色渐暗。\n他推开了门。\n第二章 云涌\n雨下了一夜。\n",
+			want: []Chapter{
+				{Title: "第一章 风起", Paragraphs: []string{"天色渐暗。", "他推开了门。"}},
+				{Title: "第二章 云涌", Paragraphs: []string{"雨下了一夜。"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := parseText(tt.src)
+			// Text before the first heading forms a leading chapter
+			got := b.Chapters
+			if len(got) > 0 && got[0].Title == "" && len(got[0].Paragraphs) == 0 {
+				got = got[1:]
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseText() chapters = %#v\nwant %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMarkdown(t *testing.T) {
+	tests := []struct {
+		name      string
+		src       string
+		wantTitle string
+		want      []Chapter
+	}{
+		{
+			name:      "single top heading titles the book",
+			src:       "# My Book\n\n## One\n\nSome *emphasis* and a [link](http://x).\n\n## Two\n\n- item\n- other\n",
+			wantTitle: "My Book",
+			want: []Chapter{
+				{Title: "One", Paragraphs: []string{"Some emphasis and a link."}},
+				{Title: "Two", Paragraphs: []string{"item", "other"}},
+			},
+		},
+		{
+			name: "code is skipped and deeper headings are paragraphs",
+			src:  "# A\n\nText.\n\n```\ncode\n```\n\n### Aside\n\n# B\n\nMore.\n",
+			want: []Chapter{
+				{Title: "A", Paragraphs: []string{"Text.", "Aside"}},
+				{Title: "B", Paragraphs: []string{"More."}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := parseMarkdown(tt.src)
+			got := b.Chapters
+			if len(got) > 0 && got[0].Title == "" && len(got[0].Paragraphs) == 0 {
+				got = got[1:]
+			}
+			if b.Title != tt.wantTitle {
+				t.Errorf("parseMarkdown() title = %q, want %q", b.Title, tt.wantTitle)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMarkdown() chapters = %#v\nwant %#v", got, tt.want)
+			}
+		})
+	}
+}
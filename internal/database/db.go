@@ -39,14 +39,90 @@ func InitDB(dataDir string) {
 	// migrateVoiceStorage(DB)
 
 	// Auto Migrate
-	err = DB.AutoMigrate(&model.ApiKey{}, &model.Voice{}, &model.SynthesisTask{})
+	err = DB.AutoMigrate(&model.ApiKey{}, &model.Voice{}, &model.SynthesisTask{}, &model.CloneJob{}, &model.CloneSample{}, &model.DesignSession{}, &model.DesignCandidate{},
+		&model.PreviewTemplate{}, &model.VoicePreview{}, &model.PreviewJob{}, &model.PreviewJobItem{},
+		&model.VoiceComparison{}, &model.VoiceComparisonCandidate{}, &model.Preset{}, &model.PronunciationDict{},
+		&model.Project{}, &model.ProjectFolder{}, &model.Script{}, &model.TextFile{}, &model.Line{}, &model.Audiobook{}, &model.AudiobookChapter{}, &model.NormalizationRule{})
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
+	backfillVoiceExpiry(DB)
+	seedPreviewTemplates(DB)
+	backfillVoicePreviews(DB)
+	setupTaskSearch(DB)
+
 	log.Println("Database initialized successfully at", dbPath)
 }
 
+// backfillVoiceExpiry derives first use and expiry for temporary voices
+// recorded before they were tracked. A successful task counts as first use.
+func backfillVoiceExpiry(db *gorm.DB) {
+	var voices []model.Voice
+	db.Where("type IN ? AND first_used_at IS NULL AND expires_at IS NULL", []string{"cloned", "generated"}).Find(&voices)
+
+	for i := range voices {
+		v := &voices[i]
+		var task model.SynthesisTask
+		if err := db.Where("voice_id = ? AND status = ?", v.VoiceID, "success").Order("created_at asc").First(&task).Error; err == nil {
+			used := task.CreatedAt
+			v.FirstUsedAt = &used
+		}
+		v.ComputeExpiry()
+		db.Model(v).Updates(map[string]interface{}{
+			"first_used_at": v.FirstUsedAt,
+			"expires_at":    v.ExpiresAt,
+		})
+	}
+}
+
+// seedPreviewTemplates creates the built-in preview templates on first start
+func seedPreviewTemplates(db *gorm.DB) {
+	var count int64
+	db.Unscoped().Model(&model.PreviewTemplate{}).Count(&count)
+	if count > 0 {
+		return
+	}
+
+	templates := []model.PreviewTemplate{
+		{Name: "默认", Language: "", Text: "你好，欢迎来到赛博笔记的专属频道，让我们一起探索互联网的世界吧。", Emotion: "happy"},
+		{Name: "普通话", Language: "Chinese (Mandarin)", Text: "你好，欢迎来到赛博笔记的专属频道，让我们一起探索互联网的世界吧。", Emotion: "happy", IsDefault: true},
+		{Name: "粤语", Language: "Cantonese", Text: "你好，欢迎嚟到我哋嘅频道，一齐探索互联网嘅世界啦。", IsDefault: true},
+		{Name: "English", Language: "English", Text: "Hello, and welcome to the channel. Let's explore the world of the internet together.", Emotion: "happy", IsDefault: true},
+		{Name: "日本語", Language: "Japanese", Text: "こんにちは、チャンネルへようこそ。一緒にインターネットの世界を探検しましょう。", IsDefault: true},
+		{Name: "한국어", Language: "Korean", Text: "안녕하세요, 채널에 오신 것을 환영합니다. 함께 인터넷 세상을 탐험해 봐요.", IsDefault: true},
+	}
+	for i := range templates {
+		templates[i].Model = "speech-2.6-hd"
+		templates[i].Speed = 1
+		templates[i].Vol = 1
+		templates[i].SampleRate = 32000
+		templates[i].Bitrate = 128000
+		templates[i].Format = "mp3"
+	}
+	db.Create(&templates)
+}
+
+// backfillVoicePreviews records the single preview of voices created before
+// previews were stored separately
+func backfillVoicePreviews(db *gorm.DB) {
+	var voices []model.Voice
+	db.Where("preview <> '' AND voice_id NOT IN (?)", db.Model(&model.VoicePreview{}).Select("voice_id")).Find(&voices)
+
+	for _, v := range voices {
+		source := "template"
+		if strings.HasPrefix(filepath.Base(v.Preview), "demo_") {
+			source = "demo"
+		}
+		db.Create(&model.VoicePreview{
+			VoiceID: v.VoiceID,
+			Source:  source,
+			File:    v.Preview,
+			KeyID:   v.KeyID,
+		})
+	}
+}
+
 func migrateVoiceStorage(db *gorm.DB) {
 	// Ensure new directory exists
 	newDir := "generated/voices"
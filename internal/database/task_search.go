@@ -0,0 +1,59 @@
+package database
+
+import (
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// TaskSearchTable is the FTS5 index over synthesis task text, notes, tags
+// and the text of uploaded input files. Its rowid is the task ID.
+const TaskSearchTable = "task_search"
+
+// taskSearchRow selects the indexed columns of the task in new
+const taskSearchRow = `new.id, new.text, new.notes, ` + taskSearchTags + `,
+	COALESCE((SELECT text FROM text_files WHERE id = new.text_input_id), '')`
+
+// taskSearchTags indexes the tags of new as words rather than JSON
+const taskSearchTags = `CASE WHEN json_valid(new.tags) THEN (SELECT group_concat(value, ' ') FROM json_each(new.tags)) ELSE new.tags END`
+
+// setupTaskSearch creates the search index and the triggers that keep it in
+// sync with synthesis_tasks. The trigram tokenizer matches substrings, so
+// Chinese text without spaces can be searched too. The triggers are
+// recreated on every start so that they follow the current definitions.
+func setupTaskSearch(db *gorm.DB) {
+	created := !db.Migrator().HasTable(TaskSearchTable)
+
+	statements := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS ` + TaskSearchTable + ` USING fts5(text, notes, tags, file_text, tokenize='trigram')`,
+		`DROP TRIGGER IF EXISTS task_search_insert`,
+		`DROP TRIGGER IF EXISTS task_search_update`,
+		`DROP TRIGGER IF EXISTS task_search_delete`,
+		`CREATE TRIGGER task_search_insert AFTER INSERT ON synthesis_tasks BEGIN
+			INSERT INTO ` + TaskSearchTable + `(rowid, text, notes, tags, file_text) SELECT ` + taskSearchRow + `;
+		END`,
+		`CREATE TRIGGER task_search_update AFTER UPDATE OF text, notes, tags, text_input_id ON synthesis_tasks
+		WHEN old.text IS NOT new.text OR old.notes IS NOT new.notes OR old.tags IS NOT new.tags OR old.text_input_id IS NOT new.text_input_id
+		BEGIN
+			DELETE FROM ` + TaskSearchTable + ` WHERE rowid = old.id;
+			INSERT INTO ` + TaskSearchTable + `(rowid, text, notes, tags, file_text) SELECT ` + taskSearchRow + `;
+		END`,
+		`CREATE TRIGGER task_search_delete AFTER DELETE ON synthesis_tasks BEGIN
+			DELETE FROM ` + TaskSearchTable + ` WHERE rowid = old.id;
+		END`,
+	}
+	for _, stmt := range statements {
+		if err := db.Exec(stmt).Error; err != nil {
+			log.Println("Failed to set up task search:", err)
+			return
+		}
+	}
+
+	// Index the tasks recorded before the index existed
+	if created {
+		db.Exec(`INSERT INTO ` + TaskSearchTable + `(rowid, text, notes, tags, file_text)
+			SELECT id, text, notes, ` + strings.ReplaceAll(taskSearchTags, "new.", "") + `,
+				COALESCE((SELECT text FROM text_files WHERE text_files.id = text_input_id), '') FROM synthesis_tasks`)
+	}
+}
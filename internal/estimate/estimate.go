@@ -0,0 +1,134 @@
+// Package estimate predicts what a synthesis request costs and how long its
+// audio runs, before it is submitted.
+package estimate
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ModelPrice is the pay-as-you-go price of a speech model per million
+// billable characters
+type ModelPrice struct {
+	Model string
+	Price float64
+}
+
+// PriceList is what one Minimax platform charges, in its own currency. The
+// prices follow the Minimax price lists and may lag behind them.
+type PriceList struct {
+	Currency string // ISO 4217 code
+	Prices   []ModelPrice
+}
+
+// ChinaPrices are billed by api.minimaxi.com, in yuan
+var ChinaPrices = &PriceList{
+	Currency: "CNY",
+	Prices: []ModelPrice{
+		{"speech-2.6-hd", 350},
+		{"speech-2.6-turbo", 200},
+		{"speech-02-hd", 350},
+		{"speech-02-turbo", 200},
+		{"speech-01-hd", 350},
+		{"speech-01-turbo", 200},
+	},
+}
+
+// InternationalPrices are billed by api.minimax.io, in US dollars
+var InternationalPrices = &PriceList{
+	Currency: "USD",
+	Prices: []ModelPrice{
+		{"speech-2.6-hd", 100},
+		{"speech-2.6-turbo", 60},
+		{"speech-02-hd", 100},
+		{"speech-02-turbo", 60},
+		{"speech-01-hd", 100},
+		{"speech-01-turbo", 60},
+	},
+}
+
+// ForEndpoint returns the price list of the Minimax API at baseURL: the
+// international one for minimax.io hosts, the China one otherwise
+func ForEndpoint(baseURL string) *PriceList {
+	u, err := url.Parse(baseURL)
+	if err == nil && (u.Hostname() == "minimax.io" || strings.HasSuffix(u.Hostname(), ".minimax.io")) {
+		return InternationalPrices
+	}
+	return ChinaPrices
+}
+
+// BillableChars counts characters the way Minimax bills them: each Chinese
+// character counts twice, every other character, punctuation and spaces
+// included, once.
+func BillableChars(text string) int {
+	n := 0
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// Cost is the price of billable characters with a model, in the currency
+// of the list. ok is false for models without a known price.
+func (l *PriceList) Cost(model string, billable int) (cost float64, ok bool) {
+	for _, p := range l.Prices {
+		if p.Model == model {
+			return float64(billable) * p.Price / 1e6, true
+		}
+	}
+	return 0, false
+}
+
+// Average speaking rates at speed 1
+const (
+	hanPerSecond   = 4.5 // Chinese characters
+	wordsPerSecond = 2.5 // Words of other scripts, numbers included
+	sentencePause  = 0.3 // Seconds after a sentence or line
+)
+
+var pauseMarker = regexp.MustCompile(`<#([0-9.]+)#>`)
+
+// Duration estimates the seconds of audio text makes at speed, 0 meaning
+// the normal speed. Pause markers such as <#1.5#> add their length.
+func Duration(text string, speed float64) float64 {
+	if speed <= 0 {
+		speed = 1
+	}
+	pauses := 0.0
+	for _, m := range pauseMarker.FindAllStringSubmatch(text, -1) {
+		if s, err := strconv.ParseFloat(m[1], 64); err == nil {
+			pauses += s
+		}
+	}
+	text = pauseMarker.ReplaceAllString(text, " ")
+
+	han, words, sentences := 0, 0, 0
+	inWord := false
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			han++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if !inWord {
+				words++
+			}
+			inWord = true
+		default:
+			inWord = inWord && (r == '\'' || r == '-')
+			switch r {
+			case '.', '!', '?', '。', '！', '？', '\n':
+				sentences++
+			}
+		}
+	}
+	speech := float64(han)/hanPerSecond + float64(words)/wordsPerSecond + float64(sentences)*sentencePause
+	return speech/speed + pauses
+}
@@ -0,0 +1,86 @@
+package estimate
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBillableChars(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"Hello, world!", 13},
+		{"你好", 4},
+		{"你好，world", 10}, // The full-width comma is not Han
+		{"こんにちは", 5},
+		{"😀 ok", 4},
+	}
+	for _, tt := range tests {
+		if got := BillableChars(tt.text); got != tt.want {
+			t.Errorf("BillableChars(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCost(t *testing.T) {
+	tests := []struct {
+		prices   *PriceList
+		model    string
+		billable int
+		want     float64
+		ok       bool
+	}{
+		{InternationalPrices, "speech-2.6-hd", 1000000, 100, true},
+		{InternationalPrices, "speech-02-turbo", 10000, 0.6, true},
+		{ChinaPrices, "speech-2.6-hd", 10000, 3.5, true},
+		{ChinaPrices, "speech-01-hd", 0, 0, true},
+		{ChinaPrices, "unknown-model", 1000, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := tt.prices.Cost(tt.model, tt.billable)
+		if ok != tt.ok || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s Cost(%q, %d) = %v, %v, want %v, %v", tt.prices.Currency, tt.model, tt.billable, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestForEndpoint(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		want    string
+	}{
+		{"https://api.minimaxi.com/v1", "CNY"},
+		{"https://api.minimax.io/v1", "USD"},
+		{"https://api.minimax.chat/v1", "CNY"},
+	}
+	for _, tt := range tests {
+		if got := ForEndpoint(tt.baseURL).Currency; got != tt.want {
+			t.Errorf("ForEndpoint(%q) = %s, want %s", tt.baseURL, got, tt.want)
+		}
+	}
+}
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		text  string
+		speed float64
+		want  float64
+	}{
+		{"", 1, 0},
+		{"一二三四五六七八九", 1, 2},
+		{"一二三四五六七八九", 2, 1},
+		{"one two three four five", 0, 2},
+		{"It's well-known", 1, 0.8},
+		{"Hi. Bye!", 1, 0.8 + 0.6},
+		{"你好。", 1, 2/4.5 + 0.3},
+		{"wait<#1.5#>go", 1, 0.8 + 1.5},
+		{"wait<#1.5#>go", 2, 0.4 + 1.5}, // Pauses do not scale with speed
+	}
+	for _, tt := range tests {
+		if got := Duration(tt.text, tt.speed); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Duration(%q, %v) = %v, want %v", tt.text, tt.speed, got, tt.want)
+		}
+	}
+}
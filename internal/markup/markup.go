@@ -0,0 +1,402 @@
+// Package markup converts a small subset of SSML into the text syntax of
+// Minimax T2A: <break> becomes a pause marker such as <#0.5#>, <say-as>
+// rewrites its content, and <sub alias> becomes a pronunciation dict entry.
+package markup
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Pauses Minimax accepts, in seconds
+const (
+	minPause = 0.01
+	maxPause = 99.99
+)
+
+// Pause of a <break> without time or strength
+const defaultBreak = 0.5
+
+// Tags of the subset, by name
+var tagNames = map[string]bool{"speak": true, "break": true, "say-as": true, "sub": true}
+
+// Pauses of the SSML break strengths
+var breakStrengths = map[string]float64{
+	"none": 0, "x-weak": 0.1, "weak": 0.25, "medium": 0.5, "strong": 0.75, "x-strong": 1,
+}
+
+var (
+	tagStart     = regexp.MustCompile(`^</?([A-Za-z][A-Za-z-]*)`)
+	tagPattern   = regexp.MustCompile(`^<(/?)([A-Za-z][A-Za-z-]*)((?:\s+[A-Za-z-]+\s*=\s*(?:"[^"<]*"|'[^'<]*'))*)\s*(/?)>`)
+	attrPattern  = regexp.MustCompile(`([A-Za-z-]+)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	pausePattern = regexp.MustCompile(`^<#([^#<>]*)#>`)
+	timePattern  = regexp.MustCompile(`^\s*([0-9]*\.?[0-9]+)\s*(ms|s)?\s*$`)
+	paragraphGap = regexp.MustCompile(`\n[ \t\r]*\n\s*`)
+	entities     = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&", "&quot;", `"`, "&apos;", "'")
+)
+
+// Options tune the conversion
+type Options struct {
+	// Pause put between paragraphs, in seconds; 0 leaves blank lines as they are
+	ParagraphPause float64 `json:"paragraph_pause"`
+}
+
+// Result is converted text, ready for a T2A request
+type Result struct {
+	Text string   `json:"text"`
+	Tone []string `json:"tone,omitempty"` // Pronunciation dict entries, "text/reading"
+}
+
+// Error is a problem at a position of the source text
+type Error struct {
+	Offset  int    `json:"offset"` // In characters from the start, from 0
+	Line    int    `json:"line"`   // From 1
+	Column  int    `json:"column"` // In characters, from 1
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// ErrorList holds every error found in a text, in order
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	if len(l) == 1 {
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0].Error(), len(l)-1)
+}
+
+// element is an open tag whose content is being collected
+type element struct {
+	name  string
+	attrs map[string]string
+	pos   int  // Byte offset of the tag
+	valid bool // Attributes checked without errors
+	text  strings.Builder
+}
+
+// converter holds the state of one conversion
+type converter struct {
+	src   string
+	out   strings.Builder
+	open  *element // <say-as> or <sub> being read; they cannot nest
+	speak int      // Depth of <speak> elements
+	tone  []string
+	subs  map[string]string
+	errs  ErrorList
+}
+
+// Convert turns text written in the SSML subset into Minimax text. Text
+// without tags is returned unchanged, except for XML entities such as &lt;.
+// Pause markers already in Minimax syntax are kept, and a < that does not
+// start a tag is kept as text. All errors are reported
+// together as an ErrorList.
+//
+// A <sub> alias applies to every occurrence of its text, as pronunciation
+// dict entries do, so one text cannot be given two different aliases.
+func Convert(text string, opts Options) (*Result, error) {
+	c := &converter{src: text, subs: map[string]string{}}
+	c.run()
+	if len(c.errs) > 0 {
+		return nil, c.errs
+	}
+	out := c.out.String()
+	if opts.ParagraphPause > 0 {
+		pause, err := pauseMarker(opts.ParagraphPause)
+		if err != nil {
+			return nil, ErrorList{c.errorAt(0, "paragraph pause: "+err.Error())}
+		}
+		out = paragraphGap.ReplaceAllString(out, "\n"+pause+"\n")
+	}
+	return &Result{Text: out, Tone: c.tone}, nil
+}
+
+// errorAt makes an error for the byte offset pos of the source
+func (c *converter) errorAt(pos int, msg string) *Error {
+	before := c.src[:pos]
+	line := strings.Count(before, "\n") + 1
+	lineStart := strings.LastIndex(before, "\n") + 1
+	return &Error{
+		Offset:  utf8.RuneCountInString(before),
+		Line:    line,
+		Column:  utf8.RuneCountInString(before[lineStart:]) + 1,
+		Message: msg,
+	}
+}
+
+func (c *converter) fail(pos int, format string, args ...any) {
+	c.errs = append(c.errs, c.errorAt(pos, fmt.Sprintf(format, args...)))
+}
+
+// write adds text to the open element, or else to the output
+func (c *converter) write(s string) {
+	if c.open != nil {
+		c.open.text.WriteString(s)
+		return
+	}
+	c.out.WriteString(s)
+}
+
+func (c *converter) run() {
+	src := c.src
+	for i := 0; i < len(src); {
+		switch src[i] {
+		case '<':
+			if m := pausePattern.FindStringSubmatch(src[i:]); m != nil {
+				if _, err := pauseSeconds(m[1]); err != nil {
+					c.fail(i, "pause marker: %v", err)
+				}
+				c.write(m[0])
+				i += len(m[0])
+				continue
+			}
+			if m := tagPattern.FindStringSubmatchIndex(src[i:]); m != nil {
+				tag := src[i : i+m[1]]
+				closing := m[3] > m[2]
+				name := strings.ToLower(tag[m[4]:m[5]])
+				attrs := tag[m[6]:m[7]]
+				selfClosing := m[9] > m[8]
+				c.tag(i, name, attrs, closing, selfClosing, i+m[6])
+				i += m[1]
+				continue
+			}
+			// Other uses of <, as in x<y, are plain text
+			if m := tagStart.FindStringSubmatch(src[i:]); m != nil && tagNames[strings.ToLower(m[1])] {
+				c.fail(i, "malformed <%s> tag", strings.ToLower(m[1]))
+			}
+			c.write("<")
+			i++
+		case '&':
+			end := strings.IndexByte(src[i:], ';')
+			if end > 0 && end <= 6 {
+				if decoded := entities.Replace(src[i : i+end+1]); decoded != src[i:i+end+1] {
+					c.write(decoded)
+					i += end + 1
+					continue
+				}
+			}
+			c.write("&")
+			i++
+		default:
+			j := i + 1
+			for j < len(src) && src[j] != '<' && src[j] != '&' {
+				j++
+			}
+			c.write(src[i:j])
+			i = j
+		}
+	}
+	if c.open != nil {
+		c.fail(c.open.pos, "<%s> is not closed", c.open.name)
+	}
+}
+
+// tag handles the tag at byte offset pos. attrPos is the offset of its
+// attributes, for errors about them.
+func (c *converter) tag(pos int, name, rawAttrs string, closing, selfClosing bool, attrPos int) {
+	attrs := map[string]string{}
+	for _, m := range attrPattern.FindAllStringSubmatch(rawAttrs, -1) {
+		attrs[strings.ToLower(m[1])] = entities.Replace(m[2] + m[3])
+	}
+
+	if closing {
+		switch {
+		case name == "break":
+			// <break> is empty, a closing tag adds nothing
+		case name == "speak" && c.speak > 0:
+			c.speak--
+		case c.open != nil && c.open.name == name:
+			c.close(c.open)
+			c.open = nil
+		default:
+			c.fail(pos, "unexpected </%s>", name)
+		}
+		return
+	}
+
+	switch name {
+	case "speak":
+		if !selfClosing {
+			c.speak++
+		}
+	case "break":
+		c.breakTag(attrPos, attrs)
+	case "say-as", "sub":
+		if c.open != nil {
+			c.fail(pos, "<%s> cannot be used inside <%s>", name, c.open.name)
+			return
+		}
+		// Content is read even with bad attributes, so that the closing tag matches
+		el := &element{name: name, attrs: attrs, pos: pos}
+		el.valid = c.checkAttrs(el, attrPos)
+		if selfClosing {
+			c.fail(pos, "<%s> needs content", name)
+			return
+		}
+		c.open = el
+	default:
+		c.fail(pos, "unsupported tag <%s>", name)
+	}
+}
+
+// say-as interpretations that are supported
+var interpretations = map[string]bool{
+	"characters": true, "spell-out": true, "verbatim": true,
+	"digits": true, "telephone": true, "cardinal": true, "number": true,
+	"interjection": true,
+}
+
+// checkAttrs validates the attributes of a <say-as> or <sub>
+func (c *converter) checkAttrs(el *element, attrPos int) bool {
+	switch el.name {
+	case "say-as":
+		as, ok := el.attrs["interpret-as"]
+		if !ok {
+			c.fail(el.pos, "<say-as> needs interpret-as")
+			return false
+		}
+		if !interpretations[as] {
+			c.fail(attrPos, "interpret-as %q is not supported", as)
+			return false
+		}
+	case "sub":
+		if strings.TrimSpace(el.attrs["alias"]) == "" {
+			c.fail(el.pos, "<sub> needs an alias")
+			return false
+		}
+	}
+	return true
+}
+
+// breakTag writes the pause of a <break>
+func (c *converter) breakTag(attrPos int, attrs map[string]string) {
+	seconds := defaultBreak
+	if t, ok := attrs["time"]; ok {
+		s, err := parseSeconds(t)
+		if err != nil {
+			c.fail(attrPos, "break time: %v", err)
+			return
+		}
+		seconds = s
+	} else if strength, ok := attrs["strength"]; ok {
+		s, known := breakStrengths[strength]
+		if !known {
+			c.fail(attrPos, "unknown break strength %q", strength)
+			return
+		}
+		seconds = s
+	}
+	if seconds == 0 {
+		return
+	}
+	marker, err := pauseMarker(seconds)
+	if err != nil {
+		c.fail(attrPos, "break time: %v", err)
+		return
+	}
+	c.write(marker)
+}
+
+// close writes the converted content of a <say-as> or <sub>
+func (c *converter) close(el *element) {
+	content := el.text.String()
+	if strings.TrimSpace(content) == "" {
+		c.fail(el.pos, "<%s> needs content", el.name)
+		return
+	}
+	if !el.valid {
+		return
+	}
+
+	switch el.name {
+	case "sub":
+		word, alias := strings.TrimSpace(content), strings.TrimSpace(el.attrs["alias"])
+		if prev, ok := c.subs[word]; ok && prev != alias {
+			c.fail(el.pos, "%q already has the alias %q", word, prev)
+		} else if !ok {
+			c.subs[word] = alias
+			c.tone = append(c.tone, word+"/"+alias)
+		}
+		c.out.WriteString(content)
+	case "say-as":
+		switch el.attrs["interpret-as"] {
+		case "characters", "spell-out", "verbatim":
+			c.out.WriteString(spaced(content, func(r rune) bool { return !unicode.IsSpace(r) }))
+		case "digits", "telephone":
+			c.out.WriteString(spaced(content, unicode.IsDigit))
+		case "interjection":
+			word := strings.TrimSpace(content)
+			for _, r := range word {
+				if !unicode.IsLetter(r) && r != '-' {
+					c.fail(el.pos, "interjection %q must be a single word", word)
+					return
+				}
+			}
+			c.out.WriteString("(" + word + ")")
+		default:
+			c.out.WriteString(content)
+		}
+	}
+}
+
+// spaced puts a space between consecutive runes that match, so that they
+// are read one by one
+func spaced(s string, match func(rune) bool) string {
+	var sb strings.Builder
+	prev := false
+	for _, r := range s {
+		m := match(r)
+		if m && prev {
+			sb.WriteByte(' ')
+		}
+		sb.WriteRune(r)
+		prev = m
+	}
+	return sb.String()
+}
+
+// parseSeconds reads a duration such as "500ms", "1.5s", or a plain number
+// of seconds
+func parseSeconds(s string) (float64, error) {
+	m := timePattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	v, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	if m[2] == "ms" {
+		v /= 1000
+	}
+	return v, nil
+}
+
+// pauseSeconds reads the number of seconds of a Minimax pause marker
+func pauseSeconds(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	if v < minPause || v > maxPause {
+		return 0, fmt.Errorf("%gs is outside %g-%gs", v, minPause, maxPause)
+	}
+	return v, nil
+}
+
+// pauseMarker writes a pause in Minimax syntax
+func pauseMarker(seconds float64) (string, error) {
+	seconds = math.Round(seconds*100) / 100
+	if seconds < minPause || seconds > maxPause {
+		return "", fmt.Errorf("%gs is outside %g-%gs", seconds, minPause, maxPause)
+	}
+	return "<#" + strconv.FormatFloat(seconds, 'f', -1, 64) + "#>", nil
+}
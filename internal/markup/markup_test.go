@@ -0,0 +1,86 @@
+package markup
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		opts Options
+		text string
+		tone []string
+	}{
+		{"plain text", "Hello, world.", Options{}, "Hello, world.", nil},
+		{"less than in text", "if x<y and y > z", Options{}, "if x<y and y > z", nil},
+		{"less than before a word", "a <b c", Options{}, "a <b c", nil},
+		{"entities", "&lt;b&gt; &amp; &quot;q&quot; &apos;", Options{}, `<b> & "q" '`, nil},
+		{"unknown entity", "AT&T; &nbsp;", Options{}, "AT&T; &nbsp;", nil},
+		{"speak", "<speak>你好</speak>", Options{}, "你好", nil},
+		{"break time", `a<break time="500ms"/>b<break time="1.5s"/>c`, Options{}, "a<#0.5#>b<#1.5#>c", nil},
+		{"break strength", `a<break strength="strong"/>b<break strength="none"/>c`, Options{}, "a<#0.75#>bc", nil},
+		{"default break", "a<break/>b", Options{}, "a<#0.5#>b", nil},
+		{"pause marker kept", "a<#2#>b", Options{}, "a<#2#>b", nil},
+		{"characters", `<say-as interpret-as="characters">ABC</say-as>`, Options{}, "A B C", nil},
+		{"telephone", `<say-as interpret-as="telephone">010-1234</say-as>`, Options{}, "0 1 0-1 2 3 4", nil},
+		{"interjection", `<say-as interpret-as="interjection">laughs</say-as>`, Options{}, "(laughs)", nil},
+		{"sub", `<sub alias="世界贸易组织">WTO</sub>与<sub alias="世界贸易组织">WTO</sub>`, Options{}, "WTO与WTO", []string{"WTO/世界贸易组织"}},
+		{"entity in attribute", `<sub alias="R&amp;D">RD</sub>`, Options{}, "RD", []string{"RD/R&D"}},
+		{"paragraph pause", "第一段。\n\n第二段。", Options{ParagraphPause: 1}, "第一段。\n<#1#>\n第二段。", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Convert(tt.in, tt.opts)
+			if err != nil {
+				t.Fatalf("Convert(%q) failed: %v", tt.in, err)
+			}
+			if res.Text != tt.text {
+				t.Errorf("Convert(%q) = %q, want %q", tt.in, res.Text, tt.text)
+			}
+			if !reflect.DeepEqual(res.Tone, tt.tone) {
+				t.Errorf("Convert(%q) tone = %q, want %q", tt.in, res.Tone, tt.tone)
+			}
+		})
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []Error // Offset, line, column and message of each error
+	}{
+		{"malformed tag", `a<break time=1s/>`, []Error{{1, 1, 2, "malformed <break> tag"}}},
+		{"unsupported tag", "<p>text</p>", []Error{{0, 1, 1, "unsupported tag <p>"}, {7, 1, 8, "unexpected </p>"}}},
+		{"not closed", `前言<sub alias="x">文本`, []Error{{2, 1, 3, "<sub> is not closed"}}},
+		{"nested", `<sub alias="a"><say-as interpret-as="digits">1</say-as></sub>`,
+			[]Error{{15, 1, 16, "<say-as> cannot be used inside <sub>"}, {46, 1, 47, "unexpected </say-as>"}}},
+		{"unexpected closing", "文字</sub>", []Error{{2, 1, 3, "unexpected </sub>"}}},
+		{"columns count runes", "第一行\n中文内容<break time=\"2x\"/>",
+			[]Error{{14, 2, 11, `break time: invalid duration "2x"`}}},
+		{"offset after emoji", "😀😀\n\n  <sub>词</sub>", []Error{{6, 3, 3, "<sub> needs an alias"}}},
+		{"pause out of range", "中<#120#>", []Error{{1, 1, 2, "pause marker: 120s is outside 0.01-99.99s"}}},
+		{"missing interpret-as", "<say-as>x</say-as>", []Error{{0, 1, 1, "<say-as> needs interpret-as"}}},
+		{"empty content", `<sub alias="a"> </sub>`, []Error{{0, 1, 1, "<sub> needs content"}}},
+		{"two aliases", `<sub alias="a">词</sub><sub alias="b">词</sub>`, []Error{{22, 1, 23, `"词" already has the alias "a"`}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Convert(tt.in, Options{})
+			var list ErrorList
+			if !errors.As(err, &list) {
+				t.Fatalf("Convert(%q) error = %v, want an ErrorList", tt.in, err)
+			}
+			var got []Error
+			for _, e := range list {
+				got = append(got, *e)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Convert(%q) errors = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
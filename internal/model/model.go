@@ -8,27 +8,73 @@ import (
 
 // ApiKey stores API keys for Minimax platform
 type ApiKey struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	Platform  string         `gorm:"size:50;default:'minimax'" json:"platform"`
-	Key       string         `gorm:"size:255;not null" json:"key"`
-	Remark    string         `gorm:"size:100" json:"remark"`
-	IsDefault bool           `gorm:"default:false" json:"is_default"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID            uint           `gorm:"primaryKey" json:"id"`
+	Platform      string         `gorm:"size:50;default:'minimax'" json:"platform"`
+	Key           string         `gorm:"size:255;not null" json:"key"`
+	Remark        string         `gorm:"size:100" json:"remark"`
+	IsDefault     bool           `gorm:"default:false" json:"is_default"`
+	Pool          string         `gorm:"size:50;default:'default';index" json:"pool"` // Keys in the same pool fail over to each other
+	CooldownUntil *time.Time     `json:"cooldown_until"`                              // Skipped by failover until then
+	FailureCount  int            `gorm:"default:0" json:"failure_count"`
+	LastError     string         `gorm:"size:255" json:"last_error"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // Voice represents a voice profile (cloned or official)
 type Voice struct {
-	ID         uint           `gorm:"primaryKey" json:"id"`
-	Name       string         `gorm:"size:100;not null" json:"name"`
-	VoiceID    string         `gorm:"size:100;uniqueIndex;not null" json:"voice_id"` // Minimax Voice ID
-	Type       string         `gorm:"size:20;default:'cloned'" json:"type"`          // cloned, system, generated
-	Preview    string         `gorm:"size:255" json:"preview"`                       // Path to preview audio
-	IsFavorite bool           `gorm:"default:false;index" json:"is_favorite"`
-	CreatedAt  time.Time      `json:"created_at"`
-	UpdatedAt  time.Time      `json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                uint           `gorm:"primaryKey" json:"id"`
+	Name              string         `gorm:"size:100;not null" json:"name"`
+	VoiceID           string         `gorm:"size:100;uniqueIndex;not null" json:"voice_id"` // Minimax Voice ID
+	Type              string         `gorm:"size:20;default:'cloned'" json:"type"`          // cloned, system, generated
+	Preview           string         `gorm:"size:255" json:"preview"`                       // Path to preview audio
+	IsFavorite        bool           `gorm:"default:false;index" json:"is_favorite"`
+	KeyID             uint           `gorm:"index" json:"key_id"`                // Owning API key, 0 for system voices
+	Description       string         `gorm:"type:text" json:"description"`       // Reported by Minimax during sync
+	RemoteCreatedTime string         `gorm:"size:50" json:"remote_created_time"` // Reported by Minimax during sync
+	Language          string         `gorm:"size:50;index" json:"language"`      // e.g. Chinese (Mandarin), English
+	Gender            string         `gorm:"size:20;index" json:"gender"`        // male, female, neutral
+	Age               string         `gorm:"size:20" json:"age"`                 // child, young, middle_aged, senior
+	Tags              StringList     `gorm:"type:text" json:"tags"`              // Style tags
+	Notes             string         `gorm:"type:text" json:"notes"`
+	FirstUsedAt       *time.Time     `json:"first_used_at"`           // First real synthesis with this voice
+	ExpiresAt         *time.Time     `gorm:"index" json:"expires_at"` // Deletion deadline of unused temporary voices
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// VoiceRetention is how long Minimax keeps a cloned or designed voice
+// that has not been used in a real synthesis yet
+const VoiceRetention = 7 * 24 * time.Hour
+
+// IsTemporary reports whether Minimax deletes the voice when left unused
+func (v *Voice) IsTemporary() bool {
+	return v.Type == "cloned" || v.Type == "generated"
+}
+
+// ComputeExpiry sets ExpiresAt from the creation time for temporary voices
+// that were never used, and clears it otherwise. The remote creation date
+// is preferred when Minimax reported one.
+func (v *Voice) ComputeExpiry() {
+	if !v.IsTemporary() || v.FirstUsedAt != nil {
+		v.ExpiresAt = nil
+		return
+	}
+
+	created := v.CreatedAt
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, v.RemoteCreatedTime, time.Local); err == nil {
+			created = t
+			break
+		}
+	}
+	if created.IsZero() {
+		created = time.Now()
+	}
+	expires := created.Add(VoiceRetention)
+	v.ExpiresAt = &expires
 }
 
 // SynthesisTask tracks text-to-speech tasks
@@ -36,6 +82,7 @@ type SynthesisTask struct {
 	ID             uint           `gorm:"primaryKey" json:"id"`
 	TaskID         int64          `gorm:"index" json:"task_id"` // For async tasks
 	Text           string         `gorm:"type:text" json:"text"`
+	NormalizedText string         `gorm:"type:text" json:"normalized_text,omitempty"` // Text as sent, when markup or normalization changed it
 	VoiceID        string         `gorm:"size:100" json:"voice_id"`
 	Format         string         `gorm:"size:10" json:"format"`  // mp3, pcm, flac
 	SampleRate     int64          `json:"sample_rate"`            // e.g. 32000
@@ -44,7 +91,369 @@ type SynthesisTask struct {
 	Status         string         `gorm:"size:20;default:'pending'" json:"status"`
 	Error          string         `gorm:"size:255" json:"error,omitempty"`
 	RequestPayload string         `gorm:"type:text" json:"request_payload"`
+	TextFileID     int64          `gorm:"index" json:"text_file_id"`  // Minimax file the task was submitted with, 0 for plain text
+	TextInputID    uint           `gorm:"index" json:"text_input_id"` // Stored TextFile of the input, 0 for plain text
+	KeyID          uint           `gorm:"index" json:"key_id"`        // API key the task was submitted with
+	ProjectID      uint           `gorm:"index" json:"project_id"`    // 0 when not in a project
+	FolderID       uint           `gorm:"index" json:"folder_id"`     // 0 for the project root
+	ParentID       uint           `gorm:"index" json:"parent_id"`     // Task this one was retried or duplicated from, 0 for none
+	LineID         uint           `gorm:"index" json:"line_id"`       // Line this task is a take of, 0 for none
+	Tags           StringList     `gorm:"type:text" json:"tags"`
+	ReviewStatus   string         `gorm:"size:20;default:'draft';index" json:"review_status"` // draft, approved, rejected
+	Rating         int            `gorm:"default:0;index" json:"rating"`                      // 1-5 stars, 0 when unrated
+	Notes          string         `gorm:"type:text" json:"notes"`
+	IsFavorite     bool           `gorm:"default:false;index" json:"is_favorite"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TextFile is a .txt or .zip input uploaded for async synthesis. The
+// original is kept so it can be uploaded again once the Minimax copy has
+// expired, along with the text extracted from it for search and display.
+type TextFile struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	FileID    int64          `gorm:"index" json:"file_id"` // Current Minimax copy
+	KeyID     uint           `gorm:"index" json:"key_id"`  // The Minimax copy only exists for the account of this key
+	Filename  string         `gorm:"size:255" json:"filename"`
+	Path      string         `gorm:"size:255" json:"-"` // Local copy of the original
+	Size      int64          `json:"size"`
+	Text      string         `gorm:"type:text" json:"text"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// CloneJob records one voice clone request: the source audio kept on disk,
+// every VoiceCloneRequest parameter and the outcome, so that failed clones
+// can be retried and successful ones audited
+type CloneJob struct {
+	ID                      uint           `gorm:"primaryKey" json:"id"`
+	Name                    string         `gorm:"size:100;not null" json:"name"`    // Name of the resulting voice
+	VoiceID                 string         `gorm:"size:100;index" json:"voice_id"`   // Requested Minimax Voice ID
+	SampleID                uint           `gorm:"index" json:"sample_id,omitempty"` // Prepared sample used as source, if any
+	KeyID                   uint           `gorm:"index" json:"key_id"`              // Key of the last attempt
+	SourceFile              string         `gorm:"size:255" json:"source_file"`      // Local path of the clone sample
+	SourceFilename          string         `gorm:"size:255" json:"source_filename"`
+	PromptFile              string         `gorm:"size:255" json:"prompt_file"` // Local path of the prompt audio
+	PromptFilename          string         `gorm:"size:255" json:"prompt_filename"`
+	PromptText              string         `gorm:"type:text" json:"prompt_text"`  // Transcript of the prompt audio
+	DemoText                string         `gorm:"type:text" json:"demo_text"`    // Text for demo generation
+	Model                   string         `gorm:"size:50" json:"model"`          // Speech model for the demo
+	LanguageBoost           string         `gorm:"size:50" json:"language_boost"` // auto, Chinese, English, etc.
+	NeedNoiseReduction      bool           `json:"need_noise_reduction"`
+	NeedVolumeNormalization bool           `json:"need_volume_normalization"`
+	AigcWatermark           bool           `json:"aigc_watermark"`
+	Status                  string         `gorm:"size:20;default:'pending';index" json:"status"` // pending, success, failed
+	FailedStep              string         `gorm:"size:20" json:"failed_step,omitempty"`          // upload, clone, save
+	Warnings                StringList     `gorm:"type:text" json:"warnings"`                     // Audio validation warnings
+	Error                   string         `gorm:"type:text" json:"error,omitempty"`
+	InputSensitive          bool           `json:"input_sensitive"` // Flagged by Minimax content review
+	InputSensitiveType      int            `json:"input_sensitive_type"`
+	FileID                  int64          `json:"file_id"`                    // Uploaded sample of the last attempt
+	PromptFileID            int64          `json:"prompt_file_id"`             // Uploaded prompt audio of the last attempt
+	DemoAudio               string         `gorm:"size:255" json:"demo_audio"` // Path to downloaded demo audio
+	Attempts                int            `gorm:"default:0" json:"attempts"`
+	CreatedAt               time.Time      `json:"created_at"`
+	UpdatedAt               time.Time      `json:"updated_at"`
+	DeletedAt               gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// CloneSample is a prepared clone sample: a WAV clip cut from a longer
+// recording, kept so that it can be cloned under several accounts
+type CloneSample struct {
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	Name           string         `gorm:"size:100;not null" json:"name"`
+	SourceFilename string         `gorm:"size:255" json:"source_filename"` // Original upload
+	File           string         `gorm:"size:255" json:"-"`               // Local path of the prepared WAV
+	Start          float64        `json:"start"`                           // Seconds into the source
+	End            float64        `json:"end"`                             // 0 means the end of the source
+	StripSilence   bool           `json:"strip_silence"`
+	Mono           bool           `json:"mono"`
+	Duration       float64        `json:"duration"` // Of the prepared clip
+	SampleRate     int            `json:"sample_rate"`
+	Channels       int            `json:"channels"`
+	Size           int64          `json:"size"`
+	PeakDB         float64        `json:"peak_db"`
+	RMSDB          float64        `json:"rms_db"`
+	Warnings       StringList     `gorm:"type:text" json:"warnings"` // Audio validation warnings
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// DesignSession groups the candidates of one voice design iteration.
+// Only the promoted candidate becomes a library voice.
+type DesignSession struct {
+	ID         uint              `gorm:"primaryKey" json:"id"`
+	Name       string            `gorm:"size:100" json:"name"`                       // Name for the promoted voice
+	KeyID      uint              `json:"key_id"`                                     // Preferred key for new candidates
+	Status     string            `gorm:"size:20;default:'open';index" json:"status"` // open, promoted, discarded
+	VoiceID    string            `gorm:"size:100" json:"voice_id,omitempty"`         // Promoted Minimax Voice ID
+	Candidates []DesignCandidate `gorm:"foreignKey:SessionID" json:"candidates"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt    `gorm:"index" json:"-"`
+}
+
+// DesignCandidate is one designed voice with its trial audio. Candidates
+// exist on Minimax until they are promoted or discarded.
+type DesignCandidate struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	SessionID     uint      `gorm:"index" json:"session_id"`
+	Prompt        string    `gorm:"type:text" json:"prompt"`
+	PreviewText   string    `gorm:"type:text" json:"preview_text"`
+	VoiceID       string    `gorm:"size:100;index" json:"voice_id"`               // Minimax Voice ID
+	KeyID         uint      `json:"key_id"`                                       // Account that owns the voice
+	TrialAudio    string    `gorm:"size:255" json:"trial_audio"`                  // Path to trial audio
+	Status        string    `gorm:"size:20;default:'active';index" json:"status"` // active, promoted, discarded
+	RemoteDeleted bool      `json:"remote_deleted"`                               // Removed from Minimax after discarding
+	CleanupError  string    `gorm:"type:text" json:"cleanup_error,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// PreviewTemplate defines the text and settings of voice previews for one
+// language. The template with an empty language is the fallback.
+type PreviewTemplate struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	Name       string         `gorm:"size:100;not null" json:"name"`
+	Language   string         `gorm:"size:50;index" json:"language"` // Matches Voice.Language
+	Text       string         `gorm:"type:text;not null" json:"text"`
+	Model      string         `gorm:"size:50;default:'speech-2.6-hd'" json:"model"`
+	Emotion    string         `gorm:"size:20" json:"emotion"` // Empty lets the model decide
+	Speed      float64        `gorm:"default:1" json:"speed"`
+	Vol        float64        `gorm:"default:1" json:"vol"`
+	Pitch      int            `gorm:"default:0" json:"pitch"`
+	SampleRate int            `gorm:"default:32000" json:"sample_rate"`
+	Bitrate    int            `gorm:"default:128000" json:"bitrate"`
+	Format     string         `gorm:"size:10;default:'mp3'" json:"format"`
+	IsDefault  bool           `gorm:"default:false" json:"is_default"` // Preferred template of its language
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// VoicePreview is one stored preview of a voice. Voice.Preview points to
+// the one shown by default.
+type VoicePreview struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	VoiceID    string    `gorm:"size:100;index" json:"voice_id"` // Minimax Voice ID
+	TemplateID uint      `json:"template_id,omitempty"`
+	Source     string    `gorm:"size:20" json:"source"` // template, custom, demo, design
+	Text       string    `gorm:"type:text" json:"text"`
+	Model      string    `gorm:"size:50" json:"model"`
+	Emotion    string    `gorm:"size:20" json:"emotion"`
+	File       string    `gorm:"size:255" json:"file"` // Path to preview audio
+	KeyID      uint      `json:"key_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// PreviewJob generates missing previews for a filtered set of voices in
+// the background. Progress is kept per item so a job survives restarts.
+type PreviewJob struct {
+	ID            uint           `gorm:"primaryKey" json:"id"`
+	Type          string         `gorm:"size:20" json:"type"` // Filters used to select voices
+	Favorite      bool           `json:"favorite"`
+	Tag           string         `gorm:"size:50" json:"tag"`
+	Language      string         `gorm:"size:50" json:"language"`
+	KeyID         uint           `json:"key_id"`
+	RatePerMinute int            `gorm:"default:20" json:"rate_per_minute"`
+	Status        string         `gorm:"size:20;default:'pending';index" json:"status"` // pending, running, paused, completed, cancelled
+	Total         int            `json:"total"`
+	Generated     int            `json:"generated"`
+	Skipped       int            `json:"skipped"` // Preview file already on disk
+	Failed        int            `json:"failed"`
+	LastError     string         `gorm:"type:text" json:"last_error,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// PreviewJobItem is one voice of a preview job
+type PreviewJobItem struct {
+	ID      uint   `gorm:"primaryKey" json:"id"`
+	JobID   uint   `gorm:"index" json:"job_id"`
+	VoiceID string `gorm:"size:100" json:"voice_id"`
+	Status  string `gorm:"size:20;default:'pending';index" json:"status"` // pending, generated, skipped, failed
+	Error   string `gorm:"type:text" json:"error,omitempty"`
+}
+
+// VoiceComparison is one text synthesized with several candidate voices,
+// kept with the ratings that led to a choice
+type VoiceComparison struct {
+	ID         uint                       `gorm:"primaryKey" json:"id"`
+	Name       string                     `gorm:"size:100" json:"name"`
+	Text       string                     `gorm:"type:text" json:"text"`
+	Model      string                     `gorm:"size:50" json:"model"`
+	Notes      string                     `gorm:"type:text" json:"notes"`
+	Candidates []VoiceComparisonCandidate `gorm:"foreignKey:ComparisonID" json:"candidates"`
+	CreatedAt  time.Time                  `json:"created_at"`
+	UpdatedAt  time.Time                  `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt             `gorm:"index" json:"-"`
+}
+
+// VoiceComparisonCandidate is one voice and its settings in a comparison
+type VoiceComparisonCandidate struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	ComparisonID  uint      `gorm:"index" json:"comparison_id"`
+	VoiceID       string    `gorm:"size:100" json:"voice_id"`
+	PresetID      uint      `gorm:"index" json:"preset_id,omitempty"` // Preset the settings came from
+	Label         string    `gorm:"size:100" json:"label"`
+	Speed         float64   `json:"speed"`
+	Vol           float64   `json:"vol"`
+	Pitch         int       `json:"pitch"`
+	Emotion       string    `gorm:"size:20" json:"emotion"`
+	LanguageBoost string    `gorm:"size:20" json:"language_boost,omitempty"`
+	CacheKey      string    `gorm:"size:64;index" json:"-"` // Hash of the synthesis request
+	Audio         string    `gorm:"size:255" json:"audio"`
+	Cached        bool      `json:"cached"`                // Audio reused from an earlier identical synthesis
+	Status        string    `gorm:"size:20" json:"status"` // success, failed
+	Error         string    `gorm:"type:text" json:"error,omitempty"`
+	Rating        int       `json:"rating"` // 1-5, 0 when unrated
+	Notes         string    `gorm:"type:text" json:"notes"`
+	Chosen        bool      `json:"chosen"`
+	KeyID         uint      `json:"key_id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Preset is a named voice and settings to start synthesis from. Presets
+// without a project are offered everywhere.
+type Preset struct {
+	ID        uint              `gorm:"primaryKey" json:"id"`
+	ProjectID uint              `gorm:"index" json:"project_id"` // 0 for all projects
+	Name      string            `gorm:"size:100;not null" json:"name"`
+	VoiceID   string            `gorm:"size:100;index" json:"voice_id"` // Empty to keep the voice chosen
+	Settings  SynthesisSettings `gorm:"type:text" json:"settings"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	DeletedAt gorm.DeletedAt    `gorm:"index" json:"-"`
+}
+
+// PronunciationDict lists readings of words, sent as the pronunciation_dict
+// tone entries of every matching synthesis request. Dictionaries without a
+// project apply everywhere, and those without a voice to any voice.
+type PronunciationDict struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	ProjectID uint           `gorm:"index" json:"project_id"`        // 0 for all projects
+	VoiceID   string         `gorm:"size:100;index" json:"voice_id"` // Empty for any voice
+	Name      string         `gorm:"size:100;not null" json:"name"`
+	Entries   StringList     `gorm:"type:text" json:"entries"` // "text/reading", e.g. 重庆/(chong2)(qing4)
+	Enabled   bool           `json:"enabled"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// Project groups synthesis tasks into nested folders. Its default voice
+// and settings fill in what new tasks in the project leave out.
+type Project struct {
+	ID             uint              `gorm:"primaryKey" json:"id"`
+	Name           string            `gorm:"size:100;not null" json:"name"`
+	Description    string            `gorm:"type:text" json:"description"`
+	DefaultVoiceID string            `gorm:"size:100" json:"default_voice_id"`
+	Settings       SynthesisSettings `gorm:"type:text" json:"settings"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt    `gorm:"index" json:"-"`
+}
+
+// Line is one piece of text that is synthesized again and again with small
+// changes. Each synthesis task of the line is a take, and the selected take
+// is the one used on export.
+type Line struct {
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	ProjectID      uint           `gorm:"index" json:"project_id"` // 0 when not in a project
+	FolderID       uint           `gorm:"index" json:"folder_id"`  // 0 for the project root
+	Name           string         `gorm:"size:100" json:"name"`
+	Text           string         `gorm:"type:text" json:"text"`         // Used by takes submitted without text
+	SelectedTaskID uint           `gorm:"index" json:"selected_task_id"` // 0 until a take is selected
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
 	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 }
+
+// Audiobook is a document split into chapters and paragraphs. It lives in
+// a project: each chapter is a folder and each paragraph a line, so single
+// paragraphs can be edited and synthesized again.
+type Audiobook struct {
+	ID        uint               `gorm:"primaryKey" json:"id"`
+	ProjectID uint               `gorm:"index" json:"project_id"`
+	Title     string             `gorm:"size:255" json:"title"`
+	Format    string             `gorm:"size:20" json:"format"` // markdown, text, epub
+	Filename  string             `gorm:"size:255" json:"filename"`
+	KeyID     uint               `json:"key_id"`                                      // Key of the last build, 0 for the default
+	Status    string             `gorm:"size:20;default:'draft';index" json:"status"` // draft, building, ready, partial, failed, cancelled
+	Error     string             `gorm:"type:text" json:"error,omitempty"`
+	Output    string             `gorm:"size:255" json:"output"` // Combined track with chapter markers
+	Duration  float64            `json:"duration"`               // Seconds
+	Chapters  []AudiobookChapter `gorm:"foreignKey:AudiobookID" json:"chapters,omitempty"`
+	CreatedAt time.Time          `json:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
+	DeletedAt gorm.DeletedAt     `gorm:"index" json:"-"`
+}
+
+// AudiobookChapter is a chapter of an audiobook, synthesized with its own
+// voice, preset and settings. Its paragraphs are the lines of its folder.
+type AudiobookChapter struct {
+	ID            uint              `gorm:"primaryKey" json:"id"`
+	AudiobookID   uint              `gorm:"index" json:"audiobook_id"`
+	FolderID      uint              `gorm:"index" json:"folder_id"`
+	Position      int               `json:"position"` // 1 for the first chapter
+	Title         string            `gorm:"size:255" json:"title"`
+	Chars         int               `json:"chars"`
+	VoiceID       string            `gorm:"size:100" json:"voice_id"` // Empty for the preset or project default
+	PresetID      uint              `json:"preset_id"`                // Fills in what the voice and settings leave empty, 0 for none
+	Settings      SynthesisSettings `gorm:"type:text" json:"settings"`
+	Pipeline      string            `gorm:"size:20" json:"pipeline"`               // chunked, async, or empty to choose by length
+	Normalization string            `gorm:"size:20" json:"normalization"`          // rules (empty), all to add the built-in normalizers, or off
+	Status        string            `gorm:"size:20;default:'draft'" json:"status"` // draft, queued, synthesizing, ready, failed
+	Error         string            `gorm:"type:text" json:"error,omitempty"`
+	Output        string            `gorm:"size:255" json:"output"`
+	Duration      float64           `json:"duration"` // Seconds
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+// ProjectFolder is a folder inside a project, optionally nested in another
+type ProjectFolder struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	ProjectID uint           `gorm:"index" json:"project_id"`
+	ParentID  uint           `gorm:"index" json:"parent_id"` // 0 for top-level folders
+	Name      string         `gorm:"size:100;not null" json:"name"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// Script is a draft of text to be synthesized, such as narration or
+// dialogue, kept in a project folder
+type Script struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	ProjectID uint           `gorm:"index" json:"project_id"` // 0 when not in a project
+	FolderID  uint           `gorm:"index" json:"folder_id"`  // 0 for the project root
+	Name      string         `gorm:"size:100;not null" json:"name"`
+	Text      string         `gorm:"type:text" json:"text"`
+	Notes     string         `gorm:"type:text" json:"notes"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// NormalizationRule rewrites text before it is synthesized: matches of a
+// regular expression are replaced, $1 expanding to the first group. Rules
+// without a project apply everywhere, and those without a language to any.
+type NormalizationRule struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	ProjectID   uint           `gorm:"index" json:"project_id"` // 0 for all projects
+	Language    string         `gorm:"size:50" json:"language"` // Matches language_boost, empty for any
+	Name        string         `gorm:"size:100" json:"name"`
+	Pattern     string         `gorm:"type:text;not null" json:"pattern"` // Go regexp syntax
+	Replacement string         `gorm:"type:text" json:"replacement"`
+	Position    int            `gorm:"default:0" json:"position"` // Rules run in ascending position
+	Enabled     bool           `json:"enabled"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
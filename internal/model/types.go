@@ -0,0 +1,89 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// StringList is a list of strings stored as a JSON array in a text column.
+// Single entries can be matched with json_each.
+type StringList []string
+
+// Value implements driver.Valuer
+func (l StringList) Value() (driver.Value, error) {
+	if len(l) == 0 {
+		return "[]", nil
+	}
+	b, err := json.Marshal([]string(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner
+func (l *StringList) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*l = StringList{}
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return errors.New("unsupported type for StringList")
+	}
+	if len(data) == 0 {
+		*l = StringList{}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(l))
+}
+
+// SynthesisSettings are default synthesis parameters, stored as a JSON
+// object in a text column. Zero values mean "not set".
+type SynthesisSettings struct {
+	Model         string  `json:"model,omitempty"`
+	Speed         float64 `json:"speed,omitempty"`
+	Vol           float64 `json:"vol,omitempty"`
+	Pitch         int     `json:"pitch,omitempty"`
+	Emotion       string  `json:"emotion,omitempty"`
+	LanguageBoost string  `json:"language_boost,omitempty"`
+	Format        string  `json:"format,omitempty"`
+	SampleRate    int64   `json:"sample_rate,omitempty"`
+	Bitrate       int64   `json:"bitrate,omitempty"`
+	Channel       int64   `json:"channel,omitempty"`
+}
+
+// Value implements driver.Valuer
+func (s SynthesisSettings) Value() (driver.Value, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner
+func (s *SynthesisSettings) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*s = SynthesisSettings{}
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return errors.New("unsupported type for SynthesisSettings")
+	}
+	if len(data) == 0 {
+		*s = SynthesisSettings{}
+		return nil
+	}
+	return json.Unmarshal(data, s)
+}
@@ -0,0 +1,210 @@
+package normalize
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	zhDigits = []string{"零", "一", "二", "三", "四", "五", "六", "七", "八", "九"}
+
+	// Latin words or numbers next to Han characters
+	hanThenLatin = regexp.MustCompile(`(\p{Han})([A-Za-z])`)
+	latinThenHan = regexp.MustCompile(`([A-Za-z][A-Za-z0-9]*)(\p{Han})`)
+
+	// Mainland mobile numbers and landlines with an area code
+	zhMobile   = regexp.MustCompile(`(^|[^0-9])(1[3-9][0-9]{9})($|[^0-9])`)
+	zhLandline = regexp.MustCompile(`(^|[^0-9])(0[0-9]{2,3})-([0-9]{7,8})($|[^0-9])`)
+
+	zhDatePattern = regexp.MustCompile(`([0-9]{4})(?:[-/.]|年)([0-9]{1,2})(?:[-/.]|月)([0-9]{1,2})日?`)
+	zhYear        = regexp.MustCompile(`([0-9]{4})年`)
+	zhClock       = regexp.MustCompile(`(^|[^0-9:])([01]?[0-9]|2[0-4]):([0-5][0-9])($|[^0-9:])`)
+	zhPercentage  = regexp.MustCompile(`([0-9]+(?:\.[0-9]+)?)[%％]`)
+	zhYuan        = regexp.MustCompile(`[¥￥]([0-9][0-9,]*(?:\.[0-9]+)?)`)
+	zhDollar      = regexp.MustCompile(`(?:US)?\$([0-9][0-9,]*(?:\.[0-9]+)?)`)
+	zhNumeral     = regexp.MustCompile(`[0-9]{1,3}(?:,[0-9]{3})+(?:\.[0-9]+)?|[0-9]+(?:\.[0-9]+)?`)
+)
+
+// Numbers with more digits are read digit by digit
+const maxCardinalDigits = 12
+
+// spaceMixedScripts separates Latin words from the Han characters around
+// them, so that they are read as English words
+func spaceMixedScripts(s string) string {
+	s = hanThenLatin.ReplaceAllString(s, "$1 $2")
+	return latinThenHan.ReplaceAllString(s, "$1 $2")
+}
+
+// zhDigitString reads each digit, with 一 as 幺 as in phone numbers when
+// phone is set
+func zhDigitString(digits string, phone bool) string {
+	var sb strings.Builder
+	for _, d := range digits {
+		if d < '0' || d > '9' {
+			continue
+		}
+		if phone && d == '1' {
+			sb.WriteString("幺")
+			continue
+		}
+		sb.WriteString(zhDigits[d-'0'])
+	}
+	return sb.String()
+}
+
+func zhPhone(s string) string {
+	s = zhLandline.ReplaceAllStringFunc(s, func(m string) string {
+		g := zhLandline.FindStringSubmatch(m)
+		return g[1] + zhDigitString(g[2], true) + "，" + zhDigitString(g[3], true) + g[4]
+	})
+	return zhMobile.ReplaceAllStringFunc(s, func(m string) string {
+		g := zhMobile.FindStringSubmatch(m)
+		return g[1] + zhDigitString(g[2], true) + g[3]
+	})
+}
+
+// zhDate reads full dates, and years followed by 年, with the year digit
+// by digit
+func zhDate(s string) string {
+	s = zhDatePattern.ReplaceAllStringFunc(s, func(m string) string {
+		g := zhDatePattern.FindStringSubmatch(m)
+		month, _ := strconv.Atoi(g[2])
+		day, _ := strconv.Atoi(g[3])
+		if month < 1 || month > 12 || day < 1 || day > 31 {
+			return m
+		}
+		return zhDigitString(g[1], false) + "年" + zhCardinal(int64(month)) + "月" + zhCardinal(int64(day)) + "日"
+	})
+	return zhYear.ReplaceAllStringFunc(s, func(m string) string {
+		return zhDigitString(m, false) + "年"
+	})
+}
+
+func zhTime(s string) string {
+	return zhClock.ReplaceAllStringFunc(s, func(m string) string {
+		g := zhClock.FindStringSubmatch(m)
+		hour, _ := strconv.Atoi(g[2])
+		minute, _ := strconv.Atoi(g[3])
+		reading := zhCardinal(int64(hour)) + "点"
+		switch {
+		case minute == 0:
+			reading += "整"
+		case minute < 10:
+			reading += "零" + zhCardinal(int64(minute)) + "分"
+		default:
+			reading += zhCardinal(int64(minute)) + "分"
+		}
+		return g[1] + reading + g[4]
+	})
+}
+
+func zhPercent(s string) string {
+	return zhPercentage.ReplaceAllStringFunc(s, func(m string) string {
+		return "百分之" + zhNumberString(zhPercentage.FindStringSubmatch(m)[1])
+	})
+}
+
+func zhCurrency(s string) string {
+	s = zhYuan.ReplaceAllStringFunc(s, func(m string) string {
+		return zhNumberString(zhYuan.FindStringSubmatch(m)[1]) + "元"
+	})
+	return zhDollar.ReplaceAllStringFunc(s, func(m string) string {
+		return zhNumberString(zhDollar.FindStringSubmatch(m)[1]) + "美元"
+	})
+}
+
+// zhNumber reads the numbers left as Chinese numerals. Digits that are
+// part of a Latin word, as in iPhone15, are kept.
+func zhNumber(s string) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range zhNumeral.FindAllStringIndex(s, -1) {
+		if loc[0] > 0 && isLatinLetter(s[loc[0]-1]) {
+			continue
+		}
+		sb.WriteString(s[last:loc[0]])
+		sb.WriteString(zhNumberString(s[loc[0]:loc[1]]))
+		last = loc[1]
+	}
+	sb.WriteString(s[last:])
+	return sb.String()
+}
+
+func isLatinLetter(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// zhNumberString reads a number such as "1,024" or "3.14"
+func zhNumberString(n string) string {
+	n = strings.ReplaceAll(n, ",", "")
+	whole, frac, hasFrac := strings.Cut(n, ".")
+	var reading string
+	if len(whole) > maxCardinalDigits || (len(whole) > 1 && whole[0] == '0') {
+		reading = zhDigitString(whole, false)
+	} else {
+		v, _ := strconv.ParseInt(whole, 10, 64)
+		reading = zhCardinal(v)
+	}
+	if hasFrac && frac != "" {
+		reading += "点" + zhDigitString(frac, false)
+	}
+	return reading
+}
+
+// zhCardinal reads a whole number below 10^12 in Chinese
+func zhCardinal(n int64) string {
+	if n == 0 {
+		return zhDigits[0]
+	}
+	var sections []int64 // Groups of four digits, lowest first
+	for ; n > 0; n /= 10000 {
+		sections = append(sections, n%10000)
+	}
+
+	var sb strings.Builder
+	zero := false
+	for i := len(sections) - 1; i >= 0; i-- {
+		section := sections[i]
+		if section == 0 {
+			zero = sb.Len() > 0
+			continue
+		}
+		// A gap of zeros before the section, as in 10,005 一万零五
+		if sb.Len() > 0 && (zero || section < 1000) {
+			sb.WriteString(zhDigits[0])
+		}
+		sb.WriteString(zhSection(section) + zhSectionUnits[i])
+		zero = false
+	}
+	reading := sb.String()
+	// 10 to 19 are read 十, 十一…, not 一十
+	if strings.HasPrefix(reading, "一十") {
+		reading = strings.TrimPrefix(reading, "一")
+	}
+	return reading
+}
+
+var (
+	zhUnits        = []string{"", "十", "百", "千"}
+	zhSectionUnits = []string{"", "万", "亿"}
+)
+
+// zhSection reads a group of four digits, 1 to 9999
+func zhSection(n int64) string {
+	var sb strings.Builder
+	zero := false
+	for u, p := 3, int64(1000); u >= 0; u, p = u-1, p/10 {
+		d := n / p % 10
+		if d == 0 {
+			zero = sb.Len() > 0
+			continue
+		}
+		if zero {
+			sb.WriteString(zhDigits[0])
+			zero = false
+		}
+		sb.WriteString(zhDigits[d] + zhUnits[u])
+	}
+	return sb.String()
+}
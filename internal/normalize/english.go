@@ -0,0 +1,100 @@
+package normalize
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Abbreviations read as the words they stand for
+var enAbbreviations = []struct {
+	pattern *regexp.Regexp
+	words   string
+}{
+	{regexp.MustCompile(`\be\.g\.`), "for example"},
+	{regexp.MustCompile(`\bi\.e\.`), "that is"},
+	{regexp.MustCompile(`\betc\.`), "et cetera"},
+	{regexp.MustCompile(`\bvs\.`), "versus"},
+	{regexp.MustCompile(`\bapprox\.`), "approximately"},
+	{regexp.MustCompile(`\bDr\.(\s+[A-Z])`), "Doctor$1"},
+	{regexp.MustCompile(`\bMr\.(\s+[A-Z])`), "Mister$1"},
+	{regexp.MustCompile(`\bMrs\.(\s+[A-Z])`), "Missus$1"},
+	{regexp.MustCompile(`\bProf\.(\s+[A-Z])`), "Professor$1"},
+	{regexp.MustCompile(`\bNo\.\s*([0-9])`), "number $1"},
+}
+
+var (
+	enPhonePattern = regexp.MustCompile(`(^|[^0-9])(\+1[ -]?)?\(?([0-9]{3})\)?[ .-]([0-9]{3})[.-]([0-9]{4})($|[^0-9])`)
+	enISODate      = regexp.MustCompile(`\b([0-9]{4})-([0-9]{2})-([0-9]{2})\b`)
+	enMoney        = regexp.MustCompile(`([$€£])([0-9][0-9,]*)(?:\.([0-9]{2}))?\b`)
+	enPercentage   = regexp.MustCompile(`([0-9]+(?:\.[0-9]+)?)\s?%`)
+)
+
+var enMonths = []string{"January", "February", "March", "April", "May", "June",
+	"July", "August", "September", "October", "November", "December"}
+
+// Currency names, singular and plural, with their hundredths
+var enCurrencies = map[string][4]string{
+	"$": {"dollar", "dollars", "cent", "cents"},
+	"€": {"euro", "euros", "cent", "cents"},
+	"£": {"pound", "pounds", "penny", "pence"},
+}
+
+func enAbbreviation(s string) string {
+	for _, a := range enAbbreviations {
+		s = a.pattern.ReplaceAllString(s, a.words)
+	}
+	return s
+}
+
+// enPhone reads North American phone numbers digit by digit, with a pause
+// between the groups
+func enPhone(s string) string {
+	return enPhonePattern.ReplaceAllStringFunc(s, func(m string) string {
+		g := enPhonePattern.FindStringSubmatch(m)
+		groups := []string{enDigitString(g[3]), enDigitString(g[4]), enDigitString(g[5])}
+		return g[1] + strings.Join(groups, ", ") + g[6]
+	})
+}
+
+func enDigitString(digits string) string {
+	return strings.Join(strings.Split(digits, ""), " ")
+}
+
+// enDate reads ISO dates such as 2024-05-01 as May 1, 2024
+func enDate(s string) string {
+	return enISODate.ReplaceAllStringFunc(s, func(m string) string {
+		g := enISODate.FindStringSubmatch(m)
+		month, _ := strconv.Atoi(g[2])
+		day, _ := strconv.Atoi(g[3])
+		if month < 1 || month > 12 || day < 1 || day > 31 {
+			return m
+		}
+		return enMonths[month-1] + " " + strconv.Itoa(day) + ", " + g[1]
+	})
+}
+
+func enCurrency(s string) string {
+	return enMoney.ReplaceAllStringFunc(s, func(m string) string {
+		g := enMoney.FindStringSubmatch(m)
+		names := enCurrencies[g[1]]
+		amount := g[2]
+		unit := names[1]
+		if strings.ReplaceAll(amount, ",", "") == "1" {
+			unit = names[0]
+		}
+		reading := amount + " " + unit
+		if cents, _ := strconv.Atoi(g[3]); cents > 0 {
+			sub := names[3]
+			if cents == 1 {
+				sub = names[2]
+			}
+			reading += " and " + strconv.Itoa(cents) + " " + sub
+		}
+		return reading
+	})
+}
+
+func enPercent(s string) string {
+	return enPercentage.ReplaceAllString(s, "$1 percent")
+}
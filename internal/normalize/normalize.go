@@ -0,0 +1,88 @@
+// Package normalize rewrites text so that numbers, dates, currency, phone
+// numbers and abbreviations are read as intended. Built-in normalizers
+// cover common Chinese and English patterns; user rules are regular
+// expressions run before them.
+package normalize
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Func is one normalization step
+type Func func(string) string
+
+// Rule replaces the matches of a regular expression. The replacement
+// expands $1 and ${name} as in regexp.ReplaceAllString.
+type Rule struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// Func returns the rule as a step
+func (r Rule) Func() Func {
+	return func(s string) string { return r.Pattern.ReplaceAllString(s, r.Replacement) }
+}
+
+// Languages with built-in normalizers
+const (
+	Chinese = "Chinese"
+	English = "English"
+)
+
+// Built-in normalizers of each language, in the order they run. Patterns
+// that contain numbers come before the plain number readings.
+var builtins = map[string][]Func{
+	Chinese: {spaceMixedScripts, zhPhone, zhDate, zhTime, zhPercent, zhCurrency, zhNumber},
+	English: {enAbbreviation, enPhone, enDate, enCurrency, enPercent},
+}
+
+// Builtins returns the built-in normalizers for language, a Minimax
+// language_boost value. Other values, such as auto, pick the language from
+// the text.
+func Builtins(language, text string) []Func {
+	if _, ok := builtins[language]; !ok {
+		language = Detect(text)
+	}
+	return builtins[language]
+}
+
+// Detect guesses whether text is Chinese or English: any Han character
+// makes it Chinese
+func Detect(text string) string {
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) {
+			return Chinese
+		}
+	}
+	return English
+}
+
+// Minimax syntax that is left untouched: pause markers such as <#0.5#>
+// and interjections such as (laughs)
+var protected = regexp.MustCompile(`<#[0-9.]+#>|\([a-z-]+\)`)
+
+// Text runs steps over text in order. Minimax pause markers and
+// interjections are kept as they are.
+func Text(text string, steps []Func) string {
+	if len(steps) == 0 {
+		return text
+	}
+	var sb strings.Builder
+	last := 0
+	for _, loc := range protected.FindAllStringIndex(text, -1) {
+		sb.WriteString(run(text[last:loc[0]], steps))
+		sb.WriteString(text[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	sb.WriteString(run(text[last:], steps))
+	return sb.String()
+}
+
+func run(s string, steps []Func) string {
+	for _, step := range steps {
+		s = step(s)
+	}
+	return s
+}
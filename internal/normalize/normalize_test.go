@@ -0,0 +1,114 @@
+package normalize
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestZhCardinal(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "零"},
+		{7, "七"},
+		{10, "十"},
+		{15, "十五"},
+		{20, "二十"},
+		{105, "一百零五"},
+		{110, "一百一十"},
+		{1001, "一千零一"},
+		{10005, "一万零五"},
+		{10500, "一万零五百"},
+		{120000, "十二万"},
+		{100000000, "一亿"},
+		{100010000, "一亿零一万"},
+		{123456789012, "一千二百三十四亿五千六百七十八万九千零一十二"},
+	}
+	for _, tt := range tests {
+		if got := zhCardinal(tt.n); got != tt.want {
+			t.Errorf("zhCardinal(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestChinese(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"共有1,024人", "共有一千零二十四人"},
+		{"圆周率约为3.14", "圆周率约为三点一四"},
+		{"编号007", "编号零零七"},
+		{"卡号1234567890123", "卡号一二三四五六七八九零一二三"},
+		{"2024年5月1日开业", "二零二四年五月一日开业"},
+		{"生于1998年", "生于一九九八年"},
+		{"会议9:05开始，10:00结束", "会议九点零五分开始，十点整结束"},
+		{"增长了12.5%", "增长了百分之十二点五"},
+		{"售价¥1,299", "售价一千二百九十九元"},
+		{"价格$20", "价格二十美元"},
+		{"电话13812345678", "电话幺三八幺二三四五六七八"},
+		{"拨打010-12345678", "拨打零幺零，幺二三四五六七八"},
+		{"我用iPhone15拍照", "我用 iPhone15 拍照"},
+		{"停顿<#1.5#>后3个", "停顿<#1.5#>后三个"},
+		{"他(laughs)笑了5次", "他(laughs)笑了五次"},
+	}
+	for _, tt := range tests {
+		if got := Text(tt.in, Builtins(Chinese, tt.in)); got != tt.want {
+			t.Errorf("Text(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEnglish(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Dr. Smith, e.g. a doctor", "Doctor Smith, for example a doctor"},
+		{"See No. 5, etc.", "See number 5, et cetera"},
+		{"the Dr. said", "the Dr. said"},
+		{"Call (555) 123-4567 now", "Call 5 5 5, 1 2 3, 4 5 6 7 now"},
+		{"Due 2024-05-01.", "Due May 1, 2024."},
+		{"Not a date 2024-13-01", "Not a date 2024-13-01"},
+		{"It costs $1.", "It costs 1 dollar."},
+		{"It costs $1,250.50", "It costs 1,250 dollars and 50 cents"},
+		{"Only £2.01", "Only 2 pounds and 1 penny"},
+		{"Up 12.5% or 3 %", "Up 12.5 percent or 3 percent"},
+		{"Pause<#0.5#>here (sighs)", "Pause<#0.5#>here (sighs)"},
+	}
+	for _, tt := range tests {
+		if got := Text(tt.in, Builtins("auto", tt.in)); got != tt.want {
+			t.Errorf("Text(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"Hello", English},
+		{"", English},
+		{"Hello 世界", Chinese},
+		{"こんにちは", English},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.text); got != tt.want {
+			t.Errorf("Detect(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	steps := []Func{
+		Rule{regexp.MustCompile(`\bASAP\b`), "as soon as possible"}.Func(),
+		Rule{regexp.MustCompile(`(?P<n>[0-9]+)x`), "${n} times"}.Func(),
+	}
+	in := "Reply ASAP, 3x faster <#0.5#> 2x"
+	want := "Reply as soon as possible, 3 times faster <#0.5#> 2 times"
+	if got := Text(in, steps); got != want {
+		t.Errorf("Text(%q) = %q, want %q", in, got, want)
+	}
+	if got := Text(in, nil); got != in {
+		t.Errorf("Text without steps = %q, want it unchanged", got)
+	}
+}
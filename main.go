@@ -2,6 +2,7 @@ package main
 
 import (
 	"embed"
+	"flag"
 	"fmt"
 	"io/fs"
 	"log"
@@ -18,9 +19,19 @@ import (
 var staticFS embed.FS
 
 func main() {
+	voiceSyncInterval := flag.Duration("voice-sync-interval", 0, "Reconcile voices with Minimax periodically, e.g. 6h (0 disables)")
+	flag.Parse()
+
 	// Initialize Database
 	database.InitDB(".")
 
+	// Background voice sync
+	api.StartVoiceSyncScheduler(*voiceSyncInterval)
+
+	// Continue bulk preview jobs interrupted by a restart
+	api.ResumePreviewJobs()
+	api.ResumeAudiobookBuilds()
+
 	r := gin.Default()
 
 	// API Routes
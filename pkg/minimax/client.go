@@ -51,7 +51,7 @@ func (c *Client) doRequest(method, url string, body any, result any) error {
 
 	if resp.StatusCode != http.StatusOK {
 		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("api error %d: %s", resp.StatusCode, string(b))
+		return &APIError{Op: "api error", HTTPStatus: resp.StatusCode, StatusMsg: string(b)}
 	}
 
 	if result != nil {
@@ -70,7 +70,7 @@ func (c *Client) T2A(req *T2ARequest) (*T2AResponse, error) {
 		return nil, err
 	}
 	if resp.BaseResp.StatusCode != 0 {
-		return nil, fmt.Errorf("minimax api error: %s", resp.BaseResp.StatusMsg)
+		return nil, newAPIError("minimax api error", resp.BaseResp)
 	}
 	return &resp, nil
 }
@@ -83,7 +83,7 @@ func (c *Client) T2AAsync(req *T2ARequest) (*T2AAsyncResponse, error) {
 		return nil, err
 	}
 	if resp.BaseResp.StatusCode != 0 {
-		return nil, fmt.Errorf("minimax api error: %s", resp.BaseResp.StatusMsg)
+		return nil, newAPIError("minimax api error", resp.BaseResp)
 	}
 	return &resp, nil
 }
@@ -116,7 +116,7 @@ func (c *Client) T2AAsyncQuery(taskID int64) (*T2AAsyncQueryResponse, error) {
 	}
 
 	if result.BaseResp.StatusCode != 0 {
-		return nil, fmt.Errorf("query error: %s", result.BaseResp.StatusMsg)
+		return nil, newAPIError("query error", result.BaseResp)
 	}
 	return &result, nil
 }
@@ -146,7 +146,7 @@ func (c *Client) RetrieveFile(fileID int64) (*FileRetrieveResponse, error) {
 		return nil, err
 	}
 	if result.BaseResp.StatusCode != 0 {
-		return nil, fmt.Errorf("retrieve error: %s", result.BaseResp.StatusMsg)
+		return nil, newAPIError("retrieve error", result.BaseResp)
 	}
 	return &result, nil
 }
@@ -192,7 +192,7 @@ func (c *Client) UploadFile(filePath string, purpose string) (*UploadResponse, e
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("upload failed: %s", string(bodyBytes))
+		return nil, &APIError{Op: "upload failed", HTTPStatus: resp.StatusCode, StatusMsg: string(bodyBytes)}
 	}
 
 	var result UploadResponse
@@ -201,7 +201,7 @@ func (c *Client) UploadFile(filePath string, purpose string) (*UploadResponse, e
 	}
 
 	if result.BaseResp.StatusCode != 0 {
-		return nil, fmt.Errorf("upload error: %s", result.BaseResp.StatusMsg)
+		return nil, newAPIError("upload error", result.BaseResp)
 	}
 
 	return &result, nil
@@ -215,7 +215,7 @@ func (c *Client) VoiceClone(req *VoiceCloneRequest) (*VoiceCloneResponse, error)
 		return nil, err
 	}
 	if resp.BaseResp.StatusCode != 0 {
-		return nil, fmt.Errorf("voice clone error: %s", resp.BaseResp.StatusMsg)
+		return nil, newAPIError("voice clone error", resp.BaseResp)
 	}
 	return &resp, nil
 }
@@ -228,7 +228,7 @@ func (c *Client) VoiceDesign(req *VoiceDesignRequest) (*VoiceDesignResponse, err
 		return nil, err
 	}
 	if resp.BaseResp.StatusCode != 0 {
-		return nil, fmt.Errorf("voice design error: %s", resp.BaseResp.StatusMsg)
+		return nil, newAPIError("voice design error", resp.BaseResp)
 	}
 	return &resp, nil
 }
@@ -242,7 +242,7 @@ func (c *Client) GetVoices(voiceType string) (*GetVoicesResponse, error) {
 		return nil, err
 	}
 	if resp.BaseResp.StatusCode != 0 {
-		return nil, fmt.Errorf("get voices error: %s", resp.BaseResp.StatusMsg)
+		return nil, newAPIError("get voices error", resp.BaseResp)
 	}
 	return &resp, nil
 }
@@ -256,7 +256,7 @@ func (c *Client) DeleteVoice(voiceType, voiceID string) error {
 		return err
 	}
 	if resp.BaseResp.StatusCode != 0 {
-		return fmt.Errorf("delete voice error: %s", resp.BaseResp.StatusMsg)
+		return newAPIError("delete voice error", resp.BaseResp)
 	}
 	return nil
 }
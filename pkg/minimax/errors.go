@@ -0,0 +1,63 @@
+package minimax
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MiniMax base_resp status codes that concern the API key rather than the request
+const (
+	StatusRateLimited         = 1002
+	StatusAuthFailed          = 1004
+	StatusInsufficientBalance = 1008
+	StatusTokenRateLimited    = 1039
+	StatusInvalidApiKey       = 2049
+)
+
+// APIError is returned when MiniMax rejects a request, either with a non-200
+// HTTP status or with a non-zero base_resp status code.
+type APIError struct {
+	Op         string // Message prefix, e.g. "minimax api error"
+	HTTPStatus int    // Set when the HTTP status was not 200
+	StatusCode int    // base_resp.status_code
+	StatusMsg  string
+}
+
+func (e *APIError) Error() string {
+	if e.HTTPStatus != 0 {
+		return fmt.Sprintf("%s %d: %s", e.Op, e.HTTPStatus, e.StatusMsg)
+	}
+	return fmt.Sprintf("%s: %s", e.Op, e.StatusMsg)
+}
+
+func newAPIError(op string, base BaseResp) *APIError {
+	return &APIError{Op: op, StatusCode: base.StatusCode, StatusMsg: base.StatusMsg}
+}
+
+// IsRateLimited reports whether err is a MiniMax rate limit rejection
+func IsRateLimited(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.HTTPStatus == 429 ||
+		apiErr.StatusCode == StatusRateLimited ||
+		apiErr.StatusCode == StatusTokenRateLimited
+}
+
+// IsInvalidKey reports whether err means the API key itself was refused
+func IsInvalidKey(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.HTTPStatus == 401 ||
+		apiErr.StatusCode == StatusAuthFailed ||
+		apiErr.StatusCode == StatusInvalidApiKey
+}
+
+// IsInsufficientBalance reports whether err means the account ran out of balance
+func IsInsufficientBalance(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == StatusInsufficientBalance
+}
@@ -146,8 +146,10 @@ type GetVoicesResponse struct {
 }
 
 type VoiceInfo struct {
-	VoiceID   string `json:"voice_id"`
-	VoiceName string `json:"voice_name"`
+	VoiceID     string   `json:"voice_id"`
+	VoiceName   string   `json:"voice_name"`
+	Description []string `json:"description"`
+	CreatedTime string   `json:"created_time"` // e.g. 2025-01-01
 }
 
 type DeleteVoiceRequest struct {
@@ -0,0 +1,13 @@
+// Lists are paginated; fetchAll follows the cursor until every item of a
+// list endpoint is loaded
+export async function fetchAll(api, url, params = {}) {
+  const items = []
+  let cursor = ''
+  for (;;) {
+    const res = await api.get(url, { params: { ...params, page_size: 500, cursor } })
+    items.push(...(res.data.data || []))
+    const page = res.data.pagination
+    if (!page?.has_more || !page.next_cursor) return items
+    cursor = page.next_cursor
+  }
+}
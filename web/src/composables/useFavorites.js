@@ -1,5 +1,6 @@
 import { ref } from 'vue'
 import axios from 'axios'
+import { fetchAll } from './fetchAll'
 
 const favorites = ref(new Set())
 let initialized = false
@@ -12,12 +13,9 @@ const api = axios.create({
 const ensureLoaded = () => {
   if (initialized) return initPromise
   initialized = true
-  initPromise = api.get('/favorites')
-    .then((res) => {
-      const ids = res?.data?.data
-      if (Array.isArray(ids)) {
-        favorites.value = new Set(ids)
-      }
+  initPromise = fetchAll(api, '/favorites')
+    .then((ids) => {
+      favorites.value = new Set(ids)
     })
     .catch((e) => {
       console.error('Failed to load favorites', e)
@@ -29,9 +27,8 @@ export function useFavorites() {
   void ensureLoaded()
 
   const refreshFavorites = async () => {
-    const res = await api.get('/favorites')
-    const ids = res?.data?.data
-    favorites.value = new Set(Array.isArray(ids) ? ids : [])
+    const ids = await fetchAll(api, '/favorites')
+    favorites.value = new Set(ids)
   }
 
   const toggleFavorite = async (voiceId) => {
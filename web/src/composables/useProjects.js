@@ -0,0 +1,54 @@
+import { ref } from 'vue'
+import axios from 'axios'
+import { fetchAll } from './fetchAll'
+
+const projects = ref([])
+
+const api = axios.create({
+  baseURL: import.meta.env.DEV ? 'http://localhost:8080/api' : '/api'
+})
+
+// Flattens a folder tree into select options, indented by depth
+const flattenFolders = (nodes, depth = 0, out = []) => {
+  for (const node of nodes || []) {
+    out.push({ id: node.id, label: '\u00a0\u00a0'.repeat(depth) + node.name, task_count: node.task_count })
+    flattenFolders(node.children, depth + 1, out)
+  }
+  return out
+}
+
+export function useProjects() {
+  const fetchProjects = async () => {
+    try {
+      projects.value = await fetchAll(api, '/projects')
+    } catch (e) {
+      console.error('Failed to load projects', e)
+    }
+  }
+
+  // Returns the project with its folders flattened for a select
+  const fetchProject = async (id) => {
+    const res = await api.get(`/projects/${id}`)
+    const project = res.data.data
+    return { ...project, folderOptions: flattenFolders(project.folders) }
+  }
+
+  const createProject = async (name) => {
+    const res = await api.post('/projects', { name })
+    await fetchProjects()
+    return res.data.data
+  }
+
+  const createFolder = async (projectId, name, parentId = 0) => {
+    const res = await api.post(`/projects/${projectId}/folders`, { name, parent_id: parentId })
+    return res.data.data
+  }
+
+  return {
+    projects,
+    fetchProjects,
+    fetchProject,
+    createProject,
+    createFolder
+  }
+}
@@ -15,11 +15,31 @@ const routes = [
     name: 'AudioManagement',
     component: () => import('../views/AudioManagement.vue')
   },
+  {
+    path: '/audiobooks',
+    name: 'Audiobooks',
+    component: () => import('../views/Audiobooks.vue')
+  },
   {
     path: '/voices',
     name: 'Voices',
     component: () => import('../views/Voices.vue')
   },
+  {
+    path: '/scripts',
+    name: 'Scripts',
+    component: () => import('../views/Scripts.vue')
+  },
+  {
+    path: '/normalization',
+    name: 'Normalization',
+    component: () => import('../views/Normalization.vue')
+  },
+  {
+    path: '/dictionaries',
+    name: 'Dictionaries',
+    component: () => import('../views/Dictionaries.vue')
+  },
   {
     path: '/keys',
     name: 'Keys',